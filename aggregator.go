@@ -0,0 +1,179 @@
+package runx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogAggregator interleaves the logs of several apps, such as the apps of a
+// pack, running a LogStreamer per app with the same options.
+type LogAggregator struct {
+	client *ClientWithResponses
+	appIds []string
+	opts   []LogOption
+	follow bool
+	events chan LogEvent
+
+	mu    sync.RWMutex
+	names map[string]string
+}
+
+// NewLogAggregator creates a LogAggregator reading the logs of the apps
+// identified by appIds, configuring each LogStreamer with opts. Events are
+// delivered once Run is called.
+func NewLogAggregator(client *ClientWithResponses, appIds []string, opts ...LogOption) *LogAggregator {
+	// read the options shared with the streamers
+	probe := NewLogStreamer(client, "", opts...)
+	return &LogAggregator{
+		client: client,
+		appIds: appIds,
+		opts:   opts,
+		follow: probe.follow,
+		events: make(chan LogEvent, probe.buffer),
+		names:  map[string]string{},
+	}
+}
+
+// Events returns the channel delivering the events of all the apps, with
+// their Name set. It is closed when Run returns.
+func (a *LogAggregator) Events() <-chan LogEvent {
+	return a.events
+}
+
+// Name returns the name of the app identified by appId, or appId when it is
+// not known yet. The names are fetched through GetApps when Run starts.
+func (a *LogAggregator) Name(appId string) string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if name, ok := a.names[appId]; ok {
+		return name
+	}
+	return appId
+}
+
+// Names returns the names of the apps, in the order of the app ids.
+func (a *LogAggregator) Names() []string {
+	names := make([]string, len(a.appIds))
+	for i, id := range a.appIds {
+		names[i] = a.Name(id)
+	}
+	return names
+}
+
+// Run streams the logs of the apps until every LogStreamer returns, then
+// closes the events channel and returns their errors joined. When following,
+// the lines are delivered as they are fetched; otherwise the lines of all the
+// apps are delivered once fetched, ordered by time.
+func (a *LogAggregator) Run(ctx context.Context) error {
+	defer close(a.events)
+
+	if apps, err := a.client.listApps(ctx); err == nil {
+		a.mu.Lock()
+		for _, id := range a.appIds {
+			if app := findApp(apps, id); app != nil {
+				a.names[id] = appLabel(app)
+			}
+		}
+		a.mu.Unlock()
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		collected []LogEvent
+		errs      = make([]error, len(a.appIds))
+	)
+	for i, id := range a.appIds {
+		s := NewLogStreamer(a.client, id, a.opts...)
+		name := a.Name(id)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := s.Run(ctx); err != nil {
+				errs[i] = fmt.Errorf("runx: log of %s: %w", name, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for e := range s.Events() {
+				e.Name = name
+				if a.follow {
+					a.emit(ctx, e)
+					continue
+				}
+				mu.Lock()
+				collected = append(collected, e)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.SliceStable(collected, func(i, j int) bool {
+		return eventTime(collected[i]).Before(eventTime(collected[j]))
+	})
+	for _, e := range collected {
+		if !a.emit(ctx, e) {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (a *LogAggregator) emit(ctx context.Context, e LogEvent) bool {
+	select {
+	case a.events <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func eventTime(e LogEvent) time.Time {
+	if e.Time == nil {
+		return time.Time{}
+	}
+	return *e.Time
+}
+
+// prefixColors are the ANSI colors given in turn to the apps of a LogPrefixer.
+var prefixColors = []string{"36", "33", "32", "35", "34", "96", "93", "92", "95", "94"}
+
+// LogPrefixer renders log lines prefixed with the name of their app, padded
+// to the longest name, like docker compose logs.
+type LogPrefixer struct {
+	width  int
+	color  bool
+	colors map[string]string
+}
+
+// NewLogPrefixer returns a LogPrefixer for the apps named names. With color,
+// each app gets its own ANSI color.
+func NewLogPrefixer(names []string, color bool) *LogPrefixer {
+	p := &LogPrefixer{color: color, colors: map[string]string{}}
+	for _, name := range names {
+		p.width = max(p.width, len(name))
+		if _, ok := p.colors[name]; !ok {
+			p.colors[name] = prefixColors[len(p.colors)%len(prefixColors)]
+		}
+	}
+	return p
+}
+
+// Format returns the line of e prefixed with its app name, without line break.
+func (p *LogPrefixer) Format(e LogEvent) string {
+	name := e.Name
+	if name == "" {
+		name = e.App
+	}
+	prefix := name + strings.Repeat(" ", max(0, p.width-len(name))) + " | "
+	if color, ok := p.colors[name]; ok && p.color {
+		prefix = "\x1b[" + color + "m" + prefix + "\x1b[0m"
+	}
+	return prefix + e.Line
+}
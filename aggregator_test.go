@@ -0,0 +1,159 @@
+package runx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	runx "github.com/run-x-app/runx-go"
+	"github.com/run-x-app/runx-go/runxtest"
+)
+
+// collect runs a and returns the lines of its events as name: line.
+func collect(t *testing.T, a *runx.LogAggregator) ([]string, error) {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- a.Run(context.Background()) }()
+	var lines []string
+	for e := range a.Events() {
+		lines = append(lines, e.Name+": "+e.Line)
+	}
+	return lines, <-done
+}
+
+func TestLogAggregator(t *testing.T) {
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	client := srv.Client()
+	api, db := *newApp(t, client, "api").Id, *newApp(t, client, "db").Id
+	srv.SetLog(api,
+		"2024-06-01T10:00:00Z api starting",
+		"2024-06-01T10:00:03Z api ready",
+		"  listening on :8080",
+	)
+	srv.SetLog(db,
+		"2024-06-01T10:00:01Z db starting",
+		"2024-06-01T10:00:02Z db ready",
+		"2024-06-01T10:00:04Z db checkpoint",
+	)
+
+	a := runx.NewLogAggregator(client, []string{api, db})
+	lines, err := collect(t, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ordered by time, a continuation line keeping the time of the line before it
+	want := []string{
+		"api: 2024-06-01T10:00:00Z api starting",
+		"db: 2024-06-01T10:00:01Z db starting",
+		"db: 2024-06-01T10:00:02Z db ready",
+		"api: 2024-06-01T10:00:03Z api ready",
+		"api:   listening on :8080",
+		"db: 2024-06-01T10:00:04Z db checkpoint",
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("lines =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+	if got := a.Names(); !slices.Equal(got, []string{"api", "db"}) {
+		t.Errorf("Names() = %v, want api and db", got)
+	}
+}
+
+func TestLogAggregatorErrors(t *testing.T) {
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	client := srv.Client()
+	api := *newApp(t, client, "api").Id
+	srv.SetLog(api, "2024-06-01T10:00:00Z api starting")
+
+	a := runx.NewLogAggregator(client, []string{"missing-1", api, "missing-2"})
+	lines, err := collect(t, a)
+	// the logs of the other apps are still delivered
+	if !slices.Equal(lines, []string{"api: 2024-06-01T10:00:00Z api starting"}) {
+		t.Errorf("lines = %q", lines)
+	}
+	if !errors.Is(err, runx.ErrNotFound) {
+		t.Fatalf("Run() error = %v, want %v", err, runx.ErrNotFound)
+	}
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok || len(joined.Unwrap()) != 2 {
+		t.Fatalf("Run() error = %v, want one error per missing app", err)
+	}
+	for i, id := range []string{"missing-1", "missing-2"} {
+		if msg := joined.Unwrap()[i].Error(); !strings.HasPrefix(msg, "runx: log of "+id+": ") {
+			t.Errorf("error %d = %q, want it to name %s", i, msg, id)
+		}
+	}
+}
+
+func TestLogAggregatorNames(t *testing.T) {
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	client := srv.Client()
+	app := newApp(t, client, "api")
+	srv.SetLog(*app.Id, "2024-06-01T10:00:00Z api starting")
+
+	// apps are named by their id while the names are not fetched
+	a := runx.NewLogAggregator(client, []string{*app.Id})
+	if got := a.Name(*app.Id); got != *app.Id {
+		t.Errorf("Name() before Run = %q, want the id", got)
+	}
+	lines, err := collect(t, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(lines, []string{"api: 2024-06-01T10:00:00Z api starting"}) {
+		t.Errorf("lines = %q, want the name", lines)
+	}
+
+	// the ids are kept when GetApps fails
+	srv.InjectFault(runxtest.Fault{Operation: "GetApps", StatusCode: http.StatusInternalServerError})
+	a = runx.NewLogAggregator(client, []string{*app.Id})
+	lines, err = collect(t, a)
+	if err != nil {
+		t.Fatalf("Run() error = %v, want the logs without names", err)
+	}
+	if !slices.Equal(lines, []string{*app.Id + ": 2024-06-01T10:00:00Z api starting"}) {
+		t.Errorf("lines = %q, want the id as name", lines)
+	}
+	if got := a.Names(); !slices.Equal(got, []string{*app.Id}) {
+		t.Errorf("Names() = %v, want the id", got)
+	}
+}
+
+func TestLogPrefixer(t *testing.T) {
+	p := runx.NewLogPrefixer([]string{"api", "postgres", "api"}, false)
+	tests := []struct {
+		event runx.LogEvent
+		want  string
+	}{
+		{runx.LogEvent{Name: "api", Line: "ready"}, "api      | ready"},
+		{runx.LogEvent{Name: "postgres", Line: "ready"}, "postgres | ready"},
+		// without a name, the app id is the prefix
+		{runx.LogEvent{App: "a1b2", Line: "ready"}, "a1b2     | ready"},
+		// a longer name than those given is not padded
+		{runx.LogEvent{Name: "postgres-replica", Line: ""}, "postgres-replica | "},
+	}
+	for _, tt := range tests {
+		if got := p.Format(tt.event); got != tt.want {
+			t.Errorf("Format(%+v) = %q, want %q", tt.event, got, tt.want)
+		}
+	}
+
+	// the colors are given in turn, once per name, and reused after ten names
+	names := []string{"a", "b", "a", "c", "d", "e", "f", "g", "h", "i", "j", "k"}
+	p = runx.NewLogPrefixer(names, true)
+	want := map[string]string{"a": "36", "b": "33", "c": "32", "j": "94", "k": "36"}
+	for name, color := range want {
+		if got, prefix := p.Format(runx.LogEvent{Name: name, Line: "x"}), "\x1b["+color+"m"+name+" | \x1b[0mx"; got != prefix {
+			t.Errorf("Format(%s) = %q, want %q", name, got, prefix)
+		}
+	}
+	// a name outside of those given has no color
+	if got := p.Format(runx.LogEvent{Name: "z", Line: "x"}); got != "z | x" {
+		t.Errorf("Format(z) = %q, want no color", got)
+	}
+}
@@ -0,0 +1,253 @@
+package runx
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ReportPeriod is the length of the periods of a BillingReport.
+type ReportPeriod string
+
+// Defines values for ReportPeriod.
+const (
+	PeriodDay   ReportPeriod = "day"
+	PeriodWeek  ReportPeriod = "week"
+	PeriodMonth ReportPeriod = "month"
+)
+
+// ParseReportPeriod parses day, week or month.
+func ParseReportPeriod(s string) (ReportPeriod, error) {
+	switch p := ReportPeriod(s); p {
+	case PeriodDay, PeriodWeek, PeriodMonth:
+		return p, nil
+	}
+	return "", fmt.Errorf("runx: unknown report period %q, expected day, week or month", s)
+}
+
+// start returns the beginning of the period holding t, in the location of t.
+// Weeks start on Monday.
+func (p ReportPeriod) start(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch p {
+	case PeriodWeek:
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	case PeriodMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// next returns the beginning of the period following the one starting at start.
+func (p ReportPeriod) next(start time.Time) time.Time {
+	switch p {
+	case PeriodWeek:
+		return start.AddDate(0, 0, 7)
+	case PeriodMonth:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// BillingPeriod aggregates the payments and the consumption of a period.
+type BillingPeriod struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Payments is the sum of the PaymentCount payments made in the period.
+	Payments     float64 `json:"payments"`
+	PaymentCount int     `json:"payment_count"`
+
+	// Consumption is the amount consumed by the apps in the period.
+	Consumption float64 `json:"consumption"`
+
+	// Balance is the credit of the account at the end of the period, derived
+	// from the current credit and the later payments and consumption.
+	Balance float64 `json:"balance"`
+}
+
+// BillingReport aggregates the billing history of an account per period over
+// a time range, as returned by ClientWithResponses.BillingReport.
+type BillingReport struct {
+	// From and To bound the report, To being excluded.
+	From   time.Time    `json:"from"`
+	To     time.Time    `json:"to"`
+	Period ReportPeriod `json:"period"`
+
+	Periods []BillingPeriod `json:"periods"`
+
+	TotalPayments    float64 `json:"total_payments"`
+	TotalConsumption float64 `json:"total_consumption"`
+
+	// Credit and Limit are the current FilteredUser.Credit and Limit.
+	Credit float64 `json:"credit"`
+	Limit  float64 `json:"limit"`
+
+	// Remaining is the amount that can still be consumed: the credit, capped
+	// by what is left of the limit after the consumption of the report.
+	Remaining float64 `json:"remaining"`
+}
+
+// BillingReport fetches the payments through MeBilling and the consumption
+// through Me, and aggregates them per period from from to to, to excluded.
+// A zero from starts the report at the oldest record, a zero to ends it now.
+// Periods are computed in the location of from, or of to when from is zero.
+func (c *ClientWithResponses) BillingReport(ctx context.Context, from, to time.Time, period ReportPeriod) (*BillingReport, error) {
+	me, err := c.MeWithResponse(ctx)
+	if err == nil {
+		err = CheckResponse("Me", me.HTTPResponse, me.Body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("runx: billing report: %w", err)
+	}
+	billing, err := c.MeBillingWithResponse(ctx)
+	if err == nil {
+		err = CheckResponse("MeBilling", billing.HTTPResponse, billing.Body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("runx: billing report: %w", err)
+	}
+
+	var (
+		user         FilteredUser
+		payments     []Payment
+		consumptions []Consumption
+	)
+	if me.JSON200 != nil {
+		if me.JSON200.User != nil {
+			user = *me.JSON200.User
+		}
+		if me.JSON200.Consumptions != nil {
+			consumptions = *me.JSON200.Consumptions
+		}
+	}
+	if billing.JSON200 != nil && billing.JSON200.Payments != nil {
+		payments = *billing.JSON200.Payments
+	}
+	if to.IsZero() {
+		to = time.Now()
+		if !from.IsZero() {
+			to = to.In(from.Location())
+		}
+	}
+	return NewBillingReport(user, payments, consumptions, from, to, period)
+}
+
+// NewBillingReport aggregates payments and consumptions per period from from
+// to to, to excluded, see ClientWithResponses.BillingReport. user provides
+// the current credit and limit.
+func NewBillingReport(user FilteredUser, payments []Payment, consumptions []Consumption, from, to time.Time, period ReportPeriod) (*BillingReport, error) {
+	if _, err := ParseReportPeriod(string(period)); err != nil {
+		return nil, err
+	}
+	loc := to.Location()
+	if !from.IsZero() {
+		loc = from.Location()
+	}
+	if from.IsZero() {
+		for _, p := range payments {
+			if p.CreatedAt != nil && (from.IsZero() || p.CreatedAt.Before(from)) {
+				from = *p.CreatedAt
+			}
+		}
+		for _, c := range consumptions {
+			if c.Date != nil && (from.IsZero() || c.Date.Before(from)) {
+				from = *c.Date
+			}
+		}
+		if from.IsZero() {
+			from = to
+		}
+		from = period.start(from.In(loc))
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("runx: billing report: range ends at %s before it starts at %s", to.Format(time.RFC3339), from.Format(time.RFC3339))
+	}
+
+	report := &BillingReport{
+		From:    from,
+		To:      to,
+		Period:  period,
+		Periods: []BillingPeriod{},
+		Credit:  roundCents(value(user.Credit)),
+		Limit:   roundCents(value(user.Limit)),
+	}
+	for start := period.start(from.In(loc)); start.Before(to); start = period.next(start) {
+		report.Periods = append(report.Periods, BillingPeriod{Start: start, End: period.next(start)})
+	}
+	if n := len(report.Periods); n > 0 {
+		report.Periods[0].Start = from
+		report.Periods[n-1].End = to
+	}
+
+	// later accumulates the net change of the credit after to, which is
+	// undone, together with the changes of the later periods, to obtain the
+	// balance at the end of each period.
+	var later float64
+	index := func(t time.Time) (int, bool) {
+		if t.Before(from) {
+			return 0, false
+		}
+		for i := range report.Periods {
+			if t.Before(report.Periods[i].End) {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+	for _, p := range payments {
+		if p.CreatedAt == nil {
+			continue
+		}
+		amount := value(p.Amount)
+		if i, ok := index(*p.CreatedAt); ok {
+			report.Periods[i].Payments += amount
+			report.Periods[i].PaymentCount++
+			report.TotalPayments += amount
+		} else if !p.CreatedAt.Before(to) {
+			later += amount
+		}
+	}
+	for _, c := range consumptions {
+		if c.Date == nil {
+			continue
+		}
+		amount := value(c.Value)
+		if i, ok := index(*c.Date); ok {
+			report.Periods[i].Consumption += amount
+			report.TotalConsumption += amount
+		} else if !c.Date.Before(to) {
+			later -= amount
+		}
+	}
+
+	balance := report.Credit - later
+	for i := len(report.Periods) - 1; i >= 0; i-- {
+		p := &report.Periods[i]
+		p.Payments, p.Consumption = roundCents(p.Payments), roundCents(p.Consumption)
+		p.Balance = roundCents(balance)
+		balance -= p.Payments - p.Consumption
+	}
+	report.TotalPayments = roundCents(report.TotalPayments)
+	report.TotalConsumption = roundCents(report.TotalConsumption)
+	report.Remaining = roundCents(math.Max(0, math.Min(report.Credit, report.Limit-report.TotalConsumption)))
+	return report, nil
+}
+
+// value returns the amount v, 0 when missing, without the binary noise of
+// its float32 encoding: 0.1 becomes 0.1 rather than 0.10000000149011612.
+func value(v *float32) float64 {
+	if v == nil {
+		return 0
+	}
+	f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(*v), 'g', -1, 32), 64)
+	return f
+}
+
+// roundCents rounds an amount to the nearest hundredth, hiding the float32
+// noise of the API amounts in reports.
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,264 @@
+package runx
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day, hour int) time.Time {
+	return time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+}
+
+func payment(at time.Time, amount float32) Payment {
+	return Payment{CreatedAt: &at, Amount: &amount}
+}
+
+func consumption(day time.Time, amount float32) Consumption {
+	return Consumption{Date: &day, Value: &amount}
+}
+
+func TestNewBillingReport(t *testing.T) {
+	payments := []Payment{
+		payment(date(2024, 5, 30, 10), 50),
+		payment(date(2024, 6, 3, 9), 25.5),
+		// after the report, undone from the current credit
+		payment(date(2024, 6, 20, 8), 10),
+		{Amount: ptr[float32](1000)},
+	}
+	consumptions := []Consumption{
+		consumption(date(2024, 5, 31, 0), 1.25),
+		consumption(date(2024, 6, 1, 0), 2.5),
+		consumption(date(2024, 6, 3, 0), 0.1),
+		// on the end of the range, which is excluded
+		consumption(date(2024, 6, 10, 0), 3),
+	}
+	user := FilteredUser{Credit: ptr[float32](70.5), Limit: ptr[float32](100)}
+
+	type period struct {
+		start, end           time.Time
+		payments             float64
+		count                int
+		consumption, balance float64
+	}
+	tests := []struct {
+		name      string
+		from, to  time.Time
+		period    ReportPeriod
+		user      FilteredUser
+		want      []period
+		wantFrom  time.Time
+		remaining float64
+	}{
+		{
+			name:   "weeks",
+			from:   date(2024, 5, 30, 0),
+			to:     date(2024, 6, 10, 0),
+			period: PeriodWeek,
+			user:   user,
+			// 70.5 - 10 + 3 at the end of the range
+			want: []period{
+				{date(2024, 5, 30, 0), date(2024, 6, 3, 0), 50, 1, 3.75, 38.1},
+				{date(2024, 6, 3, 0), date(2024, 6, 10, 0), 25.5, 1, 0.1, 63.5},
+			},
+			wantFrom:  date(2024, 5, 30, 0),
+			remaining: 70.5,
+		},
+		{
+			name:   "months from the oldest record",
+			to:     date(2024, 6, 10, 0),
+			period: PeriodMonth,
+			user:   user,
+			want: []period{
+				{date(2024, 5, 1, 0), date(2024, 6, 1, 0), 50, 1, 1.25, 40.6},
+				{date(2024, 6, 1, 0), date(2024, 6, 10, 0), 25.5, 1, 2.6, 63.5},
+			},
+			wantFrom:  date(2024, 5, 1, 0),
+			remaining: 70.5,
+		},
+		{
+			name:   "days",
+			from:   date(2024, 6, 1, 0),
+			to:     date(2024, 6, 3, 12),
+			period: PeriodDay,
+			user:   user,
+			// the payment of 06-03 09:00 falls in the partial last day
+			want: []period{
+				{date(2024, 6, 1, 0), date(2024, 6, 2, 0), 0, 0, 2.5, 38.1},
+				{date(2024, 6, 2, 0), date(2024, 6, 3, 0), 0, 0, 0, 38.1},
+				{date(2024, 6, 3, 0), date(2024, 6, 3, 12), 25.5, 1, 0.1, 63.5},
+			},
+			wantFrom:  date(2024, 6, 1, 0),
+			remaining: 70.5,
+		},
+		{
+			name:   "remaining capped by the limit",
+			from:   date(2024, 5, 30, 0),
+			to:     date(2024, 6, 10, 0),
+			period: PeriodMonth,
+			user:   FilteredUser{Credit: ptr[float32](70.5), Limit: ptr[float32](10)},
+			want: []period{
+				{date(2024, 5, 30, 0), date(2024, 6, 1, 0), 50, 1, 1.25, 40.6},
+				{date(2024, 6, 1, 0), date(2024, 6, 10, 0), 25.5, 1, 2.6, 63.5},
+			},
+			wantFrom: date(2024, 5, 30, 0),
+			// 10 - 3.85
+			remaining: 6.15,
+		},
+		{
+			name:     "limit exceeded",
+			from:     date(2024, 5, 30, 0),
+			to:       date(2024, 6, 3, 0),
+			period:   PeriodWeek,
+			user:     FilteredUser{Credit: ptr[float32](70.5), Limit: ptr[float32](3)},
+			want:     []period{{date(2024, 5, 30, 0), date(2024, 6, 3, 0), 50, 1, 3.75, 38.1}},
+			wantFrom: date(2024, 5, 30, 0),
+		},
+		{
+			name:     "empty range",
+			from:     date(2024, 6, 1, 0),
+			to:       date(2024, 6, 1, 0),
+			period:   PeriodDay,
+			user:     user,
+			want:     []period{},
+			wantFrom: date(2024, 6, 1, 0),
+			// every consumption is after the range
+			remaining: 70.5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := NewBillingReport(tt.user, payments, consumptions, tt.from, tt.to, tt.period)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !report.From.Equal(tt.wantFrom) || !report.To.Equal(tt.to) {
+				t.Errorf("range = %s - %s, want %s - %s", report.From, report.To, tt.wantFrom, tt.to)
+			}
+			if len(report.Periods) != len(tt.want) {
+				t.Fatalf("%d periods, want %d: %+v", len(report.Periods), len(tt.want), report.Periods)
+			}
+			var payments, consumption float64
+			for i, want := range tt.want {
+				got := report.Periods[i]
+				if !got.Start.Equal(want.start) || !got.End.Equal(want.end) {
+					t.Errorf("period %d = %s - %s, want %s - %s", i, got.Start, got.End, want.start, want.end)
+				}
+				if got.Payments != want.payments || got.PaymentCount != want.count || got.Consumption != want.consumption || got.Balance != want.balance {
+					t.Errorf("period %d = %v paid in %d, %v consumed, balance %v, want %v in %d, %v, %v",
+						i, got.Payments, got.PaymentCount, got.Consumption, got.Balance, want.payments, want.count, want.consumption, want.balance)
+				}
+				payments += want.payments
+				consumption += want.consumption
+			}
+			if report.TotalPayments != roundCents(payments) || report.TotalConsumption != roundCents(consumption) {
+				t.Errorf("totals = %v paid, %v consumed, want %v, %v", report.TotalPayments, report.TotalConsumption, roundCents(payments), roundCents(consumption))
+			}
+			if report.Credit != value(tt.user.Credit) || report.Limit != value(tt.user.Limit) {
+				t.Errorf("credit %v, limit %v", report.Credit, report.Limit)
+			}
+			if report.Remaining != tt.remaining {
+				t.Errorf("Remaining = %v, want %v", report.Remaining, tt.remaining)
+			}
+		})
+	}
+}
+
+func TestNewBillingReportErrors(t *testing.T) {
+	if _, err := NewBillingReport(FilteredUser{}, nil, nil, time.Time{}, date(2024, 6, 1, 0), "year"); err == nil {
+		t.Error("NewBillingReport() accepted the period year")
+	}
+	if _, err := NewBillingReport(FilteredUser{}, nil, nil, date(2024, 6, 2, 0), date(2024, 6, 1, 0), PeriodDay); err == nil {
+		t.Error("NewBillingReport() accepted a range ending before it starts")
+	}
+	report, err := NewBillingReport(FilteredUser{}, nil, nil, time.Time{}, date(2024, 6, 1, 12), PeriodDay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// without any record, the report starts with the period of to
+	if !report.From.Equal(date(2024, 6, 1, 0)) || len(report.Periods) != 1 || report.Remaining != 0 {
+		t.Errorf("report without records = %+v", report)
+	}
+}
+
+func TestBillingRounding(t *testing.T) {
+	// ten daily consumptions of 0.1, each 0.10000000149011612 as float32
+	var tenths []Consumption
+	for day := range 10 {
+		tenths = append(tenths, consumption(date(2024, 6, 1+day, 0), 0.1))
+	}
+	report, err := NewBillingReport(FilteredUser{Credit: ptr[float32](0.3), Limit: ptr[float32](1.1)}, nil, tenths, date(2024, 6, 1, 0), date(2024, 6, 11, 0), PeriodMonth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TotalConsumption != 1 || report.Periods[0].Consumption != 1 {
+		t.Errorf("consumption = %v, want 1", report.TotalConsumption)
+	}
+	if report.Credit != 0.3 || report.Periods[0].Balance != 0.3 {
+		t.Errorf("credit = %v, balance %v, want 0.3", report.Credit, report.Periods[0].Balance)
+	}
+	// min(0.3, 1.1 - 1) without the noise of 1.1 - 1 = 0.10000000000000009
+	if report.Remaining != 0.1 {
+		t.Errorf("Remaining = %v, want 0.1", report.Remaining)
+	}
+
+	tests := []struct {
+		in, want float64
+	}{
+		{0, 0},
+		{0.004, 0},
+		{0.005, 0.01},
+		{0.0149, 0.01},
+		{0.125, 0.13},
+		{-0.125, -0.13},
+		{0.994, 0.99},
+		{0.995, 1},
+		{99.999, 100},
+		{0.1 + 0.2, 0.3},
+	}
+	for _, tt := range tests {
+		if got := roundCents(tt.in); got != tt.want {
+			t.Errorf("roundCents(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	// the float32 amounts read back as their decimal values
+	for _, want := range []float64{0.01, 0.1, 0.2, 25.5, 70.3, 1234.56} {
+		v := float32(want)
+		if got := value(&v); got != want {
+			t.Errorf("value(float32(%v)) = %v", want, got)
+		}
+	}
+	if value(nil) != 0 {
+		t.Errorf("value(nil) = %v, want 0", value(nil))
+	}
+}
+
+func TestReportPeriodStart(t *testing.T) {
+	paris := time.FixedZone("CEST", 2*60*60)
+	tests := []struct {
+		period ReportPeriod
+		t      time.Time
+		start  time.Time
+		next   time.Time
+	}{
+		{PeriodDay, date(2024, 6, 1, 23), date(2024, 6, 1, 0), date(2024, 6, 2, 0)},
+		{PeriodDay, time.Date(2024, 6, 1, 1, 0, 0, 0, paris), time.Date(2024, 6, 1, 0, 0, 0, 0, paris), time.Date(2024, 6, 2, 0, 0, 0, 0, paris)},
+		// 2024-06-03 is a Monday
+		{PeriodWeek, date(2024, 6, 3, 0), date(2024, 6, 3, 0), date(2024, 6, 10, 0)},
+		{PeriodWeek, date(2024, 6, 9, 23), date(2024, 6, 3, 0), date(2024, 6, 10, 0)},
+		{PeriodWeek, date(2024, 6, 1, 12), date(2024, 5, 27, 0), date(2024, 6, 3, 0)},
+		{PeriodMonth, date(2024, 2, 29, 12), date(2024, 2, 1, 0), date(2024, 3, 1, 0)},
+		{PeriodMonth, date(2024, 12, 31, 23), date(2024, 12, 1, 0), date(2025, 1, 1, 0)},
+	}
+	for _, tt := range tests {
+		start := tt.period.start(tt.t)
+		if !start.Equal(tt.start) {
+			t.Errorf("%s start(%s) = %s, want %s", tt.period, tt.t, start, tt.start)
+		}
+		if next := tt.period.next(start); !next.Equal(tt.next) {
+			t.Errorf("%s next(%s) = %s, want %s", tt.period, start, next, tt.next)
+		}
+	}
+	if _, err := ParseReportPeriod("quarter"); err == nil {
+		t.Error("ParseReportPeriod(quarter) succeeded")
+	}
+}
@@ -0,0 +1,183 @@
+package runx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrBudgetExceeded is matched by the *BudgetError returned by a client
+// configured WithBudget.
+var ErrBudgetExceeded = errors.New("runx: budget exceeded")
+
+// BudgetError reports a request refused because its cost would take the
+// monthly spending over the budget.
+type BudgetError struct {
+	// Operation is CreateApp or EnableApp.
+	Operation string
+
+	// Budget is the configured monthly budget.
+	Budget float64
+
+	// Current is the monthly cost of the billed apps, and Added the monthly
+	// cost of the apps created or enabled by the request.
+	Current float64
+	Added   float64
+
+	// Apps names the apps created or enabled by the request.
+	Apps []string
+}
+
+// Projected returns the monthly cost the request would lead to.
+func (e *BudgetError) Projected() float64 {
+	return e.Current + e.Added
+}
+
+// Error implements the error interface.
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("runx: %s %s: projected monthly cost %.2f (%.2f running + %.2f requested) exceeds the budget of %.2f",
+		e.Operation, strings.Join(e.Apps, ", "), e.Projected(), e.Current, e.Added, e.Budget)
+}
+
+// Is matches ErrBudgetExceeded.
+func (e *BudgetError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// WithBudget refuses the CreateApp requests and the EnableApp requests
+// enabling an app when the monthly cost of the billed apps, together with the
+// cost of the apps created or enabled, would exceed budget. Costs are the
+// catalog prices over HoursPerMonth, and a refused request fails with a
+// *BudgetError without reaching the server.
+//
+// The guard lists the apps and the catalog with the credentials of the
+// guarded request, so each guarded request costs two extra calls.
+func WithBudget(budget float64) ClientOption {
+	return WithDoerMiddleware(func(next HttpRequestDoer) HttpRequestDoer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			op, params := matchOperation(req)
+			if op == nil {
+				return next.Do(req)
+			}
+			switch {
+			case op.name == "CreateApp":
+			case op.name == "EnableApp" && params["enabled"] == string(True):
+			default:
+				return next.Do(req)
+			}
+			if err := checkBudget(req, op, params, next, budget); err != nil {
+				return nil, err
+			}
+			return next.Do(req)
+		})
+	})
+}
+
+// checkBudget returns a *BudgetError when the request would exceed budget.
+func checkBudget(req *http.Request, op *operation, params map[string]string, next HttpRequestDoer, budget float64) error {
+	ctx := req.Context()
+	client := guardClient(req, op, next)
+	apps, err := client.listApps(ctx)
+	if err != nil {
+		return fmt.Errorf("runx: budget: %w", err)
+	}
+	cat, err := client.GetCatalog(ctx)
+	if err != nil {
+		return fmt.Errorf("runx: budget: %w", err)
+	}
+
+	var current Cost
+	for _, cost := range AppCosts(apps, cat) {
+		if cost.Billed {
+			current = current.Add(cost.Cost)
+		}
+	}
+
+	var added Cost
+	var names []string
+	switch op.name {
+	case "CreateApp":
+		var body CreateAppRequest
+		if err := decodeRequestBody(req, &body); err != nil {
+			return fmt.Errorf("runx: budget: %w", err)
+		}
+		requests, err := cat.Requests(body)
+		if err != nil {
+			// let the server report the unknown pack
+			return nil
+		}
+		for _, r := range requests {
+			price, _ := cat.Price(r.App)
+			added = added.Add(HourlyCost(price))
+			names = append(names, r.Name)
+		}
+	case "EnableApp":
+		app := findApp(apps, params["appId"])
+		if app == nil || billed(*app) {
+			return nil
+		}
+		price, _ := cat.Price(deref(app.App))
+		added = HourlyCost(price)
+		names = append(names, firstNonEmpty(deref(app.Name), params["appId"]))
+	}
+
+	if added.Monthly > 0 && current.Monthly+added.Monthly > budget {
+		return &BudgetError{
+			Operation: op.name,
+			Budget:    budget,
+			Current:   roundCents(current.Monthly),
+			Added:     roundCents(added.Monthly),
+			Apps:      names,
+		}
+	}
+	return nil
+}
+
+// guardClient returns a client sending requests through next to the server
+// of req, with its Authorization header.
+func guardClient(req *http.Request, op *operation, next HttpRequestDoer) *ClientWithResponses {
+	server := *req.URL
+	segments := strings.Split(strings.Trim(server.EscapedPath(), "/"), "/")
+	base := "/" + strings.Join(segments[:len(segments)-len(op.segments)], "/")
+	server.Path, server.RawPath, server.RawQuery = base, "", ""
+	auth := req.Header.Get("Authorization")
+	return &ClientWithResponses{&Client{
+		Server: strings.TrimSuffix(server.String(), "/") + "/",
+		Client: next,
+		RequestEditors: []RequestEditorFn{func(ctx context.Context, r *http.Request) error {
+			if auth != "" {
+				r.Header.Set("Authorization", auth)
+			}
+			return nil
+		}},
+	}}
+}
+
+// decodeRequestBody decodes the JSON body of req into v, leaving the body
+// readable by the next Doer.
+func decodeRequestBody(req *http.Request, v any) error {
+	if err := rewindable(req); err != nil {
+		return err
+	}
+	if req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return json.NewDecoder(body).Decode(v)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
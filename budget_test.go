@@ -0,0 +1,133 @@
+package runx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	runx "github.com/run-x-app/runx-go"
+	"github.com/run-x-app/runx-go/runxtest"
+)
+
+func createApps(ctx context.Context, client *runx.ClientWithResponses, req runx.CreateAppRequest) error {
+	rsp, err := client.CreateAppWithResponse(ctx, req)
+	if err != nil {
+		return err
+	}
+	return runx.CheckResponse("CreateApp", rsp.HTTPResponse, rsp.Body)
+}
+
+func TestWithBudget(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	// nginx costs 7.30 a month and postgres 14.60
+	client := srv.Client(runx.WithBudget(25))
+
+	// under budget
+	if err := createApps(ctx, client, runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "web", App: "nginx"}, {Name: "db", App: "postgres"}}}); err != nil {
+		t.Fatalf("CreateApp() under budget: %v", err)
+	}
+
+	// over budget, without reaching the server
+	err := createApps(ctx, client, runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "cache", App: "nginx"}}})
+	var budgetErr *runx.BudgetError
+	if !errors.Is(err, runx.ErrBudgetExceeded) || !errors.As(err, &budgetErr) {
+		t.Fatalf("CreateApp() error = %v, want a *BudgetError", err)
+	}
+	want := runx.BudgetError{Operation: "CreateApp", Budget: 25, Current: 21.9, Added: 7.3, Apps: []string{"cache"}}
+	if budgetErr.Operation != want.Operation || budgetErr.Budget != want.Budget || budgetErr.Current != want.Current ||
+		budgetErr.Added != want.Added || !slices.Equal(budgetErr.Apps, want.Apps) {
+		t.Errorf("BudgetError = %+v, want %+v", *budgetErr, want)
+	}
+	if got := budgetErr.Projected(); got != 29.2 {
+		t.Errorf("Projected() = %v, want 29.2", got)
+	}
+	if got, want := err.Error(), "runx: CreateApp cache: projected monthly cost 29.20 (21.90 running + 7.30 requested) exceeds the budget of 25.00"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if len(srv.Apps()) != 2 {
+		t.Errorf("%d apps, want the refused one not created", len(srv.Apps()))
+	}
+
+	// disabled apps are not billed, and enabling them is guarded
+	db := appByName(t, srv, "db")
+	if rsp, err := client.EnableAppWithResponse(ctx, db, runx.False); err != nil || rsp.StatusCode() != http.StatusOK {
+		t.Fatalf("EnableApp(false) = %v, %v", rsp, err)
+	}
+	if err := createApps(ctx, client, runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "cache", App: "nginx"}}}); err != nil {
+		t.Fatalf("CreateApp() under budget once db is disabled: %v", err)
+	}
+	_, err = client.EnableAppWithResponse(ctx, db, runx.True)
+	if !errors.As(err, &budgetErr) || budgetErr.Operation != "EnableApp" || budgetErr.Current != 14.6 || budgetErr.Added != 14.6 || !slices.Equal(budgetErr.Apps, []string{"db"}) {
+		t.Errorf("EnableApp(true) error = %v, want a *BudgetError for db", err)
+	}
+
+	// enabling a billed app adds nothing
+	if rsp, err := client.EnableAppWithResponse(ctx, appByName(t, srv, "web"), runx.True); err != nil || rsp.StatusCode() != http.StatusOK {
+		t.Errorf("EnableApp(true) of an enabled app = %v, %v", rsp, err)
+	}
+}
+
+func TestWithBudgetPack(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer()
+	defer srv.Close()
+
+	err := createApps(ctx, srv.Client(runx.WithBudget(20)), runx.CreateAppRequest{Pack: ptr("web")})
+	var budgetErr *runx.BudgetError
+	if !errors.As(err, &budgetErr) || budgetErr.Added != 21.9 || !slices.Equal(budgetErr.Apps, []string{"nginx", "postgres"}) {
+		t.Errorf("CreateApp(pack) error = %v, want a *BudgetError for the apps of the pack", err)
+	}
+	if err := createApps(ctx, srv.Client(runx.WithBudget(22)), runx.CreateAppRequest{Pack: ptr("web")}); err != nil {
+		t.Errorf("CreateApp(pack) under budget: %v", err)
+	}
+
+	// an unknown pack is left to the server
+	err = createApps(ctx, srv.Client(runx.WithBudget(0)), runx.CreateAppRequest{Pack: ptr("missing")})
+	if errors.Is(err, runx.ErrBudgetExceeded) || err == nil {
+		t.Errorf("CreateApp(unknown pack) error = %v, want the server error", err)
+	}
+}
+
+func TestWithBudgetGuardErrors(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	client := srv.Client(runx.WithBudget(100))
+
+	for _, op := range []string{"GetApps", "GetCatalogApps"} {
+		srv.InjectFault(runxtest.Fault{Operation: op, StatusCode: http.StatusInternalServerError, Times: 1})
+		err := createApps(ctx, client, runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "web", App: "nginx"}}})
+		if !errors.Is(err, runx.ErrServer) || !strings.HasPrefix(err.Error(), "runx: budget:") {
+			t.Errorf("CreateApp() with %s failing: %v", op, err)
+		}
+	}
+	if len(srv.Apps()) != 0 {
+		t.Error("app created while the budget could not be checked")
+	}
+
+	// other requests are not guarded
+	srv.InjectFault(runxtest.Fault{Operation: "GetCatalogApps", StatusCode: http.StatusInternalServerError, Times: 1})
+	if rsp, err := client.GetAppsWithResponse(ctx); err != nil || rsp.StatusCode() != http.StatusOK {
+		t.Errorf("GetApps() = %v, %v", rsp, err)
+	}
+}
+
+func appByName(t *testing.T, srv *runxtest.Server, name string) string {
+	t.Helper()
+	for _, app := range srv.Apps() {
+		if *app.Name == name {
+			return *app.Id
+		}
+	}
+	t.Fatalf("no app %s", name)
+	return ""
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
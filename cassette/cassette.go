@@ -0,0 +1,166 @@
+// Package cassette records the HTTP interactions of a runx.Client to a file
+// and replays them, so integration tests can run without the network.
+//
+// Record once against the real API:
+//
+//	rec := cassette.NewRecorder(http.DefaultClient)
+//	client, _ := runx.NewClientWithResponses(server, apiKey, runx.WithHTTPClient(rec))
+//	// ... exercise client ...
+//	err := rec.Save("testdata/apps.json")
+//
+// and replay in CI:
+//
+//	player, err := cassette.Load("testdata/apps.json")
+//	client, _ := runx.NewClientWithResponses(server, "", runx.WithHTTPClient(player))
+//
+// Credentials, session tokens, cookies and phone numbers are redacted before
+// the interactions are kept in memory, so they never reach the cassette file.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded HTTP request.
+type Request struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  string      `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Matcher reports whether a live request matches a recorded one. Both have
+// been redacted, so the bearer token or the phone number of the live request
+// does not prevent a match.
+type Matcher func(live, recorded Request) bool
+
+// MatchMethod matches requests with the same HTTP method.
+func MatchMethod(live, recorded Request) bool {
+	return live.Method == recorded.Method
+}
+
+// MatchPath matches requests with the same URL path.
+func MatchPath(live, recorded Request) bool {
+	return live.Path == recorded.Path
+}
+
+// MatchQuery matches requests with the same query string.
+func MatchQuery(live, recorded Request) bool {
+	return live.Query == recorded.Query
+}
+
+// MatchBody matches requests with equal bodies. JSON bodies are compared
+// after decoding, ignoring formatting and key order.
+func MatchBody(live, recorded Request) bool {
+	if live.Body == recorded.Body {
+		return true
+	}
+	var a, b any
+	if json.Unmarshal([]byte(live.Body), &a) != nil || json.Unmarshal([]byte(recorded.Body), &b) != nil {
+		return false
+	}
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return bytes.Equal(x, y)
+}
+
+// DefaultMatchers returns the matchers used unless WithMatchers is given:
+// method, path and body.
+func DefaultMatchers() []Matcher {
+	return []Matcher{MatchMethod, MatchPath, MatchBody}
+}
+
+// Option configures a Recorder or a Player.
+type Option func(*config)
+
+type config struct {
+	matchers  []Matcher
+	redactors []Redactor
+	reuse     bool
+}
+
+// WithMatchers replaces the matchers used to find the recorded interaction
+// of a live request.
+func WithMatchers(matchers ...Matcher) Option {
+	return func(c *config) {
+		c.matchers = matchers
+	}
+}
+
+// WithRedactor adds a redactor run after the default ones.
+func WithRedactor(r Redactor) Option {
+	return func(c *config) {
+		c.redactors = append(c.redactors, r)
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{matchers: DefaultMatchers(), redactors: DefaultRedactors()}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+func (c *config) match(live, recorded Request) bool {
+	for _, m := range c.matchers {
+		if !m(live, recorded) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *config) redact(i *Interaction) {
+	for _, r := range c.redactors {
+		r(i)
+	}
+}
+
+// Read loads a cassette file.
+func Read(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cassette: %w", err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("cassette: %s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Write saves the cassette to path.
+func (c *Cassette) Write(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	return nil
+}
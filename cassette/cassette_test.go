@@ -0,0 +1,230 @@
+package cassette
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	runx "github.com/run-x-app/runx-go"
+	"github.com/run-x-app/runx-go/runxtest"
+)
+
+// session exercises the client and returns what it saw, leaving out the
+// secrets, which a replayed session receives redacted and which are returned
+// apart.
+func session(t *testing.T, client, anonymous *runx.ClientWithResponses) (got, secrets []string) {
+	t.Helper()
+	ctx := context.Background()
+	must := func(op string, status int, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: %v", op, err)
+		}
+		got = append(got, op+" "+http.StatusText(status))
+	}
+
+	created, err := client.CreateAppWithResponse(ctx, runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "web", App: "nginx"}}})
+	must("CreateApp", created.StatusCode(), err)
+	apps, err := client.GetAppsWithResponse(ctx)
+	must("GetApps", apps.StatusCode(), err)
+	for _, app := range *apps.JSON200.Apps {
+		got = append(got, *app.Name+" "+*app.App)
+	}
+	missing, err := client.GetAppWithResponse(ctx, "missing")
+	must("GetApp", missing.StatusCode(), err)
+
+	auth, err := anonymous.AuthWithResponse(ctx, runx.AuthRequest{Number: runxtest.DefaultNumber})
+	must("Auth", auth.StatusCode(), err)
+	secrets = append(secrets, *auth.JSON200.Session)
+	registered, err := anonymous.RegisterWithResponse(ctx)
+	must("Register", registered.StatusCode(), err)
+	secrets = append(secrets, *registered.JSON200.Session)
+	sessions, err := client.MeSessionWithResponse(ctx)
+	must("MeSession", sessions.StatusCode(), err)
+	for _, s := range *sessions.JSON200.Sessions {
+		secrets = append(secrets, *s.Id)
+		deleted, err := client.DeleteSessionWithResponse(ctx, *s.Id)
+		must("DeleteSession", deleted.StatusCode(), err)
+	}
+	me, err := client.MeWithResponse(ctx)
+	must("Me", me.StatusCode(), err)
+	got = append(got, *me.JSON200.User.Email)
+	secrets = append(secrets, *me.JSON200.User.ApiKey)
+	number, err := client.RevealNumberWithResponse(ctx)
+	must("RevealNumber", number.StatusCode(), err)
+	secrets = append(secrets, *number.JSON200.Number)
+	key, err := client.GenerateApiKeyWithResponse(ctx)
+	must("GenerateApiKey", key.StatusCode(), err)
+	secrets = append(secrets, *key.JSON200.ApiKey)
+	return got, secrets
+}
+
+func newClient(t *testing.T, server, token string, doer runx.HttpRequestDoer) *runx.ClientWithResponses {
+	t.Helper()
+	client, err := runx.NewClientWithResponses(server, token, runx.WithHTTPClient(doer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestRecordReplay(t *testing.T) {
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	rec := NewRecorder(http.DefaultClient)
+	recorded, secrets := session(t, newClient(t, srv.URL, runxtest.DefaultAPIKey, rec), newClient(t, srv.URL, "", rec))
+	// the session listed is the one opened by Auth, Register made another user
+	if len(secrets) != 6 || secrets[2] != secrets[0] {
+		t.Fatalf("secrets = %q, want two tokens, a session, two keys and a number", secrets)
+	}
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err := rec.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range append(secrets, runxtest.DefaultAPIKey) {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, data)
+		}
+	}
+
+	// the player answers without the server, whatever the credentials
+	srv.Close()
+	player, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, _ := session(t, newClient(t, srv.URL, "another-key", player), newClient(t, srv.URL, "", player))
+	if !slices.Equal(replayed, recorded) {
+		t.Errorf("replayed %q, want %q", replayed, recorded)
+	}
+	if unused := player.Unused(); len(unused) != 0 {
+		t.Errorf("Unused() = %+v", unused)
+	}
+
+	// every interaction has been used once
+	_, err = newClient(t, srv.URL, "", player).GetAppsWithResponse(context.Background())
+	var unmatched *UnmatchedError
+	if !errors.Is(err, ErrUnmatched) || !errors.As(err, &unmatched) || unmatched.Request.Path != "/app" {
+		t.Errorf("GetApps() error = %v, want an unmatched /app", err)
+	}
+	player, err = Load(path, WithReuse())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newClient(t, srv.URL, "", player)
+	for range 2 {
+		if rsp, err := client.GetAppsWithResponse(context.Background()); err != nil || len(*rsp.JSON200.Apps) != 1 {
+			t.Errorf("GetApps() = %v, %v, want the recorded app", rsp, err)
+		}
+	}
+}
+
+func TestDefaultRedactors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Interaction
+		want Interaction
+	}{
+		{
+			name: "bearer token",
+			in:   Interaction{Request: Request{Method: "GET", Path: "/app", Header: http.Header{"Authorization": {"Bearer secret"}}}},
+			want: Interaction{Request: Request{Method: "GET", Path: "/app", Header: http.Header{"Authorization": {"Bearer REDACTED"}}}},
+		},
+		{
+			name: "cookies",
+			in: Interaction{
+				Request:  Request{Method: "GET", Path: "/me", Header: http.Header{"Cookie": {"session=secret"}}},
+				Response: Response{StatusCode: 200, Header: http.Header{"Set-Cookie": {"session=secret; HttpOnly", "theme=dark"}}},
+			},
+			want: Interaction{
+				Request:  Request{Method: "GET", Path: "/me", Header: http.Header{"Cookie": {"REDACTED"}}},
+				Response: Response{StatusCode: 200, Header: http.Header{"Set-Cookie": {"REDACTED", "REDACTED"}}},
+			},
+		},
+		{
+			name: "generated API key",
+			in:   Interaction{Request: Request{Method: "POST", Path: "/me/key/generate"}, Response: Response{Body: `{"api_key":"secret"}`}},
+			want: Interaction{Request: Request{Method: "POST", Path: "/me/key/generate"}, Response: Response{Body: `{"api_key":"REDACTED"}`}},
+		},
+		{
+			name: "API key of the user",
+			in:   Interaction{Request: Request{Method: "GET", Path: "/v1/me"}, Response: Response{Body: `{"user":{"api_key":"secret","email":"user@example.com"}}`}},
+			want: Interaction{Request: Request{Method: "GET", Path: "/v1/me"}, Response: Response{Body: `{"user":{"api_key":"REDACTED","email":"user@example.com"}}`}},
+		},
+		{
+			name: "session of Auth",
+			in:   Interaction{Request: Request{Method: "POST", Path: "/auth", Body: `{"number":"+33612345678"}`}, Response: Response{Body: `{"session":"secret"}`}},
+			want: Interaction{Request: Request{Method: "POST", Path: "/auth", Body: `{"number":"REDACTED"}`}, Response: Response{Body: `{"session":"REDACTED"}`}},
+		},
+		{
+			name: "session of Register",
+			in:   Interaction{Request: Request{Method: "POST", Path: "/register"}, Response: Response{Body: `{"session":"secret"}`}},
+			want: Interaction{Request: Request{Method: "POST", Path: "/register"}, Response: Response{Body: `{"session":"REDACTED"}`}},
+		},
+		{
+			name: "session ids",
+			in:   Interaction{Request: Request{Method: "GET", Path: "/me/session"}, Response: Response{Body: `{"sessions":[{"id":"s1","ip":"10.0.0.1"},{"id":"s2"}]}`}},
+			want: Interaction{Request: Request{Method: "GET", Path: "/me/session"}, Response: Response{Body: `{"sessions":[{"id":"REDACTED","ip":"10.0.0.1"},{"id":"REDACTED"}]}`}},
+		},
+		{
+			name: "deleted session",
+			in:   Interaction{Request: Request{Method: "DELETE", Path: "/v1/me/session/secret"}},
+			want: Interaction{Request: Request{Method: "DELETE", Path: "/v1/me/session/REDACTED"}},
+		},
+		{
+			name: "revealed number",
+			in:   Interaction{Request: Request{Method: "GET", Path: "/me/number"}, Response: Response{Body: `{"number":"+33612345678"}`}},
+			want: Interaction{Request: Request{Method: "GET", Path: "/me/number"}, Response: Response{Body: `{"number":"REDACTED"}`}},
+		},
+		{
+			name: "error response",
+			in:   Interaction{Request: Request{Method: "POST", Path: "/auth"}, Response: Response{Body: `{"error":"user not found"}`}},
+			want: Interaction{Request: Request{Method: "POST", Path: "/auth"}, Response: Response{Body: `{"error":"user not found"}`}},
+		},
+		{
+			name: "body that is not JSON",
+			in:   Interaction{Request: Request{Method: "GET", Path: "/me"}, Response: Response{Body: "api_key=secret"}},
+			want: Interaction{Request: Request{Method: "GET", Path: "/me"}, Response: Response{Body: "api_key=secret"}},
+		},
+		{
+			name: "no secret",
+			in:   Interaction{Request: Request{Method: "GET", Path: "/me/billing"}, Response: Response{Body: `{"session":"not one"}`}},
+			want: Interaction{Request: Request{Method: "GET", Path: "/me/billing"}, Response: Response{Body: `{"session":"not one"}`}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.in
+			for _, r := range DefaultRedactors() {
+				r(&got)
+			}
+			if got.Request.Method != tt.want.Request.Method || got.Request.Path != tt.want.Request.Path || got.Request.Body != tt.want.Request.Body ||
+				got.Response.Body != tt.want.Response.Body ||
+				!equalHeader(got.Request.Header, tt.want.Request.Header) || !equalHeader(got.Response.Header, tt.want.Response.Header) {
+				t.Errorf("redacted %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func equalHeader(a, b http.Header) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if !slices.Equal(v, b[k]) {
+			return false
+		}
+	}
+	return true
+}
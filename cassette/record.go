@@ -0,0 +1,93 @@
+package cassette
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+
+	runx "github.com/run-x-app/runx-go"
+)
+
+// Recorder is a runx.HttpRequestDoer sending requests through another doer
+// and recording every interaction.
+type Recorder struct {
+	next   runx.HttpRequestDoer
+	config *config
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder returns a Recorder sending requests through next.
+func NewRecorder(next runx.HttpRequestDoer, opts ...Option) *Recorder {
+	return &Recorder{next: next, config: newConfig(opts)}
+}
+
+// Do implements runx.HttpRequestDoer. Failed requests are not recorded.
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	recorded, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := r.next.Do(req)
+	if err != nil {
+		return rsp, err
+	}
+	body, err := io.ReadAll(rsp.Body)
+	rsp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	rsp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// the length no longer holds once the body is redacted
+	header := rsp.Header.Clone()
+	header.Del("Content-Length")
+	i := Interaction{
+		Request: recorded,
+		Response: Response{
+			StatusCode: rsp.StatusCode,
+			Header:     header,
+			Body:       string(body),
+		},
+	}
+	r.config.redact(&i)
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, i)
+	r.mu.Unlock()
+	return rsp, nil
+}
+
+// Cassette returns a copy of the interactions recorded so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Cassette{Interactions: append([]Interaction(nil), r.cassette.Interactions...)}
+}
+
+// Save writes the interactions recorded so far to path.
+func (r *Recorder) Save(path string) error {
+	return r.Cassette().Write(path)
+}
+
+// newRequest copies req, leaving its body readable.
+func newRequest(req *http.Request) (Request, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return Request{}, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return Request{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.RawQuery,
+		Header: req.Header.Clone(),
+		Body:   string(body),
+	}, nil
+}
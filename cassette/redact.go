@@ -0,0 +1,127 @@
+package cassette
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Redacted replaces the secrets removed from an interaction.
+const Redacted = "REDACTED"
+
+// Redactor removes secrets from an interaction before it is recorded, and
+// from live requests before they are matched during replay.
+type Redactor func(i *Interaction)
+
+// DefaultRedactors returns the redactors always applied: RedactAuthorization,
+// RedactCookies, RedactAPIKey, RedactSession and RedactNumber.
+func DefaultRedactors() []Redactor {
+	return []Redactor{RedactAuthorization, RedactCookies, RedactAPIKey, RedactSession, RedactNumber}
+}
+
+// RedactAuthorization hides the bearer token of the request.
+func RedactAuthorization(i *Interaction) {
+	if i.Request.Header.Get("Authorization") != "" {
+		i.Request.Header.Set("Authorization", "Bearer "+Redacted)
+	}
+}
+
+// RedactCookies hides the cookies sent with the request and set by the
+// response.
+func RedactCookies(i *Interaction) {
+	if i.Request.Header.Get("Cookie") != "" {
+		i.Request.Header.Set("Cookie", Redacted)
+	}
+	if cookies := i.Response.Header.Values("Set-Cookie"); len(cookies) > 0 {
+		redacted := make([]string, len(cookies))
+		for n := range cookies {
+			redacted[n] = Redacted
+		}
+		i.Response.Header["Set-Cookie"] = redacted
+	}
+}
+
+// RedactAPIKey hides the key returned by GenerateApiKey and the one of the
+// user returned by Me.
+func RedactAPIKey(i *Interaction) {
+	switch {
+	case i.Request.Method == http.MethodPost && strings.HasSuffix(i.Request.Path, "/me/key/generate"):
+		redactField(&i.Response.Body, "api_key")
+	case i.Request.Method == http.MethodGet && strings.HasSuffix(i.Request.Path, "/me"):
+		redactField(&i.Response.Body, "user", "api_key")
+	}
+}
+
+// RedactSession hides the session tokens returned by Auth and Register, the
+// session ids listed by MeSession, which are the tokens themselves, and the
+// id in the path of DeleteSession.
+func RedactSession(i *Interaction) {
+	switch {
+	case i.Request.Method == http.MethodPost && (strings.HasSuffix(i.Request.Path, "/auth") || strings.HasSuffix(i.Request.Path, "/register")):
+		redactField(&i.Response.Body, "session")
+	case i.Request.Method == http.MethodGet && strings.HasSuffix(i.Request.Path, "/me/session"):
+		redactField(&i.Response.Body, "sessions", "*", "id")
+	}
+	if n := strings.Index(i.Request.Path, "/me/session/"); n >= 0 {
+		i.Request.Path = i.Request.Path[:n] + "/me/session/" + Redacted
+	}
+}
+
+// RedactNumber hides the phone number returned by RevealNumber and sent to Auth.
+func RedactNumber(i *Interaction) {
+	switch {
+	case i.Request.Method == http.MethodGet && strings.HasSuffix(i.Request.Path, "/me/number"):
+		redactField(&i.Response.Body, "number")
+	case i.Request.Method == http.MethodPost && strings.HasSuffix(i.Request.Path, "/auth"):
+		redactField(&i.Request.Body, "number")
+	}
+}
+
+// redactField replaces the field of a JSON object body found by following
+// path, where "*" stands for every element of an array. Bodies that are not
+// JSON objects, or lack the field, are left untouched.
+func redactField(body *string, path ...string) {
+	var m map[string]any
+	if json.Unmarshal([]byte(*body), &m) != nil {
+		return
+	}
+	if !redactPath(m, path) {
+		return
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	*body = string(data)
+}
+
+// redactPath replaces the values found by following path in v and reports
+// whether it found any.
+func redactPath(v any, path []string) bool {
+	found := false
+	switch t := v.(type) {
+	case map[string]any:
+		f, ok := t[path[0]]
+		switch {
+		case !ok:
+		case len(path) == 1:
+			t[path[0]] = Redacted
+			found = true
+		default:
+			found = redactPath(f, path[1:])
+		}
+	case []any:
+		if path[0] != "*" {
+			return false
+		}
+		for n, item := range t {
+			if len(path) == 1 {
+				t[n] = Redacted
+				found = true
+			} else if redactPath(item, path[1:]) {
+				found = true
+			}
+		}
+	}
+	return found
+}
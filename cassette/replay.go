@@ -0,0 +1,135 @@
+package cassette
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrUnmatched is matched by the errors returned for requests that no
+// recorded interaction matches.
+var ErrUnmatched = errors.New("cassette: unmatched request")
+
+// UnmatchedError is returned by Player.Do for a request that no recorded
+// interaction matches. The request has been redacted.
+type UnmatchedError struct {
+	Request Request
+}
+
+func (e *UnmatchedError) Error() string {
+	msg := fmt.Sprintf("cassette: no recorded interaction matches %s %s", e.Request.Method, e.Request.Path)
+	if e.Request.Query != "" {
+		msg += "?" + e.Request.Query
+	}
+	if e.Request.Body != "" {
+		msg += " with body " + e.Request.Body
+	}
+	return msg
+}
+
+// Is makes errors.Is(err, ErrUnmatched) true.
+func (e *UnmatchedError) Is(target error) bool {
+	return target == ErrUnmatched
+}
+
+// WithReuse lets a Player replay the last matching interaction again once
+// every matching interaction has been used, which suits polling loops whose
+// number of iterations differs between recording and replay.
+func WithReuse() Option {
+	return func(c *config) {
+		c.reuse = true
+	}
+}
+
+// Player is a runx.HttpRequestDoer answering requests from a cassette
+// without using the network. Each recorded interaction is used once, in
+// order, and requests that match none of the remaining ones fail with an
+// *UnmatchedError.
+type Player struct {
+	config *config
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// Load returns a Player replaying the cassette file at path.
+func Load(path string, opts ...Option) (*Player, error) {
+	c, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewPlayer(c, opts...), nil
+}
+
+// NewPlayer returns a Player replaying c.
+func NewPlayer(c *Cassette, opts ...Option) *Player {
+	return &Player{
+		config:       newConfig(opts),
+		interactions: c.Interactions,
+		used:         make([]bool, len(c.Interactions)),
+	}
+}
+
+// Do implements runx.HttpRequestDoer.
+func (p *Player) Do(req *http.Request) (*http.Response, error) {
+	live, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	i := Interaction{Request: live}
+	p.config.redact(&i)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	last := -1
+	for n, recorded := range p.interactions {
+		if !p.config.match(i.Request, recorded.Request) {
+			continue
+		}
+		if !p.used[n] {
+			p.used[n] = true
+			return p.interactions[n].Response.response(req), nil
+		}
+		last = n
+	}
+	if p.config.reuse && last >= 0 {
+		return p.interactions[last].Response.response(req), nil
+	}
+	return nil, &UnmatchedError{Request: i.Request}
+}
+
+// Unused returns the recorded interactions that have not been replayed,
+// letting a test check that it made every expected request.
+func (p *Player) Unused() []Interaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var unused []Interaction
+	for n, used := range p.used {
+		if !used {
+			unused = append(unused, p.interactions[n])
+		}
+	}
+	return unused
+}
+
+func (r Response) response(req *http.Request) *http.Response {
+	header := r.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
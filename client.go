@@ -191,6 +191,17 @@ type HttpRequestDoer interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// DoerMiddleware decorates a HttpRequestDoer with additional behaviour.
+type DoerMiddleware func(next HttpRequestDoer) HttpRequestDoer
+
+// DoerFunc is an adapter to allow the use of ordinary functions as HttpRequestDoer.
+type DoerFunc func(req *http.Request) (*http.Response, error)
+
+// Do calls f(req).
+func (f DoerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
 // Client which conforms to the OpenAPI3 specification for this service.
 type Client struct {
 	// The endpoint of the server conforming to this interface, with scheme,
@@ -206,21 +217,34 @@ type Client struct {
 	// A list of callbacks for modifying requests which are generated before sending over
 	// the network.
 	RequestEditors []RequestEditorFn
+
+	// middlewares wrap Client once construction is complete, see WithDoerMiddleware.
+	middlewares []DoerMiddleware
+
+	// limiter throttles requests, see WithRateLimit and WithMaxInFlight.
+	limiter *Limiter
+
+	// sharedLimiter reports whether limiter was given through WithLimiter,
+	// in which case it may not be reconfigured.
+	sharedLimiter bool
 }
 
 // ClientOption allows setting custom parameters during construction
 type ClientOption func(*Client) error
 
-// Creates a new Client, with reasonable defaults
+// Creates a new Client, with reasonable defaults. An empty bearer sends no
+// Authorization header, leaving it to WithCredentials or to the caller.
 func NewClient(server string, bearer string, opts ...ClientOption) (*Client, error) {
 
-	bearerAuth, err := securityprovider.NewSecurityProviderBearerToken(bearer)
+	if bearer != "" {
+		bearerAuth, err := securityprovider.NewSecurityProviderBearerToken(bearer)
 
-	if err != nil {
-		return nil, err
-	}
+		if err != nil {
+			return nil, err
+		}
 
-	opts = append(opts, WithRequestEditorFn(bearerAuth.Intercept))
+		opts = append(opts, WithRequestEditorFn(bearerAuth.Intercept))
+	}
 
 	// create a client with sane default values
 	client := Client{
@@ -240,6 +264,10 @@ func NewClient(server string, bearer string, opts ...ClientOption) (*Client, err
 	if client.Client == nil {
 		client.Client = &http.Client{}
 	}
+	// wrap the doer so that the first registered middleware sees requests first
+	for i := len(client.middlewares) - 1; i >= 0; i-- {
+		client.Client = client.middlewares[i](client.Client)
+	}
 	return &client, nil
 }
 
@@ -252,6 +280,17 @@ func WithHTTPClient(doer HttpRequestDoer) ClientOption {
 	}
 }
 
+// WithDoerMiddleware registers a middleware around the client's Doer. The
+// middlewares are applied once all options have been processed, so they also
+// wrap a Doer given through WithHTTPClient regardless of option order. The
+// first registered middleware is the outermost one.
+func WithDoerMiddleware(mw DoerMiddleware) ClientOption {
+	return func(c *Client) error {
+		c.middlewares = append(c.middlewares, mw)
+		return nil
+	}
+}
+
 // WithRequestEditorFn allows setting up a callback function, which will be
 // called right before sending the request. This can be used to mutate the request.
 func WithRequestEditorFn(fn RequestEditorFn) ClientOption {
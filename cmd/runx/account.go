@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	runx "github.com/run-x-app/runx-go"
+	"github.com/run-x-app/runx-go/printers"
+)
+
+func runMe(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("me", flag.ContinueOnError)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 && args[0] == "number" {
+		return meNumber(ctx, c)
+	}
+	if err := expectArgs(fs, args, 0, "[number]"); err != nil {
+		return err
+	}
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	rsp, err := client.MeWithResponse(ctx)
+	if err != nil {
+		return err
+	}
+	if rsp.JSON200 == nil || rsp.JSON200.User == nil {
+		return fmt.Errorf("no user returned")
+	}
+	return c.printSummary(rsp.JSON200.User, rsp.JSON200)
+}
+
+func meNumber(ctx context.Context, c *cli) error {
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	rsp, err := client.RevealNumberWithResponse(ctx)
+	if err != nil {
+		return err
+	}
+	if rsp.JSON200 == nil || rsp.JSON200.Number == nil {
+		return fmt.Errorf("no number returned")
+	}
+	_, err = fmt.Fprintln(c.stdout, *rsp.JSON200.Number)
+	return err
+}
+
+func runCatalog(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("catalog", flag.ContinueOnError)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 0, ""); err != nil {
+		return err
+	}
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	rsp, err := client.GetCatalogAppsWithResponse(ctx)
+	if err != nil {
+		return err
+	}
+	if rsp.JSON200 == nil {
+		return fmt.Errorf("no catalog returned")
+	}
+	if c.format() != printers.FormatTable {
+		return c.print(rsp.JSON200)
+	}
+	catalog := []runx.CatalogApp{}
+	if rsp.JSON200.Catalog != nil {
+		catalog = *rsp.JSON200.Catalog
+	}
+	if err := c.print(catalog); err != nil {
+		return err
+	}
+	if rsp.JSON200.Packs == nil || len(*rsp.JSON200.Packs) == 0 {
+		return nil
+	}
+	fmt.Fprintln(c.stdout)
+	return c.print(*rsp.JSON200.Packs)
+}
+
+func runBilling(ctx context.Context, c *cli, args []string) error {
+	if len(args) > 0 && args[0] == "report" {
+		return billingReport(ctx, c, args[1:])
+	}
+	fs := flag.NewFlagSet("billing", flag.ContinueOnError)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 0, "[report]"); err != nil {
+		return err
+	}
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	rsp, err := client.MeBillingWithResponse(ctx)
+	if err != nil {
+		return err
+	}
+	payments := []runx.Payment{}
+	if rsp.JSON200 != nil && rsp.JSON200.Payments != nil {
+		payments = *rsp.JSON200.Payments
+	}
+	return c.print(payments)
+}
+
+func billingReport(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("billing report", flag.ContinueOnError)
+	from := fs.String("from", "", "first day of the report, as 2006-01-02 or RFC 3339 (default: oldest record)")
+	to := fs.String("to", "", "last day of the report, included, as 2006-01-02 or RFC 3339 (default: now)")
+	period := fs.String("period", string(runx.PeriodMonth), "aggregation period: day, week or month")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 0, ""); err != nil {
+		return err
+	}
+	p, err := runx.ParseReportPeriod(*period)
+	if err != nil {
+		return usagef("%v", err)
+	}
+	start, err := parseDate(*from, false)
+	if err != nil {
+		return usagef("invalid --from: %v", err)
+	}
+	end, err := parseDate(*to, true)
+	if err != nil {
+		return usagef("invalid --to: %v", err)
+	}
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	report, err := client.BillingReport(ctx, start, end, p)
+	if err != nil {
+		return err
+	}
+	switch c.format() {
+	case printers.FormatTable:
+		if err := c.print(report.Periods); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.stdout, "\ntotal payments %.2f, total consumption %.2f, credit %.2f, limit %.2f, remaining %.2f\n",
+			report.TotalPayments, report.TotalConsumption, report.Credit, report.Limit, report.Remaining)
+		return err
+	case printers.FormatCSV:
+		return c.print(report.Periods)
+	}
+	return c.print(report)
+}
+
+// parseDate parses a 2006-01-02 date, in UTC, or an RFC 3339 time. With end,
+// a date designates the end of that day. An empty value yields the zero time.
+func parseDate(s string, end bool) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+func runSessions(ctx context.Context, c *cli, args []string) error {
+	return subcommand(ctx, c, "sessions", args, map[string]func(context.Context, *cli, []string) error{
+		"audit":         sessionsAudit,
+		"list":          sessionsList,
+		"revoke":        sessionsRevoke,
+		"revoke-others": sessionsRevokeOthers,
+	})
+}
+
+func sessionsList(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("sessions list", flag.ContinueOnError)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 0, ""); err != nil {
+		return err
+	}
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	rsp, err := client.MeSessionWithResponse(ctx)
+	if err != nil {
+		return err
+	}
+	sessions := []runx.SessionInfo{}
+	if rsp.JSON200 != nil && rsp.JSON200.Sessions != nil {
+		sessions = *rsp.JSON200.Sessions
+	}
+	return c.print(sessions)
+}
+
+func sessionsRevoke(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("sessions revoke", flag.ContinueOnError)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return usagef("usage: runx sessions revoke <session-id>...")
+	}
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	for _, id := range args {
+		if _, err := client.DeleteSessionWithResponse(ctx, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.stdout, "session %s revoked\n", id)
+	}
+	return nil
+}
+
+func sessionsRevokeOthers(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("sessions revoke-others", flag.ContinueOnError)
+	concurrency := fs.Int("concurrency", 4, "maximum number of sessions revoked at once")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 0, "[--concurrency <n>]"); err != nil {
+		return err
+	}
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	token, err := c.token(ctx, c.credentials())
+	if err != nil {
+		return err
+	}
+	result, err := client.RevokeOtherSessions(ctx, token, runx.WithRevokeConcurrency(*concurrency))
+	if errors.Is(err, runx.ErrNoCurrentSession) {
+		return usagef("the current credentials are not a session, log in with runx auth first")
+	}
+	if result != nil {
+		for _, id := range result.Revoked {
+			fmt.Fprintf(c.stdout, "session %s revoked\n", id)
+		}
+	}
+	return err
+}
+
+func sessionsAudit(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("sessions audit", flag.ContinueOnError)
+	var policy runx.SessionPolicy
+	var networks, agents *[]string
+	fs.Var(listFlag{&networks}, "known-network", "trusted IP or CIDR prefix, may be repeated")
+	fs.Var(listFlag{&agents}, "known-agent", "trusted user agent prefix, may be repeated")
+	fs.DurationVar(&policy.MaxAge, "max-age", 0, "flag the sessions older than this duration")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 0, ""); err != nil {
+		return err
+	}
+	if networks != nil {
+		policy.KnownNetworks = *networks
+	}
+	if agents != nil {
+		policy.KnownUserAgents = *agents
+	}
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	token, err := c.token(ctx, c.credentials())
+	if err != nil {
+		return err
+	}
+	report, err := client.AuditSessions(ctx, token, policy)
+	if err != nil {
+		return err
+	}
+	return c.printSummary(report.Sessions, report)
+}
+
+func runKey(ctx context.Context, c *cli, args []string) error {
+	return subcommand(ctx, c, "key", args, map[string]func(context.Context, *cli, []string) error{
+		"rotate": keyRotate,
+	})
+}
+
+func keyRotate(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("key rotate", flag.ContinueOnError)
+	save := fs.Bool("save", false, "write the new key to the api_key entry of the configuration file")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 0, "[--save]"); err != nil {
+		return err
+	}
+	profile, err := c.profile()
+	if err != nil {
+		return err
+	}
+	token, err := c.token(ctx, c.credentials())
+	if err != nil {
+		return err
+	}
+	creds := runx.NewMutableCredentials(token)
+	client, err := runx.NewClientWithCredentials(c.serverURL(profile), creds, c.clientOptions(profile)...)
+	if err != nil {
+		return err
+	}
+	var opts []runx.RotateOption
+	if *save {
+		opts = append(opts, runx.WithCredentialStore(runx.ProfileStore(c.configPath, c.context)))
+	}
+	rotation, err := runx.RotateAPIKey(ctx, client, creds, opts...)
+	if rotation != nil {
+		// print the key even when a later step failed, it is the only copy
+		fmt.Fprintln(c.stdout, rotation.NewKey)
+	}
+	if err != nil {
+		return err
+	}
+	state := "still accepted"
+	if rotation.OldKeyRevoked {
+		state = "revoked"
+	}
+	fmt.Fprintf(c.stderr, "new key %s, old key %s %s\n", rotation.NewFingerprint, rotation.OldFingerprint, state)
+	return nil
+}
+
+func runAuth(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("auth", flag.ContinueOnError)
+	number := fs.String("number", "", "phone number in E.164 form, prompted for when omitted")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 0, "[--number <phone-number>]"); err != nil {
+		return err
+	}
+	profile, err := c.profile()
+	if err != nil {
+		return err
+	}
+	var session *runx.Session
+	if *number == "" {
+		session, err = runx.PromptLogin(ctx, c.serverURL(profile), c.stdin, c.stderr, c.clientOptions(profile)...)
+	} else {
+		session, err = runx.Login(ctx, c.serverURL(profile), *number, c.clientOptions(profile)...)
+	}
+	if errors.Is(err, runx.ErrInvalidNumber) {
+		return usagef("%s", strings.TrimPrefix(err.Error(), "runx: "))
+	}
+	if err != nil {
+		return err
+	}
+	return saveSession(c, session)
+}
+
+func runRegister(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("register", flag.ContinueOnError)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 0, ""); err != nil {
+		return err
+	}
+	profile, err := c.profile()
+	if err != nil {
+		return err
+	}
+	session, err := runx.Register(ctx, c.serverURL(profile), c.clientOptions(profile)...)
+	if err != nil {
+		return err
+	}
+	return saveSession(c, session)
+}
+
+// saveSession caches the session token for the next commands and prints it.
+func saveSession(c *cli, session *runx.Session) error {
+	if err := runx.SaveSession("", session.Token); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(c.stdout, session.Token)
+	return err
+}
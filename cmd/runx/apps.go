@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	runx "github.com/run-x-app/runx-go"
+	"github.com/run-x-app/runx-go/printers"
+)
+
+func runApps(ctx context.Context, c *cli, args []string) error {
+	return subcommand(ctx, c, "apps", args, map[string]func(context.Context, *cli, []string) error{
+		"list":        appsList,
+		"get":         appsGet,
+		"create":      appsCreate,
+		"update":      appsUpdate,
+		"delete":      appsDelete,
+		"enable":      func(ctx context.Context, c *cli, args []string) error { return appsEnable(ctx, c, args, runx.True) },
+		"disable":     func(ctx context.Context, c *cli, args []string) error { return appsEnable(ctx, c, args, runx.False) },
+		"restart":     appsRestart,
+		"deploy-pack": appsDeployPack,
+	})
+}
+
+func appsList(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("apps list", flag.ContinueOnError)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 0, ""); err != nil {
+		return err
+	}
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	rsp, err := client.GetAppsWithResponse(ctx)
+	if err != nil {
+		return err
+	}
+	apps := []runx.AppExtended{}
+	if rsp.JSON200 != nil && rsp.JSON200.Apps != nil {
+		apps = *rsp.JSON200.Apps
+	}
+	return c.print(apps)
+}
+
+func appsGet(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("apps get", flag.ContinueOnError)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 1, "<app-id>"); err != nil {
+		return err
+	}
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	rsp, err := client.GetAppWithResponse(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if rsp.JSON200 == nil || rsp.JSON200.App == nil {
+		return fmt.Errorf("no app returned")
+	}
+	return c.printSummary(rsp.JSON200.App, rsp.JSON200)
+}
+
+func appsCreate(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("apps create", flag.ContinueOnError)
+	file := fs.String("f", "", "JSON file holding a CreateAppRequest, instead of the flags below")
+	var app runx.AppRequest
+	var pack *string
+	fs.StringVar(&app.Name, "name", "", "application name")
+	fs.StringVar(&app.App, "app", "", "catalog application id")
+	fs.Var(stringFlag{&app.Cmd}, "cmd", "command")
+	fs.Var(intFlag{&app.Cpu}, "cpu", "CPU count")
+	fs.Var(intFlag{&app.Ram}, "ram", "RAM")
+	fs.Var(intFlag{&app.Disk}, "disk", "disk size")
+	fs.Var(intFlag{&app.Gpu}, "gpu", "GPU count")
+	fs.Var(listFlag{&app.Env}, "env", "environment variable as KEY=VALUE, repeatable")
+	fs.Var(stringFlag{&pack}, "pack", "pack")
+	estimate := fs.Bool("estimate", false, "print the cost of the apps instead of creating them")
+	dryRun := fs.Bool("dry-run", false, "validate the request against the catalog without creating the apps")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 0, "--name <name> --app <catalog-app> [flags] | -f <file>"); err != nil {
+		return err
+	}
+
+	body := runx.CreateAppRequest{Apps: []runx.AppRequest{app}, Pack: pack}
+	if *file != "" {
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		body = runx.CreateAppRequest{}
+		if err := json.Unmarshal(data, &body); err != nil {
+			return usagef("%s: %v", *file, err)
+		}
+	} else if app.Name == "" || app.App == "" {
+		return usagef("apps create: --name and --app are required")
+	}
+
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	if *estimate {
+		return printEstimate(ctx, c, client, body)
+	}
+	if *dryRun {
+		v, err := client.NewValidator(ctx)
+		if err != nil {
+			return err
+		}
+		return reportValid(c, v.ValidateCreate(body))
+	}
+	rsp, err := client.CreateAppWithResponse(ctx, body)
+	if err != nil {
+		return err
+	}
+	if rsp.JSON200 == nil || rsp.JSON200.Apps == nil {
+		return c.print(rsp.JSON200)
+	}
+	return c.printSummary(rsp.JSON200.Apps, rsp.JSON200)
+}
+
+// reportValid prints every validation problem of err to stderr, or that the
+// request is valid.
+func reportValid(c *cli, err error) error {
+	var problems runx.ValidationErrors
+	if !errors.As(err, &problems) {
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(c.stdout, "request is valid")
+		return err
+	}
+	for _, p := range problems {
+		fmt.Fprintln(c.stderr, p)
+	}
+	return fmt.Errorf("%w, see the problems above", runx.ErrInvalidRequest)
+}
+
+// printEstimate prints the cost of the apps body would create.
+func printEstimate(ctx context.Context, c *cli, client *runx.ClientWithResponses, body runx.CreateAppRequest) error {
+	est, err := client.Estimate(ctx, body)
+	if err != nil {
+		return err
+	}
+	if c.format() != printers.FormatTable {
+		return c.print(est)
+	}
+	if err := c.print(est.Apps); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.stdout, "\ntotal %.2f per hour, %.2f per day, %.2f per month\n", est.Total.Hourly, est.Total.Daily, est.Total.Monthly)
+	return err
+}
+
+func appsUpdate(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("apps update", flag.ContinueOnError)
+	var body runx.UpdateAppRequest
+	fs.Var(stringFlag{&body.Name}, "name", "application name")
+	fs.Var(stringFlag{&body.Cmd}, "cmd", "command")
+	fs.Var(intFlag{&body.Cpu}, "cpu", "CPU count")
+	fs.Var(intFlag{&body.Ram}, "ram", "RAM")
+	fs.Var(intFlag{&body.Disk}, "disk", "disk size")
+	fs.Var(intFlag{&body.Gpu}, "gpu", "GPU count")
+	fs.Var(listFlag{&body.Env}, "env", "environment variable as KEY=VALUE, repeatable")
+	dryRun := fs.Bool("dry-run", false, "validate the request against the catalog without updating the app")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 1, "<app-id> [flags]"); err != nil {
+		return err
+	}
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		v, err := client.NewValidator(ctx)
+		if err != nil {
+			return err
+		}
+		apps, err := client.GetAppsWithResponse(ctx)
+		if err != nil {
+			return err
+		}
+		var current *runx.AppExtended
+		if apps.JSON200 != nil && apps.JSON200.Apps != nil {
+			for i, app := range *apps.JSON200.Apps {
+				if (app.Id != nil && *app.Id == args[0]) || (app.ShortId != nil && *app.ShortId == args[0]) {
+					current = &(*apps.JSON200.Apps)[i]
+				}
+			}
+		}
+		return reportValid(c, v.ValidateUpdate(body, current))
+	}
+	rsp, err := client.UpdateAppWithResponse(ctx, args[0], body)
+	if err != nil {
+		return err
+	}
+	return c.printMessage(rsp.JSON200)
+}
+
+func appsDelete(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("apps delete", flag.ContinueOnError)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 1, "<app-id>"); err != nil {
+		return err
+	}
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	rsp, err := client.DeleteAppWithResponse(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return c.printMessage(rsp.JSON200)
+}
+
+func appsEnable(ctx context.Context, c *cli, args []string, enabled runx.EnableAppParamsEnabled) error {
+	name := "apps enable"
+	if enabled == runx.False {
+		name = "apps disable"
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 1, "<app-id>"); err != nil {
+		return err
+	}
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	rsp, err := client.EnableAppWithResponse(ctx, args[0], enabled)
+	if err != nil {
+		return err
+	}
+	return c.printMessage(rsp.JSON200)
+}
+
+func appsRestart(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("apps restart", flag.ContinueOnError)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 1, "<app-id>"); err != nil {
+		return err
+	}
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	rsp, err := client.RestartAppWithResponse(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return c.printMessage(rsp.JSON200)
+}
+
+func appsDeployPack(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("apps deploy-pack", flag.ContinueOnError)
+	prefix := fs.String("prefix", "", "name the apps <prefix>-<catalog-app>")
+	timeout := fs.Duration("timeout", 10*time.Minute, "maximum wait for the apps to be running")
+	noRollback := fs.Bool("no-rollback", false, "keep the created apps when the deployment fails")
+	var sets *[]string
+	fs.Var(listFlag{&sets}, "set", "override as <catalog-app>.<name|cmd|cpu|ram|disk|gpu|env>=<value>, repeatable")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 1, "<pack> [flags]"); err != nil {
+		return err
+	}
+	opts := []runx.DeployOption{
+		runx.WithNamePrefix(*prefix),
+		runx.WithDeployWait(runx.WithWaitTimeout(*timeout)),
+	}
+	if *noRollback {
+		opts = append(opts, runx.WithoutRollback())
+	}
+	if sets != nil {
+		overrides, err := parseOverrides(*sets)
+		if err != nil {
+			return err
+		}
+		for app, o := range overrides {
+			opts = append(opts, runx.WithAppOverride(app, *o))
+		}
+	}
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+	d, err := client.DeployPack(ctx, args[0], opts...)
+	if err != nil {
+		if d != nil && len(d.RolledBack) > 0 {
+			fmt.Fprintf(c.stderr, "rolled back %s\n", strings.Join(d.RolledBack, ", "))
+		}
+		return err
+	}
+	return c.printSummary(d.Apps, d)
+}
+
+// parseOverrides parses the --set flags of apps deploy-pack into the
+// overrides of each catalog app.
+func parseOverrides(sets []string) (map[string]*runx.AppRequest, error) {
+	overrides := map[string]*runx.AppRequest{}
+	for _, set := range sets {
+		key, value, ok := strings.Cut(set, "=")
+		app, field, dotted := strings.Cut(key, ".")
+		if !ok || !dotted || app == "" {
+			return nil, usagef("invalid --set %q, expected <catalog-app>.<field>=<value>", set)
+		}
+		o := overrides[app]
+		if o == nil {
+			o = &runx.AppRequest{}
+			overrides[app] = o
+		}
+		var err error
+		switch field {
+		case "name":
+			o.Name = value
+		case "cmd":
+			o.Cmd = &value
+		case "cpu":
+			err = intFlag{&o.Cpu}.Set(value)
+		case "ram":
+			err = intFlag{&o.Ram}.Set(value)
+		case "disk":
+			err = intFlag{&o.Disk}.Set(value)
+		case "gpu":
+			err = intFlag{&o.Gpu}.Set(value)
+		case "env":
+			err = listFlag{&o.Env}.Set(value)
+		default:
+			return nil, usagef("invalid --set %q: unknown field %q", set, field)
+		}
+		if err != nil {
+			return nil, usagef("invalid --set %q: %v", set, err)
+		}
+	}
+	return overrides, nil
+}
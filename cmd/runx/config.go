@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	runx "github.com/run-x-app/runx-go"
+	"github.com/run-x-app/runx-go/printers"
+)
+
+// contextInfo is a row of runx config get-contexts.
+type contextInfo struct {
+	Current     bool   `json:"current" yaml:"current"`
+	Name        string `json:"name" yaml:"name"`
+	Server      string `json:"server" yaml:"server"`
+	Credentials string `json:"credentials" yaml:"credentials"`
+	Timeout     string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	Output      string `json:"output,omitempty" yaml:"output,omitempty"`
+}
+
+func init() {
+	printers.RegisterColumns(contextInfo{}, []printers.Column{
+		{Header: "CURRENT", Value: func(v any) string {
+			if v.(contextInfo).Current {
+				return "*"
+			}
+			return ""
+		}},
+		{Header: "NAME", Value: func(v any) string { return v.(contextInfo).Name }},
+		{Header: "SERVER", Value: func(v any) string { return v.(contextInfo).Server }},
+		{Header: "CREDENTIALS", Value: func(v any) string { return v.(contextInfo).Credentials }},
+		{Header: "TIMEOUT", Value: func(v any) string { return v.(contextInfo).Timeout }},
+		{Header: "OUTPUT", Value: func(v any) string { return v.(contextInfo).Output }},
+	})
+}
+
+func runConfig(ctx context.Context, c *cli, args []string) error {
+	return subcommand(ctx, c, "config", args, map[string]func(context.Context, *cli, []string) error{
+		"current-context": configCurrentContext,
+		"get-contexts":    configGetContexts,
+		"use-context":     configUseContext,
+	})
+}
+
+func configCurrentContext(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("config current-context", flag.ContinueOnError)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 0, ""); err != nil {
+		return err
+	}
+	cfg, err := runx.LoadConfig(c.configPath)
+	if err != nil {
+		return err
+	}
+	profile, err := cfg.Profile("")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.stdout, profile.Name)
+	return err
+}
+
+func configGetContexts(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("config get-contexts", flag.ContinueOnError)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 0, ""); err != nil {
+		return err
+	}
+	cfg, err := runx.LoadConfig(c.configPath)
+	if err != nil {
+		return err
+	}
+	current := ""
+	if profile, err := cfg.Profile(""); err == nil {
+		current = profile.Name
+	}
+	contexts := []contextInfo{}
+	for _, name := range cfg.ProfileNames() {
+		profile, err := cfg.Profile(name)
+		if err != nil {
+			return err
+		}
+		info := contextInfo{
+			Current:     name == current,
+			Name:        name,
+			Server:      profile.ServerURL(),
+			Credentials: credentialSource(profile),
+			Output:      profile.Output,
+		}
+		if profile.Timeout > 0 {
+			info.Timeout = profile.Timeout.String()
+		}
+		contexts = append(contexts, info)
+	}
+	return c.print(contexts)
+}
+
+func configUseContext(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("config use-context", flag.ContinueOnError)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := expectArgs(fs, args, 1, "<name>"); err != nil {
+		return err
+	}
+	if err := runx.SetCurrentContext(c.configPath, args[0]); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.stdout, "switched to context %q\n", args[0])
+	return err
+}
+
+// credentialSource describes where a profile takes its key from, without
+// revealing it.
+func credentialSource(p *runx.Profile) string {
+	switch {
+	case p.ApiKey != "":
+		return "api_key " + runx.Fingerprint(p.ApiKey)
+	case p.ApiKeyEnv != "":
+		return "$" + p.ApiKeyEnv
+	case p.ApiKeyFile != "":
+		return "file " + p.ApiKeyFile
+	}
+	return ""
+}
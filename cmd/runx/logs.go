@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	runx "github.com/run-x-app/runx-go"
+	"github.com/run-x-app/runx-go/printers"
+)
+
+func runLogs(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	follow := fs.Bool("f", false, "follow the logs, printing the new lines until interrupted")
+	since := fs.String("since", "", "skip the lines older than a duration such as 10m, a 2006-01-02 date or an RFC 3339 time")
+	tail := fs.Int("tail", -1, "print only the last lines of each log (default: all)")
+	interval := fs.Duration("interval", 2*time.Second, "time between two polls when following")
+	var include, exclude *[]string
+	fs.Var(listFlag{&include}, "include", "print only the lines matching a regular expression (repeatable)")
+	fs.Var(listFlag{&exclude}, "exclude", "skip the lines matching a regular expression (repeatable)")
+	level := fs.String("level", "", "print only the lines of this level or more severe: debug, info, warn or error")
+	var fields *[]string
+	fs.Var(listFlag{&fields}, "field", "print only the lines holding a field, as key=value (repeatable)")
+	render := fs.String("format", "", "re-render the lines as json, logfmt or plain (default: as read)")
+	noColor := fs.Bool("no-color", false, "do not color the app prefixes (default when not writing to a terminal or NO_COLOR is set)")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return usagef("usage: runx logs [-f] [--since <time>] [--tail <n>] <app-id>...")
+	}
+	opts := []runx.LogOption{runx.WithLogTail(*tail), runx.WithLogInterval(*interval)}
+	if *since != "" {
+		t, err := parseSince(*since)
+		if err != nil {
+			return usagef("invalid --since: %v", err)
+		}
+		opts = append(opts, runx.WithLogSince(t))
+	}
+	if *follow {
+		opts = append(opts, runx.WithLogFollow())
+	}
+	for _, f := range []struct {
+		name     string
+		patterns *[]string
+		option   func(...*regexp.Regexp) runx.LogOption
+	}{{"include", include, runx.WithLogInclude}, {"exclude", exclude, runx.WithLogExclude}} {
+		if f.patterns == nil {
+			continue
+		}
+		for _, p := range *f.patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return usagef("invalid --%s: %v", f.name, err)
+			}
+			opts = append(opts, f.option(re))
+		}
+	}
+	if *level != "" {
+		l, err := runx.ParseLogLevel(*level)
+		if err != nil {
+			return usagef("invalid --level: %v", err)
+		}
+		opts = append(opts, runx.WithMinLogLevel(l))
+	}
+	var (
+		prefixer *runx.LogPrefixer
+		enc      *json.Encoder
+	)
+	switch format := c.format(); format {
+	case printers.FormatTable:
+	case printers.FormatJSON:
+		enc = json.NewEncoder(c.stdout)
+	default:
+		return usagef("logs: unsupported output format %q, expected table or json", format)
+	}
+	if fields != nil {
+		for _, f := range *fields {
+			key, value, ok := strings.Cut(f, "=")
+			if !ok {
+				return usagef("invalid --field %q, expected key=value", f)
+			}
+			opts = append(opts, runx.WithLogField(key, value))
+		}
+	}
+	switch runx.LogFormat(*render) {
+	case "", runx.LogFormatJSON, runx.LogFormatLogfmt, runx.LogFormatPlain:
+	default:
+		return usagef("invalid --format %q, expected json, logfmt or plain", *render)
+	}
+	client, err := c.apiClient()
+	if err != nil {
+		return err
+	}
+
+	a := runx.NewLogAggregator(client, args, opts...)
+	done := make(chan error, 1)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+	for e := range a.Events() {
+		switch e.Type {
+		case runx.LogNewLine:
+			if *render != "" {
+				e.Line = e.Record.Render(runx.LogFormat(*render))
+			}
+			switch {
+			case enc != nil:
+				enc.Encode(e)
+			case len(args) > 1:
+				if prefixer == nil {
+					prefixer = runx.NewLogPrefixer(a.Names(), !*noColor && c.colorful())
+				}
+				fmt.Fprintln(c.stdout, prefixer.Format(e))
+			default:
+				fmt.Fprintln(c.stdout, e.Line)
+			}
+		case runx.LogTruncated:
+			fmt.Fprintf(c.stderr, "runx: the log of %s was truncated or rotated, lines may be missing\n", e.Name)
+		case runx.LogError:
+			fmt.Fprintf(c.stderr, "runx: %s: %s, retrying\n", e.Name, strings.TrimPrefix(e.Err.Error(), "runx: "))
+		}
+	}
+	return <-done
+}
+
+// parseSince parses a duration before now, or a time accepted by parseDate.
+func parseSince(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return parseDate(s, false)
+}
+
+// colorful reports whether the output is a terminal and NO_COLOR is unset.
+func (c *cli) colorful() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := c.stdout.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,326 @@
+// Command runx is a command-line client for the Run X API.
+//
+// Usage:
+//
+//	runx [global flags] <command> [arguments]
+//
+// The API key is read from the --api-key flag, the RUNX_API_KEY environment
+// variable, the configuration profile selected by --context or the session
+// saved by runx auth, in that order. The exit status reflects
+// the class of the failure: 2 for usage errors, 3 for network errors, 4 for
+// 4xx responses and requests rejected before being sent, and 5 for 5xx
+// responses.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	runx "github.com/run-x-app/runx-go"
+)
+
+// Exit codes of the runx command.
+const (
+	exitOK          = 0
+	exitFailure     = 1
+	exitUsage       = 2
+	exitNetwork     = 3
+	exitClientError = 4
+	exitServerError = 5
+)
+
+// command is a runx subcommand.
+type command struct {
+	usage string
+	run   func(ctx context.Context, c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"apps":     {usage: "manage applications", run: runApps},
+	"auth":     {usage: "log in with a phone number", run: runAuth},
+	"billing":  {usage: "show the payments and billing reports", run: runBilling},
+	"catalog":  {usage: "show the catalog applications", run: runCatalog},
+	"config":   {usage: "manage the configuration profiles", run: runConfig},
+	"key":      {usage: "manage the API key", run: runKey},
+	"logs":     {usage: "show the log of an application", run: runLogs},
+	"me":       {usage: "show the authenticated user", run: runMe},
+	"register": {usage: "register a new account", run: runRegister},
+	"sessions": {usage: "manage the sessions", run: runSessions},
+}
+
+// cli holds the state shared by the subcommands.
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	server     string
+	apiKey     string
+	configPath string
+	context    string
+	output     string
+
+	loaded *runx.Profile
+	client *runx.ClientWithResponses
+}
+
+// usageError is reported with exit status 2.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	fs := flag.NewFlagSet("runx", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&c.server, "server", "", "API server URL (env RUNX_SERVER)")
+	fs.StringVar(&c.apiKey, "api-key", "", "API key (env RUNX_API_KEY)")
+	fs.StringVar(&c.configPath, "config", "", "configuration file (default ~/.config/runx/config)")
+	fs.StringVar(&c.context, "context", "", "configuration profile (env RUNX_PROFILE, default the current context)")
+	fs.StringVar(&c.output, "o", "", "output format: table, json, yaml, csv, template=<template> or jsonpath=<expression> (default the profile format or table)")
+	fs.StringVar(&c.output, "output", "", "same as -o")
+	fs.Usage = func() { printUsage(stderr, fs) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		printUsage(stderr, fs)
+		return exitUsage
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "runx: unknown command %q\n", fs.Arg(0))
+		printUsage(stderr, fs)
+		return exitUsage
+	}
+	err := cmd.run(ctx, c, fs.Args()[1:])
+	if err == nil {
+		return exitOK
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	// library errors already carry the prefix
+	fmt.Fprintf(stderr, "runx: %s\n", strings.TrimPrefix(err.Error(), "runx: "))
+	return exitCode(err)
+}
+
+// exitCode maps err to the exit status of the command.
+func exitCode(err error) int {
+	var usageErr *usageError
+	var apiErr *runx.APIError
+	switch {
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.As(err, &apiErr):
+		if apiErr.StatusCode >= 500 {
+			return exitServerError
+		}
+		return exitClientError
+	case errors.Is(err, runx.ErrInvalidRequest):
+		return exitClientError
+	case errors.Is(err, context.Canceled):
+		return exitFailure
+	case isNetworkError(err):
+		return exitNetwork
+	}
+	return exitFailure
+}
+
+func printUsage(w io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintln(w, "Usage: runx [global flags] <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Global flags:")
+	fs.PrintDefaults()
+}
+
+// apiClient returns the client, creating it on first use. The token is
+// taken from the --api-key flag, the RUNX_API_KEY environment variable, the
+// selected profile or the session saved by runx auth, in that order.
+func (c *cli) apiClient() (*runx.ClientWithResponses, error) {
+	if c.client != nil {
+		return c.client, nil
+	}
+	profile, err := c.profile()
+	if err != nil {
+		return nil, err
+	}
+	credentials := c.credentials()
+	if _, err := c.token(context.Background(), credentials); err != nil {
+		return nil, err
+	}
+	client, err := runx.NewClientWithResponses(c.serverURL(profile), "", append(c.clientOptions(profile), runx.WithCredentials(credentials))...)
+	if err != nil {
+		return nil, err
+	}
+	c.client = client
+	return client, nil
+}
+
+// profile returns the profile selected by --context, RUNX_PROFILE or the
+// current context of the configuration file.
+func (c *cli) profile() (*runx.Profile, error) {
+	if c.loaded != nil {
+		return c.loaded, nil
+	}
+	profile, err := runx.LoadProfile(c.configPath, c.context)
+	if err != nil {
+		return nil, err
+	}
+	c.loaded = profile
+	return profile, nil
+}
+
+// clientOptions returns the options shared by every client of the command.
+func (c *cli) clientOptions(profile *runx.Profile) []runx.ClientOption {
+	opts := []runx.ClientOption{runx.WithAPIErrors()}
+	if profile.Timeout > 0 {
+		opts = append(opts, runx.WithHTTPClient(&http.Client{Timeout: profile.Timeout}))
+	}
+	return opts
+}
+
+// credentials returns the chain providing the token of the requests.
+func (c *cli) credentials() runx.CredentialProvider {
+	return runx.ChainCredentials(
+		runx.StaticCredentials(c.apiKey),
+		runx.EnvCredentials(),
+		runx.ProfileCredentials(c.configPath, c.context),
+		runx.SessionCredentials(""),
+	)
+}
+
+// token resolves the current token of credentials, reporting a missing one
+// as a usage error.
+func (c *cli) token(ctx context.Context, credentials runx.CredentialProvider) (string, error) {
+	token, err := credentials.Token(ctx)
+	if errors.Is(err, runx.ErrNoCredentials) {
+		return "", usagef("no API key: use --api-key, RUNX_API_KEY, the configuration file or runx auth")
+	}
+	return token, err
+}
+
+// serverURL returns the API server selected by the flags, the environment
+// or the profile.
+func (c *cli) serverURL(profile *runx.Profile) string {
+	return firstNonEmpty(c.server, os.Getenv(runx.EnvServer), profile.ServerURL())
+}
+
+// subcommand dispatches args to one of the given subcommands.
+func subcommand(ctx context.Context, c *cli, name string, args []string, subs map[string]func(context.Context, *cli, []string) error) error {
+	names := make([]string, 0, len(subs))
+	for n := range subs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	if len(args) == 0 {
+		return usagef("usage: runx %s <%s>", name, strings.Join(names, "|"))
+	}
+	sub, ok := subs[args[0]]
+	if !ok {
+		return usagef("unknown command %q, expected one of %s", name+" "+args[0], strings.Join(names, ", "))
+	}
+	return sub(ctx, c, args[1:])
+}
+
+// parseFlags parses args with fs, allowing flags after positional arguments,
+// and returns the positional arguments. A "--" ends the flags, the arguments
+// after it being positional even when they start with a dash.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				fmt.Fprintf(os.Stderr, "Usage of runx %s:\n", fs.Name())
+				fs.SetOutput(os.Stderr)
+				fs.PrintDefaults()
+				return nil, err
+			}
+			return nil, usagef("%s: %v", fs.Name(), err)
+		}
+		rest := fs.Args()
+		if parsed := len(args) - len(rest); parsed > 0 && args[parsed-1] == "--" && !flagValue(fs, args[:parsed-1]) {
+			return append(positional, rest...), nil
+		}
+		args = rest
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// flagValue reports whether the last of args is a flag of fs expecting a
+// value, which the argument following it is then, even if "--".
+func flagValue(fs *flag.FlagSet, args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	name, ok := strings.CutPrefix(args[len(args)-1], "-")
+	if !ok || strings.Contains(name, "=") {
+		return false
+	}
+	f := fs.Lookup(strings.TrimPrefix(name, "-"))
+	if f == nil {
+		return false
+	}
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return !ok || !b.IsBoolFlag()
+}
+
+// expectArgs checks the number of positional arguments.
+func expectArgs(fs *flag.FlagSet, args []string, n int, names string) error {
+	if len(args) != n {
+		return usagef("usage: runx %s %s", fs.Name(), names)
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"slices"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		want      []string
+		wantF     bool
+		wantSince string
+	}{
+		{"no arguments", nil, nil, false, ""},
+		{"positional only", []string{"app-1", "app-2"}, []string{"app-1", "app-2"}, false, ""},
+		{"flags first", []string{"-f", "--since", "1h", "app-1"}, []string{"app-1"}, true, "1h"},
+		{"flags after positional", []string{"app-1", "-f", "app-2", "--since=1h"}, []string{"app-1", "app-2"}, true, "1h"},
+		{"terminator", []string{"-f", "--", "-app", "--since", "1h"}, []string{"-app", "--since", "1h"}, true, ""},
+		{"terminator after positional", []string{"app-1", "--", "-f"}, []string{"app-1", "-f"}, false, ""},
+		{"terminator first", []string{"--", "--", "-f"}, []string{"--", "-f"}, false, ""},
+		{"dashes as a flag value", []string{"--since", "--", "app-1", "-f"}, []string{"app-1"}, true, "--"},
+		{"dashes as a value then terminator", []string{"--since", "--", "--", "-f"}, []string{"-f"}, false, "--"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+			f := fs.Bool("f", false, "")
+			since := fs.String("since", "", "")
+			got, err := parseFlags(fs, tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("positional = %q, want %q", got, tt.want)
+			}
+			if *f != tt.wantF || *since != tt.wantSince {
+				t.Errorf("-f = %v, --since = %q, want %v, %q", *f, *since, tt.wantF, tt.wantSince)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/run-x-app/runx-go/printers"
+)
+
+// format returns the output format selected by -o, or by the profile.
+func (c *cli) format() string {
+	if c.output != "" {
+		return c.output
+	}
+	if profile, err := c.profile(); err == nil && profile.Output != "" {
+		return profile.Output
+	}
+	return printers.FormatTable
+}
+
+// print writes v in the selected output format.
+func (c *cli) print(v any) error {
+	p, err := printers.New(c.format())
+	if err != nil {
+		return usagef("%v", err)
+	}
+	return p.Print(c.stdout, v)
+}
+
+// printSummary writes summary in table format, and full in any other format.
+// It lets the table show the interesting part of a response while the
+// machine-readable formats keep all of it.
+func (c *cli) printSummary(summary, full any) error {
+	if c.format() == printers.FormatTable {
+		return c.print(summary)
+	}
+	return c.print(full)
+}
+
+// printMessage writes the message of an API response.
+func (c *cli) printMessage(payload *struct {
+	Message *string `json:"message,omitempty"`
+}) error {
+	if c.format() != printers.FormatTable {
+		return c.print(payload)
+	}
+	if payload == nil || payload.Message == nil {
+		return nil
+	}
+	_, err := fmt.Fprintln(c.stdout, *payload.Message)
+	return err
+}
+
+// isNetworkError reports whether err happened before a response was received.
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	var urlErr *url.Error
+	return errors.As(err, &netErr) || errors.As(err, &urlErr)
+}
+
+// intFlag is an optional integer flag, left nil when not given.
+type intFlag struct {
+	v **int
+}
+
+func (f intFlag) String() string {
+	if f.v == nil || *f.v == nil {
+		return ""
+	}
+	return strconv.Itoa(**f.v)
+}
+
+func (f intFlag) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*f.v = &n
+	return nil
+}
+
+// stringFlag is an optional string flag, left nil when not given.
+type stringFlag struct {
+	v **string
+}
+
+func (f stringFlag) String() string {
+	if f.v == nil || *f.v == nil {
+		return ""
+	}
+	return **f.v
+}
+
+func (f stringFlag) Set(s string) error {
+	*f.v = &s
+	return nil
+}
+
+// listFlag is a repeatable string flag.
+type listFlag struct {
+	v **[]string
+}
+
+func (f listFlag) String() string {
+	if f.v == nil || *f.v == nil {
+		return ""
+	}
+	return strings.Join(**f.v, ",")
+}
+
+func (f listFlag) Set(s string) error {
+	if *f.v == nil {
+		*f.v = &[]string{}
+	}
+	**f.v = append(**f.v, s)
+	return nil
+}
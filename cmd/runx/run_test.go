@@ -0,0 +1,290 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	runx "github.com/run-x-app/runx-go"
+	"github.com/run-x-app/runx-go/runxtest"
+)
+
+// isolate keeps the command away from the configuration, the session and the
+// environment of the user running the tests.
+func isolate(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	for _, env := range []string{runx.EnvAPIKey, runx.EnvServer, runx.EnvProfile} {
+		t.Setenv(env, "")
+	}
+}
+
+// runCommand runs the command with args and returns its exit status and outputs.
+func runCommand(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, strings.NewReader(""), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func newTestServer(t *testing.T) (*runxtest.Server, []string) {
+	t.Helper()
+	isolate(t)
+	srv := runxtest.NewServer()
+	t.Cleanup(srv.Close)
+	rsp, err := srv.Client().CreateAppWithResponse(context.Background(), runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "web", App: "nginx"}}})
+	if err != nil || rsp.StatusCode() != http.StatusOK {
+		t.Fatalf("CreateApp() = %v, %v", rsp, err)
+	}
+	return srv, []string{"--server", srv.URL, "--api-key", runxtest.DefaultAPIKey}
+}
+
+func TestRunOutputFormats(t *testing.T) {
+	srv, global := newTestServer(t)
+	id := *srv.Apps()[0].Id
+
+	tests := []struct {
+		name  string
+		args  []string
+		check func(t *testing.T, out string)
+	}{
+		{
+			name: "table by default",
+			args: []string{"apps", "list"},
+			check: func(t *testing.T, out string) {
+				lines := strings.Split(strings.TrimSpace(out), "\n")
+				if len(lines) != 2 || !strings.HasPrefix(lines[0], "SHORT ID") || !strings.Contains(lines[1], "web") {
+					t.Errorf("table =\n%s", out)
+				}
+			},
+		},
+		{
+			name: "json",
+			args: []string{"-o", "json", "apps", "list"},
+			check: func(t *testing.T, out string) {
+				var apps []runx.AppExtended
+				if err := json.Unmarshal([]byte(out), &apps); err != nil || len(apps) != 1 || *apps[0].Id != id {
+					t.Errorf("json = %s, %v", out, err)
+				}
+			},
+		},
+		{
+			name: "yaml",
+			args: []string{"--output", "yaml", "apps", "list"},
+			check: func(t *testing.T, out string) {
+				if !strings.Contains(out, "  id: "+id) || !strings.Contains(out, "name: web") {
+					t.Errorf("yaml =\n%s", out)
+				}
+			},
+		},
+		{
+			name: "jsonpath",
+			args: []string{"-o", "jsonpath={[*].name}", "apps", "list"},
+			check: func(t *testing.T, out string) {
+				if strings.TrimSpace(out) != "web" {
+					t.Errorf("jsonpath = %q, want web", out)
+				}
+			},
+		},
+		{
+			name: "template",
+			args: []string{"-o", `template={{range .}}{{.name}}={{.app}} {{default "-" .log}}{{end}}`, "apps", "list"},
+			check: func(t *testing.T, out string) {
+				if strings.TrimSpace(out) != "web=nginx -" {
+					t.Errorf("template = %q, want %q", out, "web=nginx -")
+				}
+			},
+		},
+		{
+			name: "summary in table format",
+			args: []string{"apps", "get", id},
+			check: func(t *testing.T, out string) {
+				if strings.Contains(out, `"log"`) || !strings.Contains(out, "web") {
+					t.Errorf("table =\n%s", out)
+				}
+			},
+		},
+		{
+			name: "full response in json",
+			args: []string{"-o", "json", "apps", "get", id},
+			check: func(t *testing.T, out string) {
+				var rsp map[string]any
+				if err := json.Unmarshal([]byte(out), &rsp); err != nil || rsp["app"] == nil {
+					t.Errorf("json = %s, %v", out, err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, out, errOut := runCommand(append(global, tt.args...)...)
+			if code != exitOK {
+				t.Fatalf("exit status %d: %s", code, errOut)
+			}
+			tt.check(t, out)
+		})
+	}
+}
+
+func TestRunProfileOutput(t *testing.T) {
+	srv, _ := newTestServer(t)
+	config := filepath.Join(t.TempDir(), "config")
+	data := "current-context: test\nprofiles:\n  test:\n    server: " + srv.URL + "\n    api_key: " + runxtest.DefaultAPIKey + "\n    output: json\n"
+	if err := os.WriteFile(config, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, errOut := runCommand("--config", config, "apps", "list")
+	if code != exitOK || !json.Valid([]byte(out)) {
+		t.Fatalf("exit status %d, output %q, %s, want json", code, out, errOut)
+	}
+	// -o takes precedence over the profile
+	code, out, _ = runCommand("--config", config, "-o", "jsonpath={[0].app}", "apps", "list")
+	if code != exitOK || strings.TrimSpace(out) != "nginx" {
+		t.Errorf("exit status %d, output %q, want nginx", code, out)
+	}
+}
+
+func TestRunExitCodes(t *testing.T) {
+	srv, global := newTestServer(t)
+	closed := runxtest.NewServer()
+	closed.Close()
+
+	tests := []struct {
+		name    string
+		args    []string
+		fault   *runxtest.Fault
+		want    int
+		wantErr string
+	}{
+		{name: "success", args: []string{"apps", "list"}, want: exitOK},
+		{name: "help", args: []string{"-h"}, want: exitOK},
+		{name: "subcommand help", args: []string{"apps", "list", "-h"}, want: exitOK},
+		{name: "not found", args: []string{"apps", "get", "missing"}, want: exitClientError, wantErr: "runx: GetApp: 404"},
+		{name: "conflict", args: []string{"apps", "create", "--name", "web", "--app", "nginx"}, want: exitClientError, wantErr: "409"},
+		{name: "invalid request", args: []string{"apps", "create", "--name", "big", "--app", "nginx", "--cpu", "64"}, want: exitClientError, wantErr: "exceeds the limit"},
+		{
+			name:    "server error",
+			args:    []string{"apps", "list"},
+			fault:   &runxtest.Fault{Operation: "GetApps", StatusCode: http.StatusInternalServerError, Times: 1},
+			want:    exitServerError,
+			wantErr: "GetApps: 500",
+		},
+		{name: "network error", args: []string{"--server", closed.URL, "apps", "list"}, want: exitNetwork},
+		{name: "unauthorized", args: []string{"--api-key", "wrong", "apps", "list"}, want: exitClientError, wantErr: "401"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.fault != nil {
+				srv.InjectFault(*tt.fault)
+			}
+			code, _, errOut := runCommand(append(global, tt.args...)...)
+			if code != tt.want {
+				t.Errorf("exit status %d, want %d: %s", code, tt.want, errOut)
+			}
+			if !strings.Contains(errOut, tt.wantErr) {
+				t.Errorf("stderr = %q, want %q", errOut, tt.wantErr)
+			}
+			if strings.Contains(errOut, "runx: runx:") {
+				t.Errorf("stderr = %q repeats the prefix", errOut)
+			}
+		})
+	}
+}
+
+func TestRunUsageErrors(t *testing.T) {
+	_, global := newTestServer(t)
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"no command", nil, "Usage: runx"},
+		{"unknown command", []string{"frobnicate"}, `unknown command "frobnicate"`},
+		{"unknown global flag", []string{"--frobnicate", "apps", "list"}, "flag provided but not defined"},
+		{"missing subcommand", []string{"apps"}, "usage: runx apps <create|delete|deploy-pack|disable|enable|get|list|restart|update>"},
+		{"unknown subcommand", []string{"apps", "frobnicate"}, `unknown command "apps frobnicate"`},
+		{"missing argument", []string{"apps", "get"}, "usage: runx apps get <app-id>"},
+		{"extra argument", []string{"apps", "list", "web"}, "usage: runx apps list"},
+		{"unknown flag", []string{"apps", "list", "--frobnicate"}, "apps list: flag provided but not defined"},
+		{"invalid flag value", []string{"apps", "create", "--name", "x", "--app", "nginx", "--cpu", "one"}, "flag -cpu"},
+		{"unknown output format", []string{"-o", "xml", "apps", "list"}, "xml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, out, errOut := runCommand(append(global, tt.args...)...)
+			if code != exitUsage {
+				t.Errorf("exit status %d, want %d: %s", code, exitUsage, errOut)
+			}
+			if !strings.Contains(errOut, tt.wantErr) {
+				t.Errorf("stderr = %q, want %q", errOut, tt.wantErr)
+			}
+			if out != "" {
+				t.Errorf("stdout = %q, want nothing", out)
+			}
+		})
+	}
+
+	// without any credentials
+	code, _, errOut := runCommand("--server", "http://127.0.0.1:1", "apps", "list")
+	if code != exitUsage || !strings.Contains(errOut, "no API key") {
+		t.Errorf("exit status %d, stderr %q, want a usage error about the API key", code, errOut)
+	}
+}
+
+func TestRunConfig(t *testing.T) {
+	isolate(t)
+	config := filepath.Join(t.TempDir(), "config")
+	data := "# contexts\ncurrent-context: production\nprofiles:\n  production:\n    api_key: prod-key\n  research:\n    server: https://research.run-x.cloud\n    api_key_env: RESEARCH_KEY\n    output: yaml\n"
+	if err := os.WriteFile(config, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, errOut := runCommand("--config", config, "config", "current-context")
+	if code != exitOK || out != "production\n" {
+		t.Errorf("current-context = %d, %q, %s", code, out, errOut)
+	}
+
+	code, out, errOut = runCommand("--config", config, "-o", "json", "config", "get-contexts")
+	var contexts []contextInfo
+	if err := json.Unmarshal([]byte(out), &contexts); code != exitOK || err != nil {
+		t.Fatalf("get-contexts = %d, %q, %s", code, out, errOut)
+	}
+	want := []contextInfo{
+		{Current: true, Name: "production", Server: runx.DefaultServer, Credentials: "api_key " + runx.Fingerprint("prod-key")},
+		{Name: "research", Server: "https://research.run-x.cloud", Credentials: "$RESEARCH_KEY", Output: "yaml"},
+	}
+	if !slices.Equal(contexts, want) {
+		t.Errorf("get-contexts = %+v, want %+v", contexts, want)
+	}
+	if strings.Contains(out, "prod-key") {
+		t.Errorf("get-contexts reveals the key: %s", out)
+	}
+
+	code, out, errOut = runCommand("--config", config, "config", "use-context", "research")
+	if code != exitOK || out != "switched to context \"research\"\n" {
+		t.Errorf("use-context = %d, %q, %s", code, out, errOut)
+	}
+	if code, out, _ = runCommand("--config", config, "config", "current-context"); out != "research\n" {
+		t.Errorf("current-context = %d, %q, want research", code, out)
+	}
+	if got, _ := os.ReadFile(config); !strings.HasPrefix(string(got), "# contexts\ncurrent-context: research\n") {
+		t.Errorf("config =\n%s", got)
+	}
+
+	code, _, errOut = runCommand("--config", config, "config", "use-context", "staging")
+	if code != exitFailure || !strings.Contains(errOut, "profile not found: staging") {
+		t.Errorf("use-context of a missing profile = %d, %q", code, errOut)
+	}
+	code, _, errOut = runCommand("--config", config, "config", "use-context")
+	if code != exitUsage || !strings.Contains(errOut, "usage: runx config use-context <name>") {
+		t.Errorf("use-context without a name = %d, %q", code, errOut)
+	}
+}
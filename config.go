@@ -0,0 +1,307 @@
+package runx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultServer is the URL of the Run X API.
+const DefaultServer = "https://api.run-x.cloud"
+
+// Environment variables read by EnvCredentials, LoadProfile and the runx command.
+const (
+	EnvAPIKey  = "RUNX_API_KEY"
+	EnvServer  = "RUNX_SERVER"
+	EnvProfile = "RUNX_PROFILE"
+)
+
+// DefaultProfileName names the profile made of the top-level server and
+// api_key entries of a configuration file without profiles.
+const DefaultProfileName = "default"
+
+// ErrProfileNotFound is returned for a profile missing from the configuration.
+var ErrProfileNotFound = errors.New("runx: profile not found")
+
+// Config is the content of the configuration file shared by the library and
+// the runx command, for example
+//
+//	current-context: production
+//	profiles:
+//	  production:
+//	    server: https://api.run-x.cloud
+//	    api_key_env: RUNX_PROD_KEY
+//	    timeout: 30s
+//	  research:
+//	    api_key_file: ~/.secrets/runx-gpu
+//	    output: yaml
+//
+// A file holding only the top-level server and api_key entries describes a
+// single profile named "default".
+type Config struct {
+	CurrentContext string              `yaml:"current-context,omitempty"`
+	Profiles       map[string]*Profile `yaml:"profiles,omitempty"`
+
+	Server string `yaml:"server,omitempty"`
+	ApiKey string `yaml:"api_key,omitempty"`
+}
+
+// Profile gathers the settings of an account.
+type Profile struct {
+	// Name is the key of the profile in the configuration file.
+	Name string `yaml:"-"`
+
+	// Server is the API server URL, DefaultServer when empty.
+	Server string `yaml:"server,omitempty"`
+
+	// The credential source: the key itself, the environment variable or
+	// the file holding it. The first one set is used.
+	ApiKey     string `yaml:"api_key,omitempty"`
+	ApiKeyEnv  string `yaml:"api_key_env,omitempty"`
+	ApiKeyFile string `yaml:"api_key_file,omitempty"`
+
+	// Timeout bounds every request when positive.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// Output is the default output format of the runx command.
+	Output string `yaml:"output,omitempty"`
+}
+
+// DefaultConfigPath returns the location of the configuration file,
+// runx/config under the user configuration directory, e.g.
+// ~/.config/runx/config on Linux.
+func DefaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "runx", "config"), nil
+}
+
+// LoadConfig reads the configuration file at path, or at DefaultConfigPath
+// when path is empty. A missing default file yields an empty Config.
+func LoadConfig(path string) (*Config, error) {
+	explicit := path != ""
+	if !explicit {
+		var err error
+		if path, err = DefaultConfigPath(); err != nil {
+			return &Config{}, nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("runx: %w", err)
+	}
+	return parseConfig(data, path)
+}
+
+func parseConfig(data []byte, path string) (*Config, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("runx: %s: %w", path, err)
+	}
+	for name, p := range cfg.Profiles {
+		if p == nil {
+			p = &Profile{}
+			cfg.Profiles[name] = p
+		}
+		p.Name = name
+	}
+	return &cfg, nil
+}
+
+// LoadProfile reads the configuration file at path, or at DefaultConfigPath
+// when path is empty, and returns the named profile. An empty name selects
+// the profile named by RUNX_PROFILE, then the current context.
+func LoadProfile(path, name string) (*Profile, error) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = os.Getenv(EnvProfile)
+	}
+	return cfg.Profile(name)
+}
+
+// Profile returns the named profile, or the current context when name is
+// empty. Without profiles, the top-level entries form the "default" profile.
+func (c *Config) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = c.CurrentContext
+	}
+	if len(c.Profiles) == 0 && (name == "" || name == DefaultProfileName) {
+		return &Profile{Name: DefaultProfileName, Server: c.Server, ApiKey: c.ApiKey}, nil
+	}
+	if name == "" {
+		return nil, fmt.Errorf("runx: no current context, pick one of %s", strings.Join(c.ProfileNames(), ", "))
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+	return p, nil
+}
+
+// ProfileNames returns the sorted names of the profiles.
+func (c *Config) ProfileNames() []string {
+	if len(c.Profiles) == 0 {
+		return []string{DefaultProfileName}
+	}
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetCurrentContext makes name the current context of the configuration file
+// at path, or at DefaultConfigPath when path is empty. The other entries and
+// the comments are preserved.
+func SetCurrentContext(path, name string) error {
+	return updateConfig(path, func(cfg *Config, root *yaml.Node) error {
+		if _, err := cfg.Profile(name); err != nil {
+			return err
+		}
+		setMappingValue(root, "current-context", name)
+		return nil
+	})
+}
+
+// updateConfig applies update to the YAML tree of the configuration file and
+// replaces the file atomically.
+func updateConfig(path string, update func(cfg *Config, root *yaml.Node) error) error {
+	if path == "" {
+		var err error
+		if path, err = DefaultConfigPath(); err != nil {
+			return fmt.Errorf("runx: %w", err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("runx: %w", err)
+	}
+	cfg, err := parseConfig(data, path)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("runx: %s: %w", path, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("runx: %s: not a mapping", path)
+	}
+	if err := update(cfg, root); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("runx: %w", err)
+	}
+	return writeFileAtomic(path, buf.Bytes(), 0o600)
+}
+
+// mappingValue returns the value of key in a YAML mapping node, or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue sets the scalar value of key in a YAML mapping node.
+func setMappingValue(mapping *yaml.Node, key, value string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			// the comments around the old value stay in place
+			old := mapping.Content[i+1]
+			mapping.Content[i+1] = &yaml.Node{
+				Kind:        yaml.ScalarNode,
+				Value:       value,
+				HeadComment: old.HeadComment,
+				LineComment: old.LineComment,
+				FootComment: old.FootComment,
+			}
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Value: value})
+}
+
+// ServerURL returns the server of the profile, or DefaultServer.
+func (p *Profile) ServerURL() string {
+	if p.Server != "" {
+		return p.Server
+	}
+	return DefaultServer
+}
+
+// Credentials returns the provider reading the key from the source of the
+// profile, or returning ErrNoCredentials when the profile has none.
+func (p *Profile) Credentials() CredentialProvider {
+	switch {
+	case p.ApiKey != "":
+		return StaticCredentials(p.ApiKey)
+	case p.ApiKeyEnv != "":
+		env := p.ApiKeyEnv
+		return CredentialProviderFunc(func(context.Context) (string, error) {
+			if key := os.Getenv(env); key != "" {
+				return key, nil
+			}
+			return "", ErrNoCredentials
+		})
+	case p.ApiKeyFile != "":
+		return &fileCredentials{
+			path: expandHome(p.ApiKeyFile),
+			parse: func(data []byte, _ string) (CredentialProvider, error) {
+				return StaticCredentials(strings.TrimSpace(string(data))), nil
+			},
+		}
+	}
+	return StaticCredentials("")
+}
+
+// NewClient returns a client for the profile: its server, its credentials
+// and its timeout. The options are applied after those of the profile.
+func (p *Profile) NewClient(opts ...ClientOption) (*ClientWithResponses, error) {
+	var base []ClientOption
+	if p.Timeout > 0 {
+		base = append(base, WithHTTPClient(&http.Client{Timeout: p.Timeout}))
+	}
+	base = append(base, WithCredentials(p.Credentials()))
+	return NewClientWithResponses(p.ServerURL(), "", append(base, opts...)...)
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
@@ -0,0 +1,150 @@
+package runx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConfig = `# runx configuration
+current-context: production # the default account
+profiles:
+  # the paying account
+  production:
+    server: https://api.run-x.cloud
+    api_key: prod-key
+    timeout: 30s
+  research:
+    api_key_env: RESEARCH_KEY
+    output: yaml
+editor: vim # read by another tool
+`
+
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadProfile(t *testing.T) {
+	path := writeConfig(t, testConfig)
+	single := writeConfig(t, "server: https://staging.run-x.cloud\napi_key: single-key\n")
+	noCurrent := writeConfig(t, "profiles:\n  a: {}\n  b: {}\n")
+
+	tests := []struct {
+		name    string
+		path    string
+		profile string
+		env     string
+		want    Profile
+		wantErr string
+	}{
+		{name: "current context", path: path, want: Profile{Name: "production", Server: "https://api.run-x.cloud", ApiKey: "prod-key", Timeout: 30 * time.Second}},
+		{name: "environment", path: path, env: "research", want: Profile{Name: "research", ApiKeyEnv: "RESEARCH_KEY", Output: "yaml"}},
+		{name: "named over environment", path: path, profile: "production", env: "research", want: Profile{Name: "production", Server: "https://api.run-x.cloud", ApiKey: "prod-key", Timeout: 30 * time.Second}},
+		{name: "missing profile", path: path, profile: "staging", wantErr: "profile not found: staging"},
+		{name: "missing profile in the environment", path: path, env: "staging", wantErr: "profile not found: staging"},
+		{name: "top-level entries", path: single, want: Profile{Name: "default", Server: "https://staging.run-x.cloud", ApiKey: "single-key"}},
+		{name: "top-level entries by name", path: single, profile: "default", want: Profile{Name: "default", Server: "https://staging.run-x.cloud", ApiKey: "single-key"}},
+		{name: "no current context", path: noCurrent, wantErr: "no current context, pick one of a, b"},
+		{name: "missing file", path: filepath.Join(t.TempDir(), "config"), wantErr: "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvProfile, tt.env)
+			got, err := LoadProfile(tt.path, tt.profile)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadProfile() = %+v, %v, want %q", got, err, tt.wantErr)
+				}
+				if strings.Contains(tt.wantErr, "not found") && !errors.Is(err, ErrProfileNotFound) {
+					t.Errorf("LoadProfile() error = %v, want ErrProfileNotFound", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != tt.want {
+				t.Errorf("LoadProfile() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+
+	t.Run("missing default file", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+		t.Setenv("HOME", t.TempDir())
+		t.Setenv(EnvProfile, "")
+		p, err := LoadProfile("", "")
+		if err != nil || p.Name != DefaultProfileName || p.ServerURL() != DefaultServer {
+			t.Errorf("LoadProfile() = %+v, %v, want the empty default profile", p, err)
+		}
+	})
+}
+
+func TestSetCurrentContext(t *testing.T) {
+	path := writeConfig(t, testConfig)
+	if err := SetCurrentContext(path, "research"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// only the current context changed
+	want := strings.Replace(testConfig, "current-context: production", "current-context: research", 1)
+	if string(data) != want {
+		t.Errorf("config =\n%s\nwant\n%s", data, want)
+	}
+	t.Setenv(EnvProfile, "")
+	if p, err := LoadProfile(path, ""); err != nil || p.Name != "research" {
+		t.Errorf("LoadProfile() = %+v, %v, want research", p, err)
+	}
+
+	// and back
+	if err := SetCurrentContext(path, "production"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != testConfig {
+		t.Errorf("config =\n%s\nwant\n%s", data, testConfig)
+	}
+
+	if err := SetCurrentContext(path, "staging"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("SetCurrentContext() error = %v, want ErrProfileNotFound", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != testConfig {
+		t.Errorf("config changed by a failed update:\n%s", data)
+	}
+}
+
+func TestSetCurrentContextAdded(t *testing.T) {
+	// a file without current context gets one, after the other entries
+	path := writeConfig(t, "profiles:\n  a:\n    api_key: a-key\n  b:\n    api_key: b-key\n")
+	if err := SetCurrentContext(path, "b"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if want := "profiles:\n  a:\n    api_key: a-key\n  b:\n    api_key: b-key\ncurrent-context: b\n"; string(data) != want {
+		t.Errorf("config = %q, want %q", data, want)
+	}
+
+	// a missing file has no profile to switch to, and is not created
+	missing := filepath.Join(t.TempDir(), "config")
+	if err := SetCurrentContext(missing, "b"); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("SetCurrentContext() error = %v, want ErrProfileNotFound", err)
+	}
+	if _, err := os.Stat(missing); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Stat() = %v, want the file not created", err)
+	}
+
+	invalid := writeConfig(t, "- not\n- a mapping\n")
+	if err := SetCurrentContext(invalid, "b"); err == nil {
+		t.Error("SetCurrentContext() of a list succeeded")
+	}
+}
@@ -0,0 +1,250 @@
+package runx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoCredentials is returned by a CredentialProvider that has no token to
+// offer, letting ChainCredentials move on to the next provider.
+var ErrNoCredentials = errors.New("runx: no credentials")
+
+// CredentialProvider supplies the bearer token of a request. It is called
+// for every request, so implementations reading files or the environment pick
+// up rotated keys without the client being rebuilt.
+type CredentialProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// CredentialProviderFunc adapts a function to a CredentialProvider.
+type CredentialProviderFunc func(ctx context.Context) (string, error)
+
+// Token calls f(ctx).
+func (f CredentialProviderFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// WithCredentials sets the Authorization header of every request to the
+// token returned by p. It is meant for clients created with an empty bearer,
+// see NewClientWithCredentials.
+func WithCredentials(p CredentialProvider) ClientOption {
+	return WithRequestEditorFn(func(ctx context.Context, req *http.Request) error {
+		token, err := p.Token(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// NewClientWithCredentials creates a Client resolving its bearer token
+// through p on every request.
+func NewClientWithCredentials(server string, p CredentialProvider, opts ...ClientOption) (*Client, error) {
+	return NewClient(server, "", append(opts, WithCredentials(p))...)
+}
+
+// StaticCredentials always returns token, or ErrNoCredentials when it is empty.
+func StaticCredentials(token string) CredentialProvider {
+	return CredentialProviderFunc(func(context.Context) (string, error) {
+		if token == "" {
+			return "", ErrNoCredentials
+		}
+		return token, nil
+	})
+}
+
+// EnvCredentials returns the API key held by the RUNX_API_KEY environment
+// variable at the time of the request.
+func EnvCredentials() CredentialProvider {
+	return CredentialProviderFunc(func(context.Context) (string, error) {
+		if key := os.Getenv(EnvAPIKey); key != "" {
+			return key, nil
+		}
+		return "", ErrNoCredentials
+	})
+}
+
+// ConfigCredentials returns the key of the current profile of the
+// configuration file at path, or at DefaultConfigPath when path is empty,
+// see ProfileCredentials.
+func ConfigCredentials(path string) CredentialProvider {
+	return ProfileCredentials(path, "")
+}
+
+// ProfileCredentials returns the key of the named profile of the
+// configuration file at path, or at DefaultConfigPath when path is empty. An
+// empty name selects the profile named by RUNX_PROFILE, then the current
+// context. The file is read again whenever it changes, and RUNX_PROFILE on
+// every call.
+func ProfileCredentials(path, name string) CredentialProvider {
+	return &fileCredentials{
+		path:        path,
+		defaultPath: DefaultConfigPath,
+		parse: func(data []byte, path string) (CredentialProvider, error) {
+			cfg, err := parseConfig(data, path)
+			if err != nil {
+				return nil, err
+			}
+			if name != "" {
+				p, err := cfg.Profile(name)
+				if err != nil {
+					return nil, err
+				}
+				return p.Credentials(), nil
+			}
+			return &envProfileCredentials{config: cfg, providers: map[string]CredentialProvider{}}, nil
+		},
+	}
+}
+
+// envProfileCredentials returns the key of the profile named by RUNX_PROFILE
+// at the time of the call, keeping the provider of each profile used.
+type envProfileCredentials struct {
+	config *Config
+
+	mu        sync.Mutex
+	providers map[string]CredentialProvider
+}
+
+func (e *envProfileCredentials) Token(ctx context.Context) (string, error) {
+	name := os.Getenv(EnvProfile)
+	e.mu.Lock()
+	provider, ok := e.providers[name]
+	if !ok {
+		p, err := e.config.Profile(name)
+		if err != nil {
+			e.mu.Unlock()
+			return "", err
+		}
+		provider = p.Credentials()
+		e.providers[name] = provider
+	}
+	e.mu.Unlock()
+	return provider.Token(ctx)
+}
+
+// SessionCredentials returns the session token cached in the file at path,
+// or at DefaultSessionPath when path is empty, as written by SaveSession.
+// The file is read again whenever it changes.
+func SessionCredentials(path string) CredentialProvider {
+	return &fileCredentials{
+		path:        path,
+		defaultPath: DefaultSessionPath,
+		parse: func(data []byte, _ string) (CredentialProvider, error) {
+			return StaticCredentials(strings.TrimSpace(string(data))), nil
+		},
+	}
+}
+
+// ChainCredentials returns the token of the first provider that has one.
+// Providers returning ErrNoCredentials or an empty token are skipped, any
+// other error is returned.
+func ChainCredentials(providers ...CredentialProvider) CredentialProvider {
+	return CredentialProviderFunc(func(ctx context.Context) (string, error) {
+		for _, p := range providers {
+			token, err := p.Token(ctx)
+			if errors.Is(err, ErrNoCredentials) {
+				continue
+			}
+			if err != nil {
+				return "", err
+			}
+			if token != "" {
+				return token, nil
+			}
+		}
+		return "", ErrNoCredentials
+	})
+}
+
+// DefaultCredentials returns the chain used when no credential is given
+// explicitly: the RUNX_API_KEY environment variable, the configuration file
+// and the cached session, in that order.
+func DefaultCredentials() CredentialProvider {
+	return ChainCredentials(EnvCredentials(), ConfigCredentials(""), SessionCredentials(""))
+}
+
+// DefaultSessionPath returns the location of the session cache, runx/session
+// under the user cache directory, e.g. ~/.cache/runx/session on Linux.
+func DefaultSessionPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "runx", "session"), nil
+}
+
+// SaveSession stores a session token in the cache file at path, or at
+// DefaultSessionPath when path is empty, readable by the owner only.
+func SaveSession(path, token string) error {
+	if path == "" {
+		var err error
+		if path, err = DefaultSessionPath(); err != nil {
+			return fmt.Errorf("runx: %w", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("runx: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		return fmt.Errorf("runx: %w", err)
+	}
+	return nil
+}
+
+// fileCredentials reads the provider of the token from a file, caching it
+// until the modification time or the size of the file changes.
+type fileCredentials struct {
+	path        string
+	defaultPath func() (string, error)
+	parse       func(data []byte, path string) (CredentialProvider, error)
+
+	mu       sync.Mutex
+	modTime  time.Time
+	size     int64
+	provider CredentialProvider
+}
+
+func (f *fileCredentials) Token(ctx context.Context) (string, error) {
+	path := f.path
+	if path == "" {
+		var err error
+		if path, err = f.defaultPath(); err != nil {
+			return "", ErrNoCredentials
+		}
+	}
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", ErrNoCredentials
+	}
+	if err != nil {
+		return "", fmt.Errorf("runx: %w", err)
+	}
+
+	f.mu.Lock()
+	if f.provider == nil || !info.ModTime().Equal(f.modTime) || info.Size() != f.size {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.mu.Unlock()
+			return "", fmt.Errorf("runx: %w", err)
+		}
+		provider, err := f.parse(data, path)
+		if err != nil {
+			f.mu.Unlock()
+			return "", err
+		}
+		f.provider, f.modTime, f.size = provider, info.ModTime(), info.Size()
+	}
+	provider := f.provider
+	f.mu.Unlock()
+	return provider.Token(ctx)
+}
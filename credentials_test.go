@@ -0,0 +1,75 @@
+package runx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProfileCredentials(t *testing.T) {
+	const config = `current-context: production
+profiles:
+  production:
+    api_key: prod-key
+  research:
+    api_key: research-key
+`
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(data string) {
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(config)
+
+	tests := []struct {
+		name    string
+		profile string
+		env     string
+		want    string
+	}{
+		{"current context", "", "", "prod-key"},
+		{"environment", "", "research", "research-key"},
+		{"environment unset", "", "", "prod-key"},
+		{"named", "research", "", "research-key"},
+		{"named over environment", "production", "research", "prod-key"},
+	}
+	provider := ProfileCredentials(path, "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvProfile, tt.env)
+			p := provider
+			if tt.profile != "" {
+				p = ProfileCredentials(path, tt.profile)
+			}
+			got, err := p.Token(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Token() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("unknown profile", func(t *testing.T) {
+		if _, err := ProfileCredentials(path, "staging").Token(context.Background()); err == nil {
+			t.Fatal("Token() of an unknown profile succeeded")
+		}
+		t.Setenv(EnvProfile, "staging")
+		if _, err := provider.Token(context.Background()); err == nil {
+			t.Fatal("Token() of an unknown profile named by the environment succeeded")
+		}
+	})
+
+	t.Run("file change", func(t *testing.T) {
+		t.Setenv(EnvProfile, "research")
+		// a change of size makes the provider parse the file again
+		write(strings.Replace(config, "research-key", "new-research-key", 1))
+		if got, err := provider.Token(context.Background()); err != nil || got != "new-research-key" {
+			t.Errorf("Token() = %q, %v, want new-research-key", got, err)
+		}
+	})
+}
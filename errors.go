@@ -0,0 +1,120 @@
+package runx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Sentinel errors matched by *APIError through errors.Is.
+var (
+	ErrBadRequest    = errors.New("runx: bad request")
+	ErrUnauthorized  = errors.New("runx: unauthorized")
+	ErrNotFound      = errors.New("runx: not found")
+	ErrNotAcceptable = errors.New("runx: not acceptable")
+	ErrConflict      = errors.New("runx: conflict")
+	ErrRateLimited   = errors.New("runx: rate limited")
+	ErrServer        = errors.New("runx: server error")
+)
+
+// APIError is returned for responses outside of the 2xx range, either by
+// CheckResponse or by any call made through a client configured WithAPIErrors.
+type APIError struct {
+	// Operation is the ClientInterface method that issued the request, such as "GetApp".
+	Operation string
+
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Message is the decoded ErrorResponse.Error, or the status text when the
+	// body does not carry one.
+	Message string
+
+	// Body holds the raw response body.
+	Body []byte
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	op := e.Operation
+	if op == "" {
+		op = "request"
+	}
+	return fmt.Sprintf("runx: %s: %d %s", op, e.StatusCode, e.Message)
+}
+
+// Is reports whether the error matches one of the package sentinels.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrNotAcceptable:
+		return e.StatusCode == http.StatusNotAcceptable
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// CheckResponse returns an *APIError when rsp is not a 2xx response. It is
+// intended for the *WithResponse results, e.g.
+//
+//	err := runx.CheckResponse("GetApp", resp.HTTPResponse, resp.Body)
+func CheckResponse(operation string, rsp *http.Response, body []byte) error {
+	if rsp == nil {
+		return &APIError{Operation: operation, Message: "no response"}
+	}
+	if rsp.StatusCode >= 200 && rsp.StatusCode < 300 {
+		return nil
+	}
+	return newAPIError(operation, rsp.StatusCode, body)
+}
+
+func newAPIError(operation string, statusCode int, body []byte) *APIError {
+	apiErr := &APIError{
+		Operation:  operation,
+		StatusCode: statusCode,
+		Body:       body,
+	}
+	var payload ErrorResponse
+	if err := json.Unmarshal(body, &payload); err == nil && payload.Error != nil {
+		apiErr.Message = *payload.Error
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(statusCode)
+	}
+	return apiErr
+}
+
+// WithAPIErrors turns every response outside of the 2xx range into an
+// *APIError, so that the *WithResponse methods return a non-nil error instead
+// of a response with one of its JSON4xx/JSON5xx fields set.
+func WithAPIErrors() ClientOption {
+	return WithDoerMiddleware(func(next HttpRequestDoer) HttpRequestDoer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			rsp, err := next.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			if rsp.StatusCode >= 200 && rsp.StatusCode < 300 {
+				return rsp, nil
+			}
+			body, err := io.ReadAll(rsp.Body)
+			_ = rsp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			return nil, newAPIError(OperationName(req), rsp.StatusCode, body)
+		})
+	})
+}
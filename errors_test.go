@@ -0,0 +1,124 @@
+package runx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIErrorIs(t *testing.T) {
+	sentinels := []error{
+		ErrBadRequest,
+		ErrUnauthorized,
+		ErrNotFound,
+		ErrNotAcceptable,
+		ErrConflict,
+		ErrRateLimited,
+		ErrServer,
+	}
+	tests := []struct {
+		status  int
+		message string
+		want    error
+	}{
+		{http.StatusBadRequest, "invalid name", ErrBadRequest},
+		{http.StatusUnauthorized, "invalid token", ErrUnauthorized},
+		{http.StatusNotFound, "app not found", ErrNotFound},
+		{http.StatusNotAcceptable, "not acceptable", ErrNotAcceptable},
+		{http.StatusConflict, "name already used", ErrConflict},
+		{http.StatusTooManyRequests, "slow down", ErrRateLimited},
+		{http.StatusInternalServerError, "boom", ErrServer},
+		{http.StatusBadGateway, "bad gateway", ErrServer},
+		{http.StatusServiceUnavailable, "credit service unavailable", ErrServer},
+		// the message does not change the sentinel
+		{http.StatusBadRequest, "insufficient credit", ErrBadRequest},
+		{http.StatusPaymentRequired, "insufficient credit", nil},
+		{http.StatusForbidden, "forbidden", nil},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d %s", tt.status, tt.message), func(t *testing.T) {
+			err := fmt.Errorf("wrapped: %w", &APIError{Operation: "GetApp", StatusCode: tt.status, Message: tt.message})
+			for _, s := range sentinels {
+				if got := errors.Is(err, s); got != (s == tt.want) {
+					t.Errorf("errors.Is(%v) = %v, want %v", s, got, !got)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantMsg string
+	}{
+		{"ok", http.StatusOK, `{}`, ""},
+		{"no content", http.StatusNoContent, ``, ""},
+		{"error payload", http.StatusNotFound, `{"error":"app not found"}`, "app not found"},
+		{"status text", http.StatusConflict, `not json`, "Conflict"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckResponse("GetApp", &http.Response{StatusCode: tt.status}, []byte(tt.body))
+			if tt.wantMsg == "" {
+				if err != nil {
+					t.Fatalf("CheckResponse() = %v, want nil", err)
+				}
+				return
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("CheckResponse() = %v, want an *APIError", err)
+			}
+			if apiErr.Operation != "GetApp" || apiErr.StatusCode != tt.status || apiErr.Message != tt.wantMsg {
+				t.Errorf("CheckResponse() = %+v", apiErr)
+			}
+		})
+	}
+}
+
+func TestAPIErrorsCreateApp(t *testing.T) {
+	// the statuses declared for POST /app, with their documented messages
+	tests := []struct {
+		status int
+		body   string
+		want   error
+	}{
+		{http.StatusBadRequest, `{"error":"Invalid request data"}`, ErrBadRequest},
+		{http.StatusUnauthorized, `{"error":"User does not own the app"}`, ErrUnauthorized},
+		{http.StatusNotFound, `{"error":"User or app not found"}`, ErrNotFound},
+		{http.StatusConflict, `{"error":"No app created due to conflict"}`, ErrConflict},
+		{http.StatusInternalServerError, `{"error":"Server error"}`, ErrServer},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			defer srv.Close()
+			client, err := NewClientWithResponses(srv.URL, "key", WithAPIErrors())
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = client.CreateAppWithResponse(context.Background(), CreateAppRequest{Apps: []AppRequest{{Name: "web", App: "nginx"}}})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("CreateApp() error = %v, want %v", err, tt.want)
+			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.Operation != "CreateApp" || apiErr.StatusCode != tt.status || string(apiErr.Body) != tt.body {
+				t.Fatalf("CreateApp() error = %#v", err)
+			}
+			if want := tt.body[len(`{"error":"`) : len(tt.body)-2]; apiErr.Message != want {
+				t.Errorf("Message = %q, want %q", apiErr.Message, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package runx
+
+import "context"
+
+// AppEstimate is the cost of one of the apps of a CreateAppRequest.
+type AppEstimate struct {
+	Name string `json:"name"`
+
+	// App is the catalog id of the app.
+	App string `json:"app"`
+
+	// Priced is false when the catalog has no price for the app, in which
+	// case Cost is zero.
+	Priced bool `json:"priced"`
+
+	Cost Cost `json:"cost"`
+}
+
+// Estimate is the cost of the apps of a CreateAppRequest.
+type Estimate struct {
+	Apps  []AppEstimate `json:"apps"`
+	Total Cost          `json:"total"`
+}
+
+// Estimate returns the cost of the apps req would create, expanding its pack
+// when it lists no app, from the catalog fetched through GetCatalogApps.
+// Unknown catalog apps and packs are reported as ValidationErrors.
+func (c *ClientWithResponses) Estimate(ctx context.Context, req CreateAppRequest) (*Estimate, error) {
+	cat, err := c.GetCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cat.Estimate(req)
+}
+
+// Estimate returns the cost of the apps req would create, see
+// ClientWithResponses.Estimate.
+func (c *Catalog) Estimate(req CreateAppRequest) (*Estimate, error) {
+	var errs ValidationErrors
+	if req.Pack != nil && c.Pack(*req.Pack) == nil {
+		errs.add(-1, "pack", "unknown pack %q", *req.Pack)
+		return nil, errs
+	}
+	requests, err := c.Requests(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(requests) == 0 {
+		errs.add(-1, "apps", "no app requested")
+		return nil, errs
+	}
+
+	est := &Estimate{Apps: make([]AppEstimate, 0, len(requests))}
+	for i, r := range requests {
+		if c.App(r.App) == nil {
+			errs.add(i, "app", "unknown catalog app %q", r.App)
+			continue
+		}
+		app := AppEstimate{Name: r.Name, App: r.App}
+		if price, ok := c.Price(r.App); ok {
+			app.Priced = true
+			app.Cost = HourlyCost(price)
+		}
+		est.Apps = append(est.Apps, app)
+		est.Total = est.Total.Add(app.Cost)
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return est, nil
+}
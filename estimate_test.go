@@ -0,0 +1,107 @@
+package runx
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCatalogEstimate(t *testing.T) {
+	cat := &Catalog{
+		Apps: []CatalogApp{
+			{Id: ptr("nginx"), Price: ptr(float32(0.01))},
+			{Id: ptr("postgres"), Price: ptr(float32(0.07))},
+			{Id: ptr("jupyter"), Price: ptr(float32(0.5))},
+			{Id: ptr("beta")},
+		},
+		Packs: []Pack{{Id: ptr("web"), Apps: &[]string{"nginx", "postgres"}}},
+	}
+
+	tests := []struct {
+		name      string
+		req       CreateAppRequest
+		want      []AppEstimate
+		wantTotal Cost
+		wantErr   string
+	}{
+		{
+			name:      "single app",
+			req:       CreateAppRequest{Apps: []AppRequest{{Name: "web", App: "nginx"}}},
+			want:      []AppEstimate{{Name: "web", App: "nginx", Priced: true, Cost: Cost{Hourly: 0.01, Daily: 0.24, Monthly: 7.3}}},
+			wantTotal: Cost{Hourly: 0.01, Daily: 0.24, Monthly: 7.3},
+		},
+		{
+			// the float32 price 0.07 is 0.07000000029802322, which would
+			// make the monthly cost 51.1000002
+			name:      "price without float32 noise",
+			req:       CreateAppRequest{Apps: []AppRequest{{Name: "db", App: "postgres"}}},
+			want:      []AppEstimate{{Name: "db", App: "postgres", Priced: true, Cost: HourlyCost(0.07)}},
+			wantTotal: HourlyCost(0.07),
+		},
+		{
+			name: "total of several apps",
+			req:  CreateAppRequest{Apps: []AppRequest{{Name: "a", App: "nginx"}, {Name: "b", App: "nginx"}, {Name: "gpu", App: "jupyter"}}},
+			want: []AppEstimate{
+				{Name: "a", App: "nginx", Priced: true, Cost: HourlyCost(0.01)},
+				{Name: "b", App: "nginx", Priced: true, Cost: HourlyCost(0.01)},
+				{Name: "gpu", App: "jupyter", Priced: true, Cost: HourlyCost(0.5)},
+			},
+			wantTotal: Cost{Hourly: 0.52, Daily: 12.48, Monthly: 379.6},
+		},
+		{
+			name:      "unpriced app",
+			req:       CreateAppRequest{Apps: []AppRequest{{Name: "x", App: "beta"}, {Name: "web", App: "nginx"}}},
+			want:      []AppEstimate{{Name: "x", App: "beta"}, {Name: "web", App: "nginx", Priced: true, Cost: HourlyCost(0.01)}},
+			wantTotal: HourlyCost(0.01),
+		},
+		{
+			name:      "pack",
+			req:       CreateAppRequest{Pack: ptr("web")},
+			want:      []AppEstimate{{Name: "nginx", App: "nginx", Priced: true, Cost: HourlyCost(0.01)}, {Name: "postgres", App: "postgres", Priced: true, Cost: HourlyCost(0.07)}},
+			wantTotal: Cost{Hourly: 0.08, Daily: 1.92, Monthly: 58.4},
+		},
+		{
+			name:    "unknown pack",
+			req:     CreateAppRequest{Pack: ptr("missing")},
+			wantErr: `runx: invalid request: pack: unknown pack "missing"`,
+		},
+		{
+			name:    "no app",
+			req:     CreateAppRequest{},
+			wantErr: "runx: invalid request: apps: no app requested",
+		},
+		{
+			name:    "unknown apps",
+			req:     CreateAppRequest{Apps: []AppRequest{{Name: "a", App: "redis"}, {Name: "b", App: "nginx"}, {Name: "c", App: "mongo"}}},
+			wantErr: `runx: invalid request: apps[0].app: unknown catalog app "redis"; apps[2].app: unknown catalog app "mongo"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cat.Estimate(tt.req)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr || !errors.Is(err, ErrInvalidRequest) {
+					t.Fatalf("Estimate() = %+v, %v, want %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Apps) != len(tt.want) {
+				t.Fatalf("Estimate().Apps = %+v, want %+v", got.Apps, tt.want)
+			}
+			for i, want := range tt.want {
+				if got.Apps[i].Name != want.Name || got.Apps[i].App != want.App || got.Apps[i].Priced != want.Priced || !equalCost(got.Apps[i].Cost, want.Cost) {
+					t.Errorf("Estimate().Apps[%d] = %+v, want %+v", i, got.Apps[i], want)
+				}
+			}
+			if !equalCost(got.Total, tt.wantTotal) {
+				t.Errorf("Estimate().Total = %+v, want %+v", got.Total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func equalCost(a, b Cost) bool {
+	return approx(a.Hourly, b.Hourly) && approx(a.Daily, b.Daily) && approx(a.Monthly, b.Monthly)
+}
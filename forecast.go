@@ -0,0 +1,185 @@
+package runx
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// AppCost is the cost of an app of the account.
+type AppCost struct {
+	Id   string `json:"id"`
+	Name string `json:"name,omitempty"`
+
+	// App is the catalog id of the app.
+	App string `json:"app"`
+
+	// Billed is true when the app is enabled and consumes credit.
+	Billed bool `json:"billed"`
+
+	// Priced is false when the catalog does not know the price of the app,
+	// in which case Cost is zero.
+	Priced bool `json:"priced"`
+
+	Cost Cost `json:"cost"`
+}
+
+// Forecast projects the spending of an account, see ClientWithResponses.Forecast.
+type Forecast struct {
+	GeneratedAt time.Time `json:"generated_at"`
+
+	// Credit and Limit are the current FilteredUser.Credit and Limit, and
+	// Consumed the total of the consumption history, which the limit caps.
+	Credit   float64 `json:"credit"`
+	Limit    float64 `json:"limit"`
+	Consumed float64 `json:"consumed"`
+
+	// HistoricalDailyBurn is the average daily consumption over the window
+	// of the forecast.
+	HistoricalDailyBurn float64 `json:"historical_daily_burn"`
+
+	// Running is the cost of the billed apps, listed in Apps.
+	Running Cost      `json:"running"`
+	Apps    []AppCost `json:"apps"`
+
+	// DailyBurn is the projected daily consumption, the larger of
+	// HistoricalDailyBurn and Running.Daily.
+	DailyBurn float64 `json:"daily_burn"`
+
+	// DaysUntilCreditExhausted and CreditExhaustedAt tell when the credit
+	// reaches zero at the DailyBurn rate. They are nil when nothing is
+	// consumed.
+	DaysUntilCreditExhausted *float64   `json:"days_until_credit_exhausted,omitempty"`
+	CreditExhaustedAt        *time.Time `json:"credit_exhausted_at,omitempty"`
+
+	// DaysUntilLimitReached and LimitReachedAt tell when Consumed reaches
+	// Limit at the DailyBurn rate. They are nil when nothing is consumed or
+	// the account has no limit.
+	DaysUntilLimitReached *float64   `json:"days_until_limit_reached,omitempty"`
+	LimitReachedAt        *time.Time `json:"limit_reached_at,omitempty"`
+}
+
+// ForecastOption configures ClientWithResponses.Forecast.
+type ForecastOption func(*forecastConfig)
+
+type forecastConfig struct {
+	window time.Duration
+}
+
+// WithForecastWindow sets the span of the consumption history averaged into
+// Forecast.HistoricalDailyBurn. It defaults to seven days.
+func WithForecastWindow(d time.Duration) ForecastOption {
+	return func(c *forecastConfig) {
+		c.window = d
+	}
+}
+
+// Forecast projects the burn rate of the account from the consumption history
+// returned by Me and the catalog price of the billed apps, and tells when the
+// credit runs out and when the limit is reached.
+func (c *ClientWithResponses) Forecast(ctx context.Context, opts ...ForecastOption) (*Forecast, error) {
+	cfg := forecastConfig{window: 7 * 24 * time.Hour}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	me, err := c.MeWithResponse(ctx)
+	if err == nil {
+		err = CheckResponse("Me", me.HTTPResponse, me.Body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("runx: forecast: %w", err)
+	}
+	apps, err := c.listApps(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("runx: forecast: %w", err)
+	}
+	cat, err := c.GetCatalog(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("runx: forecast: %w", err)
+	}
+
+	var (
+		user         FilteredUser
+		consumptions []Consumption
+	)
+	if me.JSON200 != nil {
+		if me.JSON200.User != nil {
+			user = *me.JSON200.User
+		}
+		if me.JSON200.Consumptions != nil {
+			consumptions = *me.JSON200.Consumptions
+		}
+	}
+	return NewForecast(user, consumptions, AppCosts(apps, cat), time.Now(), cfg.window), nil
+}
+
+// AppCosts prices apps with the catalog.
+func AppCosts(apps []AppExtended, cat *Catalog) []AppCost {
+	costs := make([]AppCost, 0, len(apps))
+	for _, app := range apps {
+		cost := AppCost{Id: deref(app.Id), Name: deref(app.Name), App: deref(app.App), Billed: billed(app)}
+		if price, ok := cat.Price(cost.App); ok {
+			cost.Priced = true
+			cost.Cost = HourlyCost(price)
+		}
+		costs = append(costs, cost)
+	}
+	return costs
+}
+
+// NewForecast projects the spending of an account at time now from its user,
+// its consumption history and the cost of its apps, averaging the history
+// over window, see ClientWithResponses.Forecast.
+func NewForecast(user FilteredUser, consumptions []Consumption, apps []AppCost, now time.Time, window time.Duration) *Forecast {
+	f := &Forecast{
+		GeneratedAt: now,
+		Credit:      value(user.Credit),
+		Limit:       value(user.Limit),
+		Apps:        apps,
+	}
+	if f.Apps == nil {
+		f.Apps = []AppCost{}
+	}
+
+	since := now.Add(-window)
+	var recent float64
+	first := now
+	for _, c := range consumptions {
+		amount := value(c.Value)
+		f.Consumed += amount
+		if c.Date != nil && !c.Date.Before(since) {
+			recent += amount
+			if c.Date.Before(first) {
+				first = *c.Date
+			}
+		}
+	}
+	// a history shorter than the window is averaged over its own span
+	if days := math.Max(1, now.Sub(first).Hours()/HoursPerDay); recent > 0 {
+		f.HistoricalDailyBurn = recent / days
+	}
+
+	for _, app := range apps {
+		if app.Billed {
+			f.Running = f.Running.Add(app.Cost)
+		}
+	}
+	f.DailyBurn = math.Max(f.HistoricalDailyBurn, f.Running.Daily)
+
+	if f.DailyBurn > 0 {
+		f.DaysUntilCreditExhausted, f.CreditExhaustedAt = runway(math.Max(0, f.Credit), f.DailyBurn, now)
+		if f.Limit > 0 {
+			f.DaysUntilLimitReached, f.LimitReachedAt = runway(math.Max(0, f.Limit-f.Consumed), f.DailyBurn, now)
+		}
+	}
+	return f
+}
+
+// runway returns the number of days until amount is spent at dailyBurn, and
+// the corresponding time.
+func runway(amount, dailyBurn float64, now time.Time) (*float64, *time.Time) {
+	days := amount / dailyBurn
+	at := now.Add(time.Duration(days * HoursPerDay * float64(time.Hour)))
+	return &days, &at
+}
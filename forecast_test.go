@@ -0,0 +1,170 @@
+package runx
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestNewForecast(t *testing.T) {
+	day := 24 * time.Hour
+	web := AppCost{Id: "a1", Name: "web", App: "nginx", Billed: true, Priced: true, Cost: HourlyCost(0.01)}
+	db := AppCost{Id: "a2", Name: "db", App: "postgres", Billed: true, Priced: true, Cost: HourlyCost(0.02)}
+	stopped := AppCost{Id: "a3", Name: "old", App: "postgres", Priced: true, Cost: HourlyCost(0.02)}
+
+	tests := []struct {
+		name          string
+		credit, limit float32
+		consumptions  []Consumption
+		apps          []AppCost
+		want          Forecast
+		// nil when no runway is expected
+		creditDays, limitDays *float64
+	}{
+		{
+			name:   "history over the window",
+			credit: 10, limit: 20,
+			consumptions: []Consumption{
+				consumption(noon.Add(-10*day), 10), // outside the window, only consumed
+				consumption(noon.Add(-3*day), 4),
+				consumption(noon.Add(-day), 2),
+			},
+			apps: []AppCost{web, stopped},
+			want: Forecast{
+				Credit: 10, Limit: 20, Consumed: 16,
+				// 6 over the 3 days since the first consumption of the window
+				HistoricalDailyBurn: 2,
+				Running:             HourlyCost(0.01),
+				DailyBurn:           2,
+			},
+			creditDays: ptr(5.0),
+			limitDays:  ptr(2.0),
+		},
+		{
+			name:   "running apps above the history",
+			credit: 12,
+			consumptions: []Consumption{
+				consumption(noon.Add(-7*day), 0.7),
+			},
+			apps: []AppCost{web, db},
+			want: Forecast{
+				Credit: 12, Consumed: 0.7,
+				HistoricalDailyBurn: 0.1,
+				Running:             HourlyCost(0.03),
+				DailyBurn:           0.72,
+			},
+			creditDays: ptr(12 / 0.72),
+		},
+		{
+			name:   "history shorter than a day",
+			credit: 9, limit: 100,
+			consumptions: []Consumption{
+				consumption(noon.Add(-2*time.Hour), 3),
+			},
+			want:       Forecast{Credit: 9, Limit: 100, Consumed: 3, HistoricalDailyBurn: 3, DailyBurn: 3},
+			creditDays: ptr(3.0),
+			limitDays:  ptr(97 / 3.0),
+		},
+		{
+			name:   "credit exhausted and limit exceeded",
+			credit: -1, limit: 5,
+			consumptions: []Consumption{
+				consumption(noon.Add(-day), 6),
+			},
+			want:       Forecast{Credit: -1, Limit: 5, Consumed: 6, HistoricalDailyBurn: 6, DailyBurn: 6},
+			creditDays: ptr(0.0),
+			limitDays:  ptr(0.0),
+		},
+		{
+			name:   "nothing consumed",
+			credit: 10, limit: 20,
+			apps: []AppCost{stopped},
+			want: Forecast{Credit: 10, Limit: 20},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := FilteredUser{Credit: &tt.credit, Limit: &tt.limit}
+			f := NewForecast(user, tt.consumptions, tt.apps, noon, 7*day)
+			if !f.GeneratedAt.Equal(noon) || len(f.Apps) != len(tt.apps) || f.Apps == nil {
+				t.Errorf("NewForecast() = %+v", f)
+			}
+			for _, c := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"Credit", f.Credit, tt.want.Credit},
+				{"Limit", f.Limit, tt.want.Limit},
+				{"Consumed", f.Consumed, tt.want.Consumed},
+				{"HistoricalDailyBurn", f.HistoricalDailyBurn, tt.want.HistoricalDailyBurn},
+				{"Running.Hourly", f.Running.Hourly, tt.want.Running.Hourly},
+				{"Running.Daily", f.Running.Daily, tt.want.Running.Daily},
+				{"Running.Monthly", f.Running.Monthly, tt.want.Running.Monthly},
+				{"DailyBurn", f.DailyBurn, tt.want.DailyBurn},
+			} {
+				if !approx(c.got, c.want) {
+					t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+				}
+			}
+			checkRunway(t, "credit", f.DaysUntilCreditExhausted, f.CreditExhaustedAt, tt.creditDays)
+			checkRunway(t, "limit", f.DaysUntilLimitReached, f.LimitReachedAt, tt.limitDays)
+		})
+	}
+}
+
+func checkRunway(t *testing.T, name string, days *float64, at *time.Time, want *float64) {
+	t.Helper()
+	if want == nil {
+		if days != nil || at != nil {
+			t.Errorf("%s runway = %v days, at %v, want none", name, *days, at)
+		}
+		return
+	}
+	if days == nil || at == nil {
+		t.Fatalf("%s runway missing, want %v days", name, *want)
+	}
+	if !approx(*days, *want) {
+		t.Errorf("%s runway = %v days, want %v", name, *days, *want)
+	}
+	wantAt := noon.Add(time.Duration(*want * float64(24*time.Hour)))
+	if d := at.Sub(wantAt); d < -time.Second || d > time.Second {
+		t.Errorf("%s reached at %v, want %v", name, at, wantAt)
+	}
+}
+
+func TestAppCosts(t *testing.T) {
+	cat := &Catalog{Apps: []CatalogApp{
+		{Id: ptr("nginx"), Price: ptr(float32(0.01))},
+		{Id: ptr("beta")},
+	}}
+	apps := []AppExtended{
+		{Id: ptr("a1"), Name: ptr("web"), App: ptr("nginx"), Enabled: ptr(true)},
+		{Id: ptr("a2"), App: ptr("nginx"), Enabled: ptr(false), Status: ptr(AppStatusRunning)},
+		{Id: ptr("a3"), App: ptr("nginx"), Status: ptr(AppStatusRunning)},
+		{Id: ptr("a4"), App: ptr("beta"), Enabled: ptr(true)},
+		{Id: ptr("a5"), App: ptr("gone"), Status: ptr(AppStatusStopped)},
+	}
+	want := []AppCost{
+		{Id: "a1", Name: "web", App: "nginx", Billed: true, Priced: true, Cost: HourlyCost(0.01)},
+		{Id: "a2", App: "nginx", Priced: true, Cost: HourlyCost(0.01)},
+		{Id: "a3", App: "nginx", Billed: true, Priced: true, Cost: HourlyCost(0.01)},
+		{Id: "a4", App: "beta", Billed: true},
+		{Id: "a5", App: "gone"},
+	}
+	got := AppCosts(apps, cat)
+	if len(got) != len(want) {
+		t.Fatalf("AppCosts() = %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("AppCosts()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if got := HourlyCost(0.01); !approx(got.Daily, 0.24) || !approx(got.Monthly, 7.3) {
+		t.Errorf("HourlyCost(0.01) = %+v", got)
+	}
+}
@@ -0,0 +1,217 @@
+package runx
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors of the login flow.
+var (
+	// ErrInvalidNumber is matched by the *NumberError returned for a phone
+	// number rejected locally or by Auth with a 406.
+	ErrInvalidNumber = errors.New("runx: invalid phone number")
+
+	// ErrAlreadyRegistered is matched by the *RegisteredError returned when
+	// Register answers with a 409.
+	ErrAlreadyRegistered = errors.New("runx: already registered")
+)
+
+// NumberError reports a phone number that is not a valid E.164 number.
+type NumberError struct {
+	// Number is the number as given by the caller.
+	Number string
+
+	// Reason explains why the number was rejected.
+	Reason string
+
+	// Err is the *APIError of the Auth call when the server rejected the
+	// number, nil when it was rejected before being sent.
+	Err error
+}
+
+func (e *NumberError) Error() string {
+	return fmt.Sprintf("runx: invalid phone number %q: %s", e.Number, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrInvalidNumber) true.
+func (e *NumberError) Is(target error) bool {
+	return target == ErrInvalidNumber
+}
+
+func (e *NumberError) Unwrap() error {
+	return e.Err
+}
+
+// RegisteredError is returned by Register when the account already exists.
+type RegisteredError struct {
+	// Err is the *APIError of the Register call.
+	Err error
+}
+
+func (e *RegisteredError) Error() string {
+	return "runx: already registered, log in with Login instead"
+}
+
+// Is makes errors.Is(err, ErrAlreadyRegistered) true.
+func (e *RegisteredError) Is(target error) bool {
+	return target == ErrAlreadyRegistered
+}
+
+func (e *RegisteredError) Unwrap() error {
+	return e.Err
+}
+
+// NormalizeNumber returns number in E.164 form. Spaces, dots, dashes, slashes
+// and parentheses are removed and a leading 00 international prefix is
+// replaced by +, so "0033 6 12-34-56-78" becomes "+33612345678".
+func NormalizeNumber(number string) (string, error) {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(number) {
+		switch {
+		case r >= '0' && r <= '9', r == '+':
+			b.WriteRune(r)
+		case r == ' ', r == '.', r == '-', r == '/', r == '(', r == ')':
+		default:
+			return "", &NumberError{Number: number, Reason: fmt.Sprintf("unexpected character %q", r)}
+		}
+	}
+	n := b.String()
+	if strings.HasPrefix(n, "00") {
+		n = "+" + n[2:]
+	}
+	digits, ok := strings.CutPrefix(n, "+")
+	switch {
+	case !ok:
+		return "", &NumberError{Number: number, Reason: "missing + country code prefix"}
+	case strings.Contains(digits, "+"):
+		return "", &NumberError{Number: number, Reason: "misplaced +"}
+	case digits == "" || digits[0] == '0':
+		return "", &NumberError{Number: number, Reason: "country code cannot start with 0"}
+	case len(digits) < 8 || len(digits) > 15:
+		return "", &NumberError{Number: number, Reason: "E.164 numbers have 8 to 15 digits"}
+	}
+	return n, nil
+}
+
+// Session is the outcome of a successful Login or Register.
+type Session struct {
+	// Token is the session token, sent as the bearer of Client.
+	Token string
+
+	// Number is the normalized phone number used by Login, empty after Register.
+	Number string
+
+	// Client is authenticated with Token and configured with the options
+	// given to Login or Register.
+	Client *Client
+}
+
+// Login normalizes number, exchanges it for a session token through Auth and
+// returns a client authenticated with it. The options apply both to the Auth
+// call and to the returned client. A number rejected locally or by the server
+// yields a *NumberError, an unknown number an *APIError matching ErrNotFound.
+func Login(ctx context.Context, server, number string, opts ...ClientOption) (*Session, error) {
+	normalized, err := NormalizeNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	anonymous, err := NewClientWithResponses(server, "", opts...)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := anonymous.AuthWithResponse(ctx, AuthRequest{Number: normalized})
+	if err == nil {
+		err = CheckResponse("Auth", rsp.HTTPResponse, rsp.Body)
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotAcceptable {
+		return nil, &NumberError{Number: number, Reason: apiErr.Message, Err: apiErr}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rsp.JSON200 == nil || rsp.JSON200.Session == nil || *rsp.JSON200.Session == "" {
+		return nil, fmt.Errorf("runx: Auth: no session returned")
+	}
+	return newSession(server, *rsp.JSON200.Session, normalized, opts)
+}
+
+// Register creates an account through Register and returns a client
+// authenticated with the new session token. An existing account yields a
+// *RegisteredError.
+func Register(ctx context.Context, server string, opts ...ClientOption) (*Session, error) {
+	anonymous, err := NewClientWithResponses(server, "", opts...)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := anonymous.RegisterWithResponse(ctx)
+	if err == nil {
+		err = CheckResponse("Register", rsp.HTTPResponse, rsp.Body)
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict {
+		return nil, &RegisteredError{Err: apiErr}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rsp.JSON200 == nil || rsp.JSON200.Session == nil || *rsp.JSON200.Session == "" {
+		return nil, fmt.Errorf("runx: Register: no session returned")
+	}
+	return newSession(server, *rsp.JSON200.Session, "", opts)
+}
+
+// PromptLogin asks for a phone number on out, reads it from in and logs in
+// with it, asking again up to three times when the number is invalid. When no
+// account uses the number, it offers to register one.
+func PromptLogin(ctx context.Context, server string, in io.Reader, out io.Writer, opts ...ClientOption) (*Session, error) {
+	scanner := bufio.NewScanner(in)
+	readLine := func(prompt string) (string, error) {
+		fmt.Fprint(out, prompt)
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", io.ErrUnexpectedEOF
+		}
+		return strings.TrimSpace(scanner.Text()), nil
+	}
+
+	const attempts = 3
+	for attempt := 1; ; attempt++ {
+		number, err := readLine("Phone number (E.164, e.g. +33612345678): ")
+		if err != nil {
+			return nil, err
+		}
+		session, err := Login(ctx, server, number, opts...)
+		switch {
+		case err == nil:
+			return session, nil
+		case errors.Is(err, ErrInvalidNumber) && attempt < attempts:
+			fmt.Fprintln(out, strings.TrimPrefix(err.Error(), "runx: "))
+			continue
+		case errors.Is(err, ErrNotFound):
+			answer, err := readLine("No account uses this number. Register a new account? [y/N] ")
+			if err != nil {
+				return nil, err
+			}
+			if a := strings.ToLower(answer); a == "y" || a == "yes" {
+				return Register(ctx, server, opts...)
+			}
+		}
+		return nil, err
+	}
+}
+
+func newSession(server, token, number string, opts []ClientOption) (*Session, error) {
+	client, err := NewClient(server, token, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{Token: token, Number: number, Client: client}, nil
+}
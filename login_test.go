@@ -0,0 +1,229 @@
+package runx_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	runx "github.com/run-x-app/runx-go"
+	"github.com/run-x-app/runx-go/runxtest"
+)
+
+func TestNormalizeNumber(t *testing.T) {
+	tests := []struct {
+		number  string
+		want    string
+		wantErr string
+	}{
+		{number: "+33612345678", want: "+33612345678"},
+		{number: "+33 6 12 34 56 78", want: "+33612345678"},
+		{number: "  +1 (415) 555-0100 ", want: "+14155550100"},
+		{number: "+44.20.7946.0958", want: "+442079460958"},
+		{number: "+49/30/1234567", want: "+49301234567"},
+		{number: "0033 6 12-34-56-78", want: "+33612345678"},
+		{number: "06 12 34 56 78", wantErr: "missing + country code prefix"},
+		{number: "33612345678", wantErr: "missing + country code prefix"},
+		{number: "+0612345678", wantErr: "country code cannot start with 0"},
+		{number: "000612345678", wantErr: "country code cannot start with 0"},
+		{number: "+", wantErr: "country code cannot start with 0"},
+		{number: "", wantErr: "missing + country code prefix"},
+		{number: "+33+612345678", wantErr: "misplaced +"},
+		{number: "+3361234", wantErr: "8 to 15 digits"},
+		{number: "+1234567", wantErr: "8 to 15 digits"},
+		{number: "+1234567890123456", wantErr: "8 to 15 digits"},
+		{number: "+33 6 12 34 56 7x", wantErr: `unexpected character 'x'`},
+		{number: "+33_612345678", wantErr: `unexpected character '_'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			got, err := runx.NormalizeNumber(tt.number)
+			if tt.wantErr == "" {
+				if err != nil || got != tt.want {
+					t.Errorf("NormalizeNumber() = %q, %v, want %q", got, err, tt.want)
+				}
+				return
+			}
+			var numErr *runx.NumberError
+			if !errors.As(err, &numErr) || !errors.Is(err, runx.ErrInvalidNumber) {
+				t.Fatalf("NormalizeNumber() = %q, %v, want a *NumberError", got, err)
+			}
+			if numErr.Number != tt.number || numErr.Err != nil || !strings.Contains(numErr.Reason, tt.wantErr) {
+				t.Errorf("NormalizeNumber() error = %+v, want %q", numErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+// me returns the email of the user authenticated by session.
+func me(t *testing.T, srv *runxtest.Server, session *runx.Session) string {
+	t.Helper()
+	rsp, err := srv.ClientFor(session.Token).MeWithResponse(context.Background())
+	if err != nil || rsp.JSON200 == nil {
+		t.Fatalf("Me() = %v, %v", rsp, err)
+	}
+	return *rsp.JSON200.User.Email
+}
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer()
+	defer srv.Close()
+
+	session, err := runx.Login(ctx, srv.URL, "0033 6 00 00 00 00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Number != runxtest.DefaultNumber || session.Token == "" || session.Client == nil {
+		t.Errorf("Login() = %+v", session)
+	}
+	if email := me(t, srv, session); email != "user@example.com" {
+		t.Errorf("Login() logged in %s", email)
+	}
+
+	tests := []struct {
+		name       string
+		number     string
+		fault      int
+		want       error
+		wantStatus int
+	}{
+		{name: "invalid locally", number: "06 00 00 00 00", want: runx.ErrInvalidNumber},
+		{name: "invalid for the server", number: runxtest.DefaultNumber, fault: http.StatusNotAcceptable, want: runx.ErrInvalidNumber, wantStatus: http.StatusNotAcceptable},
+		{name: "unknown number", number: "+33611111111", want: runx.ErrNotFound, wantStatus: http.StatusNotFound},
+		{name: "server error", number: runxtest.DefaultNumber, fault: http.StatusInternalServerError, want: runx.ErrServer, wantStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.fault != 0 {
+				srv.InjectFault(runxtest.Fault{Operation: "Auth", StatusCode: tt.fault, Times: 1})
+			}
+			session, err := runx.Login(ctx, srv.URL, tt.number)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Login() = %+v, %v, want %v", session, err, tt.want)
+			}
+			var apiErr *runx.APIError
+			if errors.As(err, &apiErr) != (tt.wantStatus != 0) || (apiErr != nil && apiErr.StatusCode != tt.wantStatus) {
+				t.Errorf("Login() error = %#v, want status %d", err, tt.wantStatus)
+			}
+			var numErr *runx.NumberError
+			if errors.As(err, &numErr) != (tt.want == runx.ErrInvalidNumber) {
+				t.Errorf("Login() error = %#v", err)
+			}
+			if numErr != nil && (numErr.Number != tt.number || numErr.Reason == "") {
+				t.Errorf("Login() error = %+v", numErr)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer()
+	defer srv.Close()
+
+	session, err := runx.Register(ctx, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Number != "" || session.Token == "" {
+		t.Errorf("Register() = %+v", session)
+	}
+	if email := me(t, srv, session); email != "" {
+		t.Errorf("Register() logged in %s, want a new user", email)
+	}
+
+	// the server answers 409 to a registered user
+	authenticated := runx.WithRequestEditorFn(func(_ context.Context, req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+runxtest.DefaultAPIKey)
+		return nil
+	})
+	session, err = runx.Register(ctx, srv.URL, authenticated)
+	var regErr *runx.RegisteredError
+	var apiErr *runx.APIError
+	if !errors.Is(err, runx.ErrAlreadyRegistered) || !errors.As(err, &regErr) || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Errorf("Register() = %+v, %v, want a *RegisteredError", session, err)
+	}
+
+	srv.InjectFault(runxtest.Fault{Operation: "Register", StatusCode: http.StatusServiceUnavailable, Times: 1})
+	if _, err := runx.Register(ctx, srv.URL); !errors.Is(err, runx.ErrServer) || errors.Is(err, runx.ErrAlreadyRegistered) {
+		t.Errorf("Register() error = %v, want ErrServer", err)
+	}
+}
+
+func TestPromptLogin(t *testing.T) {
+	srv := runxtest.NewServer()
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		input      string
+		wantNumber string
+		wantErr    error
+		wantOutput []string
+	}{
+		{
+			name:       "valid number",
+			input:      "+33 6 00 00 00 00\n",
+			wantNumber: runxtest.DefaultNumber,
+			wantOutput: []string{"Phone number (E.164, e.g. +33612345678): "},
+		},
+		{
+			name:       "invalid then valid number",
+			input:      "abc\n0600000000\n+33600000000\n",
+			wantNumber: runxtest.DefaultNumber,
+			wantOutput: []string{`invalid phone number "abc": unexpected character 'a'`, `invalid phone number "0600000000": missing + country code prefix`},
+		},
+		{
+			name:       "three invalid numbers",
+			input:      "abc\nabc\nabc\n+33600000000\n",
+			wantErr:    runx.ErrInvalidNumber,
+			wantOutput: []string{`invalid phone number "abc"`},
+		},
+		{
+			name:       "unknown number registered",
+			input:      "+33611111111\nyes\n",
+			wantOutput: []string{"No account uses this number. Register a new account? [y/N] "},
+		},
+		{
+			name:    "unknown number not registered",
+			input:   "+33611111111\nn\n",
+			wantErr: runx.ErrNotFound,
+		},
+		{
+			name:    "unknown number by default",
+			input:   "+33611111111\n\n",
+			wantErr: runx.ErrNotFound,
+		},
+		{
+			name:    "no input",
+			input:   "",
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "no answer",
+			input:   "+33611111111\n",
+			wantErr: io.ErrUnexpectedEOF,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			session, err := runx.PromptLogin(context.Background(), srv.URL, strings.NewReader(tt.input), &out)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("PromptLogin() = %+v, %v, want %v", session, err, tt.wantErr)
+				}
+			} else if err != nil || session.Number != tt.wantNumber || session.Token == "" {
+				t.Errorf("PromptLogin() = %+v, %v, want number %q", session, err, tt.wantNumber)
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output = %q, want %q", out.String(), want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,406 @@
+package runx
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogFormat is the format of a log line.
+type LogFormat string
+
+// Defines values for LogFormat.
+const (
+	LogFormatJSON   LogFormat = "json"
+	LogFormatLogfmt LogFormat = "logfmt"
+	LogFormatPlain  LogFormat = "plain"
+)
+
+// LogRecord is a parsed log line, see ParseLogLine.
+type LogRecord struct {
+	// Format is the detected format of the line.
+	Format LogFormat `json:"format"`
+
+	// Time, Level and Message are extracted from the well-known fields of
+	// JSON and logfmt lines, or from the start of plain lines. Time is nil
+	// and Level empty when the line holds none.
+	Time    *time.Time `json:"time,omitempty"`
+	Level   LogLevel   `json:"level,omitempty"`
+	Message string     `json:"message"`
+
+	// Fields are the other fields of JSON and logfmt lines. JSON values keep
+	// their type, logfmt values are strings.
+	Fields map[string]any `json:"fields,omitempty"`
+
+	// Raw is the line as read.
+	Raw string `json:"raw"`
+}
+
+// Keys recognized as the time, level and message of JSON and logfmt lines,
+// in order of preference.
+var (
+	logTimeKeys    = []string{"time", "ts", "timestamp", "@timestamp", "t"}
+	logLevelKeys   = []string{"level", "lvl", "severity", "@level", "log.level"}
+	logMessageKeys = []string{"msg", "message", "@message"}
+)
+
+// ParseLogLine parses line, detecting whether it is a JSON object, a sequence
+// of logfmt key=value pairs or plain text. Plain text and logfmt lines may
+// start with an RFC 3339 timestamp. The level of a plain line is found by
+// DetectLogLevel, and the message is the rest of the line once the timestamp
+// and a leading level word are removed.
+func ParseLogLine(line string) LogRecord {
+	r := LogRecord{Format: LogFormatPlain, Raw: line}
+	if fields, ok := parseJSONLine(line); ok {
+		r.Format = LogFormatJSON
+		r.setFields(fields)
+		return r
+	}
+
+	rest := line
+	if field, after, _ := strings.Cut(line, " "); field != "" {
+		if t, err := time.Parse(time.RFC3339Nano, field); err == nil {
+			r.Time = &t
+			rest = strings.TrimLeft(after, " ")
+		}
+	}
+	if fields, ok := parseLogfmt(rest); ok {
+		r.Format = LogFormatLogfmt
+		prefixed := r.Time
+		r.setFields(fields)
+		if r.Time == nil {
+			r.Time = prefixed
+		}
+		return r
+	}
+	r.Level = DetectLogLevel(rest)
+	r.Message = trimLevel(rest)
+	return r
+}
+
+// ParseLog splits log, the Log field of a GetApp response, into records. An
+// indented plain line, such as a frame of a stack trace, continues the
+// message and the raw line of the record before it.
+func ParseLog(log string) []LogRecord {
+	var records []LogRecord
+	for _, line := range splitLines(log) {
+		if n := len(records); n > 0 && continuation(line) {
+			records[n-1].Message += "\n" + line
+			records[n-1].Raw += "\n" + line
+			continue
+		}
+		records = append(records, ParseLogLine(line))
+	}
+	return records
+}
+
+// setFields extracts the time, level and message of fields, leaving the others
+// in r.Fields.
+func (r *LogRecord) setFields(fields map[string]any) {
+	if v, key := lookup(fields, logTimeKeys); key != "" {
+		if t, ok := parseLogTime(v); ok {
+			r.Time = &t
+			delete(fields, key)
+		}
+	}
+	if v, key := lookup(fields, logLevelKeys); key != "" {
+		if level, err := ParseLogLevel(fmt.Sprint(v)); err == nil {
+			r.Level = level
+			delete(fields, key)
+		}
+	}
+	if v, key := lookup(fields, logMessageKeys); key != "" {
+		r.Message = fmt.Sprint(v)
+		delete(fields, key)
+	}
+	if len(fields) > 0 {
+		r.Fields = fields
+	}
+}
+
+// Render formats r as a line of format. The time, level and message come
+// first, followed by the fields sorted by key.
+func (r LogRecord) Render(format LogFormat) string {
+	keys := make([]string, 0, len(r.Fields))
+	for k := range r.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	switch format {
+	case LogFormatJSON:
+		obj := make(map[string]any, len(r.Fields)+3)
+		for k, v := range r.Fields {
+			obj[k] = v
+		}
+		if r.Time != nil {
+			obj["time"] = r.Time.Format(time.RFC3339Nano)
+		}
+		if r.Level != "" {
+			obj["level"] = strings.ToLower(string(r.Level))
+		}
+		obj["msg"] = r.Message
+		// maps are marshalled with sorted keys
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return r.Raw
+		}
+		return string(b)
+
+	case LogFormatLogfmt:
+		var pairs []string
+		if r.Time != nil {
+			pairs = append(pairs, "time="+r.Time.Format(time.RFC3339Nano))
+		}
+		if r.Level != "" {
+			pairs = append(pairs, "level="+strings.ToLower(string(r.Level)))
+		}
+		pairs = append(pairs, "msg="+logfmtValue(r.Message))
+		for _, k := range keys {
+			pairs = append(pairs, k+"="+logfmtValue(fmt.Sprint(r.Fields[k])))
+		}
+		return strings.Join(pairs, " ")
+	}
+
+	var parts []string
+	if r.Time != nil {
+		parts = append(parts, r.Time.Format(time.RFC3339Nano))
+	}
+	if r.Level != "" {
+		parts = append(parts, string(r.Level))
+	}
+	parts = append(parts, r.Message)
+	for _, k := range keys {
+		parts = append(parts, k+"="+logfmtValue(fmt.Sprint(r.Fields[k])))
+	}
+	return strings.Join(parts, " ")
+}
+
+// LogFilter selects log records. The zero LogFilter matches every record.
+type LogFilter struct {
+	// Since and Until bound the time of the records, Until being excluded.
+	// A record without a time does not match when either is set.
+	Since time.Time
+	Until time.Time
+
+	// MinLevel keeps the records of this level or more severe. A record
+	// without a level does not match when it is set.
+	MinLevel LogLevel
+
+	// Include keeps the records whose raw line matches one of the patterns,
+	// and Exclude drops those matching any of them.
+	Include []*regexp.Regexp
+	Exclude []*regexp.Regexp
+
+	// Fields keeps the records holding every field with the given value,
+	// compared in its fmt.Sprint form.
+	Fields map[string]string
+}
+
+// Match reports whether r is selected by f.
+func (f *LogFilter) Match(r LogRecord) bool {
+	if !f.Since.IsZero() && (r.Time == nil || r.Time.Before(f.Since)) {
+		return false
+	}
+	if !f.Until.IsZero() && (r.Time == nil || !r.Time.Before(f.Until)) {
+		return false
+	}
+	if f.MinLevel != "" && (r.Level == "" || r.Level.severity() < f.MinLevel.severity()) {
+		return false
+	}
+	if len(f.Include) > 0 && !matchAny(f.Include, r.Raw) {
+		return false
+	}
+	if matchAny(f.Exclude, r.Raw) {
+		return false
+	}
+	for k, want := range f.Fields {
+		v, ok := r.Fields[k]
+		if !ok || fmt.Sprint(v) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the records of records matched by f.
+func (f *LogFilter) Filter(records []LogRecord) []LogRecord {
+	var matched []LogRecord
+	for _, r := range records {
+		if f.Match(r) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+func matchAny(patterns []*regexp.Regexp, line string) bool {
+	for _, re := range patterns {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// LogLevel is the severity of a log line.
+type LogLevel string
+
+// Defines values for LogLevel.
+const (
+	LogLevelDebug LogLevel = "DEBUG"
+	LogLevelInfo  LogLevel = "INFO"
+	LogLevelWarn  LogLevel = "WARN"
+	LogLevelError LogLevel = "ERROR"
+)
+
+// severity orders the levels, 0 being unknown.
+func (l LogLevel) severity() int {
+	switch l {
+	case LogLevelDebug:
+		return 1
+	case LogLevelInfo:
+		return 2
+	case LogLevelWarn:
+		return 3
+	case LogLevelError:
+		return 4
+	}
+	return 0
+}
+
+// ParseLogLevel parses a level name, such as "warn", "WARNING" or "fatal",
+// the latter being reported as LogLevelError.
+func ParseLogLevel(s string) (LogLevel, error) {
+	switch strings.ToUpper(s) {
+	case "TRACE", "DEBUG", "DBG":
+		return LogLevelDebug, nil
+	case "INFO", "INF", "NOTICE":
+		return LogLevelInfo, nil
+	case "WARN", "WARNING", "WRN":
+		return LogLevelWarn, nil
+	case "ERROR", "ERR", "FATAL", "PANIC", "CRIT", "CRITICAL", "ALERT", "EMERG":
+		return LogLevelError, nil
+	}
+	return "", fmt.Errorf("runx: unknown log level %q", s)
+}
+
+// levelPattern matches an upper-case level word, or a level=value or
+// "level":"value" field in any case.
+var levelPattern = regexp.MustCompile(`\b(TRACE|DEBUG|INFO|NOTICE|WARN|WARNING|ERROR|ERR|FATAL|PANIC|CRIT|CRITICAL)\b|(?i:\blevel"?\s*[=:]\s*"?([a-z]+))`)
+
+// DetectLogLevel returns the level line mentions, or "" when it mentions none.
+func DetectLogLevel(line string) LogLevel {
+	for _, m := range levelPattern.FindAllStringSubmatch(line, -1) {
+		if level, err := ParseLogLevel(m[1] + m[2]); err == nil {
+			return level
+		}
+	}
+	return ""
+}
+
+// leadingLevel matches a level word at the start of a plain line, possibly
+// within brackets or followed by a colon.
+var leadingLevel = regexp.MustCompile(`^\[?(TRACE|DEBUG|INFO|NOTICE|WARN|WARNING|ERROR|ERR|FATAL|PANIC|CRIT|CRITICAL)\]?:?(\s+|$)`)
+
+func trimLevel(s string) string {
+	return s[len(leadingLevel.FindString(s)):]
+}
+
+// continuation reports whether line continues the line before it.
+func continuation(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
+func parseJSONLine(line string) (map[string]any, bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "{") {
+		return nil, false
+	}
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(trimmed), &fields); err != nil {
+		return nil, false
+	}
+	return fields, true
+}
+
+// parseLogfmt parses s as logfmt key=value pairs, whose values may be quoted.
+// It fails unless every word of s is a pair.
+func parseLogfmt(s string) (map[string]any, bool) {
+	fields := map[string]any{}
+	for s = strings.TrimLeft(s, " "); s != ""; s = strings.TrimLeft(s, " ") {
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || strings.ContainsAny(s[:eq], " \"") {
+			return nil, false
+		}
+		key := s[:eq]
+		s = s[eq+1:]
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			quoted, err := strconv.QuotedPrefix(s)
+			if err != nil {
+				return nil, false
+			}
+			value, _ = strconv.Unquote(quoted)
+			s = s[len(quoted):]
+			if s != "" && s[0] != ' ' {
+				return nil, false
+			}
+		} else {
+			value, s, _ = strings.Cut(s, " ")
+		}
+		fields[key] = value
+	}
+	return fields, len(fields) > 0
+}
+
+// logfmtValue quotes v when it holds spaces, quotes or equal signs.
+func logfmtValue(v string) string {
+	if v == "" || strings.ContainsAny(v, " \"=\t\n") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// lookup returns the value of the first of keys held by fields, and that key.
+func lookup(fields map[string]any, keys []string) (any, string) {
+	for _, k := range keys {
+		if v, ok := fields[k]; ok {
+			return v, k
+		}
+	}
+	return nil, ""
+}
+
+// parseLogTime parses an RFC 3339 time, or a Unix time in seconds or
+// milliseconds.
+func parseLogTime(v any) (time.Time, bool) {
+	switch v := v.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			f, ferr := strconv.ParseFloat(v, 64)
+			if ferr != nil {
+				return time.Time{}, false
+			}
+			return unixTime(f), true
+		}
+		return t, true
+	case float64:
+		return unixTime(v), true
+	}
+	return time.Time{}, false
+}
+
+func unixTime(f float64) time.Time {
+	if f > 1e12 {
+		f /= 1000
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+}
@@ -0,0 +1,311 @@
+package runx
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var noon = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestParseLogLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want LogRecord
+	}{
+		{
+			name: "json",
+			line: `{"time":"2024-06-01T12:00:00Z","level":"warn","msg":"slow request","path":"/api","ms":812}`,
+			want: LogRecord{Format: LogFormatJSON, Time: &noon, Level: LogLevelWarn, Message: "slow request", Fields: map[string]any{"path": "/api", "ms": 812.0}},
+		},
+		{
+			name: "nested json",
+			line: `  {"ts":1717243200,"severity":"ERROR","message":"query failed","db":{"host":"pg","port":5432,"replicas":["a","b"]},"retry":null}`,
+			want: LogRecord{Format: LogFormatJSON, Time: &noon, Level: LogLevelError, Message: "query failed", Fields: map[string]any{
+				"db":    map[string]any{"host": "pg", "port": 5432.0, "replicas": []any{"a", "b"}},
+				"retry": nil,
+			}},
+		},
+		{
+			name: "json with an unknown level",
+			line: `{"level":"verbose","msg":"hello"}`,
+			want: LogRecord{Format: LogFormatJSON, Message: "hello", Fields: map[string]any{"level": "verbose"}},
+		},
+		{
+			name: "json with an unparsable time",
+			line: `{"time":"yesterday","msg":"hello"}`,
+			want: LogRecord{Format: LogFormatJSON, Message: "hello", Fields: map[string]any{"time": "yesterday"}},
+		},
+		{
+			name: "invalid json",
+			line: `{"msg": "unterminated`,
+			want: LogRecord{Format: LogFormatPlain, Message: `{"msg": "unterminated`},
+		},
+		{
+			name: "logfmt",
+			line: `time=2024-06-01T12:00:00Z level=info msg=started port=8080`,
+			want: LogRecord{Format: LogFormatLogfmt, Time: &noon, Level: LogLevelInfo, Message: "started", Fields: map[string]any{"port": "8080"}},
+		},
+		{
+			name: "quoted logfmt values",
+			line: `lvl=dbg msg="user logged in" user="bob \"the builder\"" path="/a b" empty=""`,
+			want: LogRecord{Format: LogFormatLogfmt, Level: LogLevelDebug, Message: "user logged in", Fields: map[string]any{
+				"user":  `bob "the builder"`,
+				"path":  "/a b",
+				"empty": "",
+			}},
+		},
+		{
+			name: "logfmt after a timestamp",
+			line: `2024-06-01T12:00:00Z level=error msg="disk full"`,
+			want: LogRecord{Format: LogFormatLogfmt, Time: &noon, Level: LogLevelError, Message: "disk full"},
+		},
+		{
+			name: "logfmt with an unterminated quote",
+			line: `level=info msg="oops`,
+			want: LogRecord{Format: LogFormatPlain, Level: LogLevelInfo, Message: `level=info msg="oops`},
+		},
+		{
+			name: "logfmt with a word",
+			line: `starting server port=8080`,
+			want: LogRecord{Format: LogFormatPlain, Message: "starting server port=8080"},
+		},
+		{
+			name: "plain with time and level",
+			line: `2024-06-01T12:00:00Z [ERROR]: connection refused`,
+			want: LogRecord{Format: LogFormatPlain, Time: &noon, Level: LogLevelError, Message: "connection refused"},
+		},
+		{
+			name: "plain with a leading level",
+			line: `WARNING cache miss`,
+			want: LogRecord{Format: LogFormatPlain, Level: LogLevelWarn, Message: "cache miss"},
+		},
+		{
+			name: "plain mentioning a level",
+			line: `request failed with ERROR 42`,
+			want: LogRecord{Format: LogFormatPlain, Level: LogLevelError, Message: "request failed with ERROR 42"},
+		},
+		{
+			name: "plain with a level field",
+			line: `[app] Level: Warning, retrying`,
+			want: LogRecord{Format: LogFormatPlain, Level: LogLevelWarn, Message: "[app] Level: Warning, retrying"},
+		},
+		{
+			name: "plain lower-case words are not levels",
+			line: `no error found`,
+			want: LogRecord{Format: LogFormatPlain, Message: "no error found"},
+		},
+		{
+			name: "empty",
+			line: ``,
+			want: LogRecord{Format: LogFormatPlain},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.Raw = tt.line
+			got := ParseLogLine(tt.line)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLogLine(%q) =\n%+v\nwant\n%+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want LogLevel
+	}{
+		{"trace", LogLevelDebug},
+		{"DEBUG", LogLevelDebug},
+		{"dbg", LogLevelDebug},
+		{"Info", LogLevelInfo},
+		{"INF", LogLevelInfo},
+		{"notice", LogLevelInfo},
+		{"warn", LogLevelWarn},
+		{"WARNING", LogLevelWarn},
+		{"wrn", LogLevelWarn},
+		{"error", LogLevelError},
+		{"ERR", LogLevelError},
+		{"fatal", LogLevelError},
+		{"panic", LogLevelError},
+		{"crit", LogLevelError},
+		{"CRITICAL", LogLevelError},
+		{"alert", LogLevelError},
+		{"emerg", LogLevelError},
+	}
+	for _, tt := range tests {
+		got, err := ParseLogLevel(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseLogLevel(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "verbose", "warnings", "5"} {
+		if got, err := ParseLogLevel(in); err == nil {
+			t.Errorf("ParseLogLevel(%q) = %q, want an error", in, got)
+		}
+	}
+	if !(LogLevelDebug.severity() < LogLevelInfo.severity() && LogLevelInfo.severity() < LogLevelWarn.severity() && LogLevelWarn.severity() < LogLevelError.severity()) {
+		t.Error("levels are not ordered by severity")
+	}
+}
+
+func TestParseLogTime(t *testing.T) {
+	paris := time.FixedZone("", 2*60*60)
+	tests := []struct {
+		name   string
+		in     any
+		want   time.Time
+		wantOK bool
+	}{
+		{"rfc3339", "2024-06-01T12:00:00Z", noon, true},
+		{"rfc3339 with offset", "2024-06-01T14:00:00+02:00", time.Date(2024, 6, 1, 14, 0, 0, 0, paris), true},
+		{"rfc3339 with nanoseconds", "2024-06-01T12:00:00.123456789Z", noon.Add(123456789), true},
+		{"unix seconds", 1717243200.0, noon, true},
+		{"unix seconds with fraction", 1717243200.25, noon.Add(250 * time.Millisecond), true},
+		{"unix milliseconds", 1717243200500.0, noon.Add(500 * time.Millisecond), true},
+		{"unix seconds as a string", "1717243200", noon, true},
+		{"unix milliseconds as a string", "1717243200500", noon.Add(500 * time.Millisecond), true},
+		{"date only", "2024-06-01", time.Time{}, false},
+		{"text", "yesterday", time.Time{}, false},
+		{"bool", true, time.Time{}, false},
+		{"nil", nil, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseLogTime(tt.in)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("parseLogTime(%v) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseLog(t *testing.T) {
+	log := "  orphan continuation\n" +
+		"2024-06-01T12:00:00Z ERROR panic: boom\n" +
+		"\tgoroutine 1 [running]:\n" +
+		"    main.go:10\n" +
+		"2024-06-01T12:00:01Z INFO restarted\n" +
+		"\n" +
+		`{"msg":"json"}` + "\n" +
+		"  at x.go:2\n"
+	records := ParseLog(log)
+	want := []struct {
+		message string
+		raw     string
+		level   LogLevel
+	}{
+		// a leading indented line has no record to continue
+		{"  orphan continuation", "  orphan continuation", ""},
+		{"panic: boom\n\tgoroutine 1 [running]:\n    main.go:10", "2024-06-01T12:00:00Z ERROR panic: boom\n\tgoroutine 1 [running]:\n    main.go:10", LogLevelError},
+		{"restarted", "2024-06-01T12:00:01Z INFO restarted", LogLevelInfo},
+		{"", "", ""},
+		{"json\n  at x.go:2", "{\"msg\":\"json\"}\n  at x.go:2", ""},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("ParseLog() = %d records, want %d: %+v", len(records), len(want), records)
+	}
+	for i, w := range want {
+		r := records[i]
+		if r.Message != w.message {
+			t.Errorf("record %d message = %q, want %q", i, r.Message, w.message)
+		}
+		if r.Raw != w.raw || r.Level != w.level {
+			t.Errorf("record %d = raw %q, level %q, want %q, %q", i, r.Raw, r.Level, w.raw, w.level)
+		}
+	}
+	if ParseLog("") != nil {
+		t.Error("ParseLog(\"\") returned records")
+	}
+}
+
+func TestLogRecordRender(t *testing.T) {
+	r := LogRecord{Time: &noon, Level: LogLevelInfo, Message: "user logged in", Fields: map[string]any{"user": "bob", "n": "3", "path": "/a b"}}
+	tests := []struct {
+		format LogFormat
+		want   string
+	}{
+		{LogFormatJSON, `{"level":"info","msg":"user logged in","n":"3","path":"/a b","time":"2024-06-01T12:00:00Z","user":"bob"}`},
+		{LogFormatLogfmt, `time=2024-06-01T12:00:00Z level=info msg="user logged in" n=3 path="/a b" user=bob`},
+		{LogFormatPlain, `2024-06-01T12:00:00Z INFO user logged in n=3 path="/a b" user=bob`},
+	}
+	for _, tt := range tests {
+		if got := r.Render(tt.format); got != tt.want {
+			t.Errorf("Render(%s) =\n%s\nwant\n%s", tt.format, got, tt.want)
+		}
+	}
+	if got, want := (LogRecord{Message: "bare"}).Render(LogFormatLogfmt), "msg=bare"; got != want {
+		t.Errorf("Render(logfmt) = %q, want %q", got, want)
+	}
+}
+
+func TestLogRecordRoundTrip(t *testing.T) {
+	lines := []string{
+		`{"time":"2024-06-01T12:00:00.5Z","level":"error","msg":"query failed","db":{"host":"pg","port":5432},"tags":["a","b"],"ok":false}`,
+		`time=2024-06-01T12:00:00Z level=warn msg="slow request" path=/api user="bob \"b\""`,
+		`2024-06-01T12:00:00Z DEBUG cache warmed`,
+		`level=info msg=empty value=""`,
+	}
+	for _, line := range lines {
+		r := ParseLogLine(line)
+		for _, format := range []LogFormat{LogFormatJSON, LogFormatLogfmt, LogFormatPlain} {
+			if format == LogFormatPlain && len(r.Fields) > 0 {
+				// plain lines do not keep their fields apart from the message
+				continue
+			}
+			if format == LogFormatLogfmt && r.Format == LogFormatJSON {
+				// logfmt turns the nested JSON values into strings
+				continue
+			}
+			rendered := r.Render(format)
+			got := ParseLogLine(rendered)
+			if got.Format != format {
+				t.Errorf("%q rendered as %s is parsed as %s: %s", line, format, got.Format, rendered)
+			}
+			if !reflect.DeepEqual(got.Time, r.Time) || got.Level != r.Level || got.Message != r.Message || !reflect.DeepEqual(got.Fields, r.Fields) {
+				t.Errorf("%q rendered as %s does not round-trip:\n%s\n%+v\nwant\n%+v", line, format, rendered, got, r)
+			}
+			if again := got.Render(format); again != rendered {
+				t.Errorf("%q rendered as %s is not stable:\n%s\n%s", line, format, rendered, again)
+			}
+		}
+	}
+}
+
+func TestLogFilter(t *testing.T) {
+	records := ParseLog(
+		"2024-06-01T11:00:00Z DEBUG starting\n" +
+			"2024-06-01T12:00:00Z level=info msg=ready port=8080\n" +
+			`{"time":"2024-06-01T13:00:00Z","level":"error","msg":"crashed","port":8080}` + "\n" +
+			"no time nor level\n",
+	)
+	tests := []struct {
+		name   string
+		filter LogFilter
+		want   []string
+	}{
+		{"zero", LogFilter{}, []string{"starting", "ready", "crashed", "no time nor level"}},
+		{"since", LogFilter{Since: noon}, []string{"ready", "crashed"}},
+		{"until", LogFilter{Until: noon}, []string{"starting"}},
+		{"min level", LogFilter{MinLevel: LogLevelInfo}, []string{"ready", "crashed"}},
+		{"include", LogFilter{Include: []*regexp.Regexp{regexp.MustCompile(`start|crash`)}}, []string{"starting", "crashed"}},
+		{"exclude", LogFilter{Exclude: []*regexp.Regexp{regexp.MustCompile(`(?i)debug|msg`)}}, []string{"no time nor level"}},
+		// a JSON number and a logfmt string compare in their fmt.Sprint form
+		{"fields", LogFilter{Fields: map[string]string{"port": "8080"}}, []string{"ready", "crashed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range tt.filter.Filter(records) {
+				got = append(got, r.Message)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Filter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package runx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// LogEventType is the kind of event reported by a LogStreamer.
+type LogEventType string
+
+// Defines values for LogEventType.
+const (
+	LogNewLine   LogEventType = "LINE"
+	LogTruncated LogEventType = "TRUNCATED"
+	LogError     LogEventType = "ERROR"
+)
+
+// LogEvent is an event reported by a LogStreamer or a LogAggregator.
+type LogEvent struct {
+	Type LogEventType `json:"type"`
+
+	// App is the app id given to NewLogStreamer.
+	App string `json:"app"`
+
+	// Name is the name of the app, set by LogAggregator.
+	Name string `json:"name,omitempty"`
+
+	// Line is the new line of LogNewLine events, without its line break.
+	Line string `json:"line,omitempty"`
+
+	// Time is the time of the line, see ParseLogLine, or that of the closest
+	// previous line holding one. It is nil when no time was seen yet.
+	Time *time.Time `json:"time,omitempty"`
+
+	// Level is the level of the line or, for an indented line without a
+	// level, that of the line before it.
+	Level LogLevel `json:"level,omitempty"`
+
+	// Record is the line parsed by ParseLogLine.
+	Record *LogRecord `json:"record,omitempty"`
+
+	// Err is set on LogError events, after which the poll is retried.
+	Err error `json:"-"`
+}
+
+// LogOption configures a LogStreamer.
+type LogOption func(*LogStreamer)
+
+// WithLogFollow keeps polling the log for new lines until the context is
+// done, instead of returning after the first fetch.
+func WithLogFollow() LogOption {
+	return func(s *LogStreamer) {
+		s.follow = true
+	}
+}
+
+// WithLogInterval sets the time between two polls when following the log.
+// It defaults to two seconds.
+func WithLogInterval(d time.Duration) LogOption {
+	return func(s *LogStreamer) {
+		s.interval = d
+	}
+}
+
+// WithLogSince skips the lines older than t, according to their time.
+func WithLogSince(t time.Time) LogOption {
+	return func(s *LogStreamer) {
+		s.filter.Since = t
+	}
+}
+
+// WithLogTail limits the lines of the first fetch to the last n. Later lines
+// are all reported. A negative n, the default, reports every line.
+func WithLogTail(n int) LogOption {
+	return func(s *LogStreamer) {
+		s.tail = n
+	}
+}
+
+// WithLogInclude keeps only the lines matching at least one of patterns.
+func WithLogInclude(patterns ...*regexp.Regexp) LogOption {
+	return func(s *LogStreamer) {
+		s.filter.Include = append(s.filter.Include, patterns...)
+	}
+}
+
+// WithLogExclude drops the lines matching any of patterns.
+func WithLogExclude(patterns ...*regexp.Regexp) LogOption {
+	return func(s *LogStreamer) {
+		s.filter.Exclude = append(s.filter.Exclude, patterns...)
+	}
+}
+
+// WithMinLogLevel keeps only the lines of level min or more severe, dropping
+// the lines whose level is unknown.
+func WithMinLogLevel(min LogLevel) LogOption {
+	return func(s *LogStreamer) {
+		s.filter.MinLevel = min
+	}
+}
+
+// WithLogField keeps only the records holding the field key with value, see
+// LogFilter.Fields.
+func WithLogField(key, value string) LogOption {
+	return func(s *LogStreamer) {
+		if s.filter.Fields == nil {
+			s.filter.Fields = map[string]string{}
+		}
+		s.filter.Fields[key] = value
+	}
+}
+
+// WithLogBuffer sets the capacity of the events channel. It defaults to 64.
+func WithLogBuffer(n int) LogOption {
+	return func(s *LogStreamer) {
+		s.buffer = n
+	}
+}
+
+// LogStreamer polls GetApp and reports the lines of the app log it has not
+// seen yet. The server returns the whole log on every call: the lines already
+// reported are recognized as the head of the new log, or as its overlap with
+// the end of the previous one when the server dropped its oldest lines. A log
+// which does not continue the previous one has been truncated or rotated, and
+// is reported as a LogTruncated event before all of its lines.
+type LogStreamer struct {
+	client   *ClientWithResponses
+	appId    string
+	follow   bool
+	interval time.Duration
+	tail     int
+	filter   LogFilter
+	buffer   int
+	events   chan LogEvent
+
+	seen  []string
+	last  *time.Time
+	level LogLevel
+	first bool
+}
+
+// NewLogStreamer creates a LogStreamer reading the log of the app identified
+// by appId, either its Id or ShortId. Events are delivered once Run is called.
+func NewLogStreamer(client *ClientWithResponses, appId string, opts ...LogOption) *LogStreamer {
+	s := &LogStreamer{
+		client:   client,
+		appId:    appId,
+		interval: 2 * time.Second,
+		tail:     -1,
+		buffer:   64,
+		first:    true,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	s.events = make(chan LogEvent, s.buffer)
+	return s
+}
+
+// Events returns the channel delivering the events. It is closed when Run returns.
+func (s *LogStreamer) Events() <-chan LogEvent {
+	return s.events
+}
+
+// Run fetches the log and reports its new lines, then closes the events
+// channel. Without WithLogFollow it returns after the first fetch, with its
+// error if any. When following, it returns nil once ctx is done; a transient
+// failure is reported as a LogError event and retried on the next tick, while
+// any other failure, such as the app being deleted, is returned.
+func (s *LogStreamer) Run(ctx context.Context) error {
+	defer close(s.events)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		err := s.poll(ctx)
+		switch {
+		case ctx.Err() != nil:
+			if s.follow {
+				return nil
+			}
+			return ctx.Err()
+		case err != nil && (!s.follow || !transient(err)):
+			return err
+		case err != nil:
+			if !s.emit(ctx, LogEvent{Type: LogError, App: s.appId, Err: err}) {
+				return nil
+			}
+		case !s.follow:
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll fetches the log and emits its new lines.
+func (s *LogStreamer) poll(ctx context.Context) error {
+	log, err := s.client.appLog(ctx, s.appId)
+	if err != nil {
+		return err
+	}
+	lines := splitLines(log)
+	fresh, truncated := newLines(s.seen, lines)
+	s.seen = lines
+	if truncated && !s.emit(ctx, LogEvent{Type: LogTruncated, App: s.appId, Time: s.last}) {
+		return ctx.Err()
+	}
+
+	events := make([]LogEvent, 0, len(fresh))
+	for _, line := range fresh {
+		record := ParseLogLine(line)
+		if record.Time != nil {
+			s.last = record.Time
+		}
+		if record.Level != "" || !continuation(line) {
+			s.level = record.Level
+		}
+		// lines without a time or level are filtered as part of the line before them
+		inherited := record
+		inherited.Time, inherited.Level = s.last, s.level
+		if !s.filter.Match(inherited) {
+			continue
+		}
+		events = append(events, LogEvent{Type: LogNewLine, App: s.appId, Line: line, Time: s.last, Level: s.level, Record: &record})
+	}
+	if s.first && s.tail >= 0 && len(events) > s.tail {
+		events = events[len(events)-s.tail:]
+	}
+	s.first = false
+	for _, e := range events {
+		if !s.emit(ctx, e) {
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (s *LogStreamer) emit(ctx context.Context, e LogEvent) bool {
+	select {
+	case s.events <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// StreamLogs writes the new lines of the log of the app identified by appId
+// to w, one per line, see LogStreamer. Truncations and transient errors are
+// not reported.
+func (c *ClientWithResponses) StreamLogs(ctx context.Context, appId string, w io.Writer, opts ...LogOption) error {
+	s := NewLogStreamer(c, appId, opts...)
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx)
+	}()
+	var werr error
+	for e := range s.Events() {
+		if e.Type == LogNewLine && werr == nil {
+			_, werr = fmt.Fprintln(w, e.Line)
+		}
+	}
+	if err := <-done; err != nil {
+		return err
+	}
+	return werr
+}
+
+// appLog returns the log of the app identified by appId through GetApp,
+// turning error responses into an *APIError.
+func (c *ClientWithResponses) appLog(ctx context.Context, appId string) (string, error) {
+	rsp, err := c.GetAppWithResponse(ctx, appId)
+	if err != nil {
+		return "", err
+	}
+	if err := CheckResponse("GetApp", rsp.HTTPResponse, rsp.Body); err != nil {
+		return "", err
+	}
+	if rsp.JSON200 == nil {
+		return "", nil
+	}
+	return deref(rsp.JSON200.Log), nil
+}
+
+// splitLines splits log into lines, dropping the empty line after a final
+// line break.
+func splitLines(log string) []string {
+	if log == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(log, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// newLines returns the lines of cur not in prev, the lines of the previous
+// poll. cur normally starts with prev. When the server dropped the oldest
+// lines, the end of prev is the start of cur. Otherwise the log was truncated
+// or rotated, and all of cur is new.
+func newLines(prev, cur []string) (fresh []string, truncated bool) {
+	if len(prev) == 0 {
+		return cur, false
+	}
+	if len(cur) >= len(prev) && equalLines(cur[:len(prev)], prev) {
+		return cur[len(prev):], false
+	}
+	for k := min(len(prev)-1, len(cur)); k > 0; k-- {
+		if equalLines(prev[len(prev)-k:], cur[:k]) {
+			return cur[k:], false
+		}
+	}
+	return cur, true
+}
+
+func equalLines(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package runx
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNewLines(t *testing.T) {
+	tests := []struct {
+		name          string
+		prev, cur     []string
+		want          []string
+		wantTruncated bool
+	}{
+		{
+			name: "empty prev",
+			cur:  []string{"a", "b"},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "both empty",
+		},
+		{
+			name: "unchanged",
+			prev: []string{"a", "b"},
+			cur:  []string{"a", "b"},
+			want: []string{},
+		},
+		{
+			name: "appended",
+			prev: []string{"a", "b"},
+			cur:  []string{"a", "b", "c", "d"},
+			want: []string{"c", "d"},
+		},
+		{
+			name: "head dropped",
+			prev: []string{"a", "b", "c"},
+			cur:  []string{"b", "c", "d"},
+			want: []string{"d"},
+		},
+		{
+			name: "head dropped without new lines",
+			prev: []string{"a", "b", "c"},
+			cur:  []string{"c"},
+			want: []string{},
+		},
+		{
+			name: "head dropped, single line overlap",
+			prev: []string{"a", "b", "c"},
+			cur:  []string{"c", "d", "e"},
+			want: []string{"d", "e"},
+		},
+		{
+			name:          "full rotation",
+			prev:          []string{"a", "b", "c"},
+			cur:           []string{"x", "y"},
+			want:          []string{"x", "y"},
+			wantTruncated: true,
+		},
+		{
+			name:          "rotated with the first lines again",
+			prev:          []string{"start", "a", "b"},
+			cur:           []string{"start"},
+			want:          []string{"start"},
+			wantTruncated: true,
+		},
+		{
+			name:          "emptied",
+			prev:          []string{"a", "b"},
+			want:          nil,
+			wantTruncated: true,
+		},
+		{
+			name: "repeated lines appended",
+			prev: []string{"ping", "ping"},
+			cur:  []string{"ping", "ping", "ping"},
+			want: []string{"ping"},
+		},
+		{
+			// the longest overlap wins, reporting the fewest lines
+			name: "repeated lines with head dropped",
+			prev: []string{"a", "ping", "ping"},
+			cur:  []string{"ping", "ping", "ping"},
+			want: []string{"ping"},
+		},
+		{
+			name: "repeated lines shifted",
+			prev: []string{"ping", "pong", "ping", "pong"},
+			cur:  []string{"ping", "pong", "ping", "pong", "ping"},
+			want: []string{"ping"},
+		},
+		{
+			name: "repeated lines, head dropped and appended",
+			prev: []string{"ping", "pong", "ping", "pong"},
+			cur:  []string{"pong", "ping", "pong", "x"},
+			want: []string{"x"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, truncated := newLines(tt.prev, tt.cur)
+			if !slices.Equal(got, tt.want) || truncated != tt.wantTruncated {
+				t.Errorf("newLines(%q, %q) = %q, %v, want %q, %v", tt.prev, tt.cur, got, truncated, tt.want, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		log  string
+		want []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a\n", []string{"a"}},
+		{"a\r\nb\r\n", []string{"a", "b"}},
+		{"a\n\nb", []string{"a", "", "b"}},
+		{"\n", []string{""}},
+	}
+	for _, tt := range tests {
+		if got := splitLines(tt.log); !slices.Equal(got, tt.want) {
+			t.Errorf("splitLines(%q) = %q, want %q", tt.log, got, tt.want)
+		}
+	}
+}
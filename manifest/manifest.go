@@ -0,0 +1,127 @@
+// Package manifest provides declarative Run X app manifests and a Reconciler
+// bringing the apps of an account in line with them.
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	runx "github.com/run-x-app/runx-go"
+)
+
+// Manifest lists the apps expected to run on an account. It is read from
+// YAML or JSON, for example
+//
+//	apps:
+//	  - name: web
+//	    app: nginx
+//	    cpu: 1
+//	    ram: 512
+//	    env: ["PORT=8080"]
+type Manifest struct {
+	Apps []App `json:"apps" yaml:"apps"`
+}
+
+// App describes a single app. Optional fields left empty are not managed:
+// whatever value the app currently has is kept.
+type App struct {
+	// Name identifies the app, it must be unique within the manifest.
+	Name string `json:"name" yaml:"name"`
+
+	// App is the id of the catalog app to run.
+	App string `json:"app" yaml:"app"`
+
+	Cmd     *string  `json:"cmd,omitempty" yaml:"cmd,omitempty"`
+	Cpu     *int     `json:"cpu,omitempty" yaml:"cpu,omitempty"`
+	Ram     *int     `json:"ram,omitempty" yaml:"ram,omitempty"`
+	Disk    *int     `json:"disk,omitempty" yaml:"disk,omitempty"`
+	Gpu     *int     `json:"gpu,omitempty" yaml:"gpu,omitempty"`
+	Env     []string `json:"env,omitempty" yaml:"env,omitempty"`
+	Enabled *bool    `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+}
+
+// Load reads the manifest stored at path.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("manifest: %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse decodes and validates a YAML or JSON manifest. Unknown keys are
+// rejected, so that a misspelled field is not silently left unmanaged.
+func Parse(data []byte) (*Manifest, error) {
+	var m Manifest
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Validate checks that every app has a unique name and a catalog app.
+func (m *Manifest) Validate() error {
+	var errs []error
+	names := map[string]bool{}
+	for i, app := range m.Apps {
+		switch {
+		case app.Name == "":
+			errs = append(errs, fmt.Errorf("apps[%d]: name is required", i))
+		case names[app.Name]:
+			errs = append(errs, fmt.Errorf("apps[%d]: duplicate name %q", i, app.Name))
+		}
+		names[app.Name] = true
+		if app.App == "" {
+			errs = append(errs, fmt.Errorf("apps[%d]: app is required", i))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// AppRequest returns the request creating the app.
+func (a App) AppRequest() runx.AppRequest {
+	req := runx.AppRequest{
+		Name: a.Name,
+		App:  a.App,
+		Cmd:  a.Cmd,
+		Cpu:  a.Cpu,
+		Ram:  a.Ram,
+		Disk: a.Disk,
+		Gpu:  a.Gpu,
+	}
+	if a.Env != nil {
+		env := append([]string(nil), a.Env...)
+		req.Env = &env
+	}
+	return req
+}
+
+// UpdateAppRequest returns the request updating an existing app to match a.
+func (a App) UpdateAppRequest() runx.UpdateAppRequest {
+	req := runx.UpdateAppRequest{
+		Cmd:  a.Cmd,
+		Cpu:  a.Cpu,
+		Ram:  a.Ram,
+		Disk: a.Disk,
+		Gpu:  a.Gpu,
+	}
+	if a.Env != nil {
+		env := append([]string(nil), a.Env...)
+		req.Env = &env
+	}
+	return req
+}
@@ -0,0 +1,75 @@
+package manifest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	one, falsy := 1, false
+	tests := []struct {
+		name    string
+		data    string
+		want    *Manifest
+		wantErr string
+	}{
+		{
+			name: "yaml",
+			data: "apps:\n  - name: web\n    app: nginx\n    cpu: 1\n    env: [PORT=8080]\n    enabled: false\n",
+			want: &Manifest{Apps: []App{{Name: "web", App: "nginx", Cpu: &one, Env: []string{"PORT=8080"}, Enabled: &falsy}}},
+		},
+		{
+			name: "json",
+			data: `{"apps": [{"name": "web", "app": "nginx", "cpu": 1}]}`,
+			want: &Manifest{Apps: []App{{Name: "web", App: "nginx", Cpu: &one}}},
+		},
+		{
+			name: "empty",
+			data: "",
+			want: &Manifest{},
+		},
+		{
+			name:    "misspelled field",
+			data:    "apps:\n  - name: web\n    app: nginx\n    cpus: 2\n",
+			wantErr: "field cpus not found",
+		},
+		{
+			name:    "unknown top-level key",
+			data:    "app:\n  - name: web\n",
+			wantErr: "field app not found",
+		},
+		{
+			name:    "missing name and app",
+			data:    "apps:\n  - cpu: 1\n",
+			wantErr: "apps[0]: name is required\napps[0]: app is required",
+		},
+		{
+			name:    "duplicate name",
+			data:    "apps:\n  - {name: web, app: nginx}\n  - {name: web, app: postgres}\n",
+			wantErr: `apps[1]: duplicate name "web"`,
+		},
+		{
+			name:    "invalid yaml",
+			data:    "apps: [",
+			wantErr: "yaml:",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
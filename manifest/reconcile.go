@@ -0,0 +1,394 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	runx "github.com/run-x-app/runx-go"
+)
+
+// ActionType is the kind of change planned by a Reconciler.
+type ActionType string
+
+// Defines values for ActionType.
+const (
+	ActionCreate  ActionType = "create"
+	ActionUpdate  ActionType = "update"
+	ActionEnable  ActionType = "enable"
+	ActionDisable ActionType = "disable"
+	ActionDelete  ActionType = "delete"
+
+	// ActionReplace deletes an app whose catalog app changed and creates it
+	// again. The app is gone if the creation then fails.
+	ActionReplace ActionType = "replace"
+)
+
+// Change is the difference on a single field of an app.
+type Change struct {
+	Field string
+	From  string
+	To    string
+}
+
+// Action is a single API call planned by a Reconciler.
+type Action struct {
+	Type ActionType
+
+	// Name is the name of the app.
+	Name string
+
+	// AppId is the id of the existing app, empty for creations.
+	AppId string
+
+	// Desired is the manifest entry, nil for deletions.
+	Desired *App
+
+	// Replaced is the catalog app of an app being replaced.
+	Replaced string
+
+	// Changes lists the fields modified by an update.
+	Changes []Change
+}
+
+// Plan is the ordered list of actions bringing an account in line with a manifest.
+type Plan struct {
+	Actions []Action
+}
+
+// Empty reports whether the account already matches the manifest.
+func (p *Plan) Empty() bool {
+	return len(p.Actions) == 0
+}
+
+// Write prints a human-readable diff of the plan to w.
+func (p *Plan) Write(w io.Writer) error {
+	if p.Empty() {
+		_, err := fmt.Fprintln(w, "No changes.")
+		return err
+	}
+	for _, a := range p.Actions {
+		var err error
+		switch a.Type {
+		case ActionCreate:
+			_, err = fmt.Fprintf(w, "+ create %s (%s)\n", a.Name, describe(a.Desired))
+		case ActionUpdate:
+			_, err = fmt.Fprintf(w, "~ update %s\n", a.Name)
+			for _, c := range a.Changes {
+				if err == nil {
+					_, err = fmt.Fprintf(w, "    %s: %s -> %s\n", c.Field, c.From, c.To)
+				}
+			}
+		case ActionEnable, ActionDisable:
+			_, err = fmt.Fprintf(w, "~ %s %s\n", a.Type, a.Name)
+		case ActionDelete:
+			_, err = fmt.Fprintf(w, "- delete %s\n", a.Name)
+		case ActionReplace:
+			_, err = fmt.Fprintf(w, "-/+ replace %s (app %s -> %s, deleted before being created again: %s)\n", a.Name, a.Replaced, a.Desired.App, describe(a.Desired))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String returns the human-readable diff of the plan.
+func (p *Plan) String() string {
+	var sb strings.Builder
+	_ = p.Write(&sb)
+	return sb.String()
+}
+
+// Option configures a Reconciler.
+type Option func(*Reconciler)
+
+// WithPrune lets the Reconciler delete the apps missing from the manifest,
+// and replace the apps whose catalog app changed.
+func WithPrune() Option {
+	return func(r *Reconciler) {
+		r.prune = true
+	}
+}
+
+// Reconciler compares manifests with the apps listed by GetApps, and applies
+// the resulting plans. Apps are matched by name. Running the same manifest
+// twice yields an empty plan the second time.
+type Reconciler struct {
+	client *runx.ClientWithResponses
+	prune  bool
+}
+
+// NewReconciler creates a Reconciler working on the account of client.
+func NewReconciler(client *runx.ClientWithResponses, opts ...Option) *Reconciler {
+	r := &Reconciler{client: client}
+	for _, o := range opts {
+		o(r)
+	}
+	return r
+}
+
+// Plan computes the actions needed for the account to match m. Apps which
+// are not in the manifest are only deleted WithPrune.
+func (r *Reconciler) Plan(ctx context.Context, m *Manifest) (*Plan, error) {
+	rsp, err := r.client.GetAppsWithResponse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := runx.CheckResponse("GetApps", rsp.HTTPResponse, rsp.Body); err != nil {
+		return nil, err
+	}
+	current := map[string]runx.AppExtended{}
+	if rsp.JSON200 != nil && rsp.JSON200.Apps != nil {
+		for _, app := range *rsp.JSON200.Apps {
+			if app.Name == nil || app.Id == nil {
+				continue
+			}
+			if _, ok := current[*app.Name]; ok {
+				return nil, fmt.Errorf("manifest: several apps are named %q", *app.Name)
+			}
+			current[*app.Name] = app
+		}
+	}
+
+	plan := &Plan{}
+	var creates, deletes []Action
+	for i := range m.Apps {
+		desired := &m.Apps[i]
+		existing, ok := current[desired.Name]
+		if !ok {
+			creates = append(creates, Action{Type: ActionCreate, Name: desired.Name, Desired: desired})
+			continue
+		}
+		delete(current, desired.Name)
+
+		if existing.App != nil && *existing.App != desired.App {
+			if !r.prune {
+				return nil, fmt.Errorf("manifest: app %q runs %q instead of %q, replacing it requires pruning", desired.Name, *existing.App, desired.App)
+			}
+			deletes = append(deletes, Action{Type: ActionReplace, Name: desired.Name, AppId: *existing.Id, Desired: desired, Replaced: *existing.App})
+			continue
+		}
+
+		changes, err := r.diff(ctx, desired, existing)
+		if err != nil {
+			return nil, err
+		}
+		if len(changes) > 0 {
+			plan.Actions = append(plan.Actions, Action{Type: ActionUpdate, Name: desired.Name, AppId: *existing.Id, Desired: desired, Changes: changes})
+		}
+		if desired.Enabled != nil && (existing.Enabled == nil || *existing.Enabled != *desired.Enabled) {
+			t := ActionEnable
+			if !*desired.Enabled {
+				t = ActionDisable
+			}
+			plan.Actions = append(plan.Actions, Action{Type: t, Name: desired.Name, AppId: *existing.Id, Desired: desired})
+		}
+	}
+	if r.prune {
+		for name, app := range current {
+			deletes = append(deletes, Action{Type: ActionDelete, Name: name, AppId: *app.Id})
+		}
+	}
+	sort.Slice(deletes, func(i, j int) bool { return deletes[i].Name < deletes[j].Name })
+
+	// the name of a replaced app is only free once it is deleted
+	plan.Actions = append(append(deletes, creates...), plan.Actions...)
+	return plan, nil
+}
+
+// diff lists the managed fields of desired which differ from the existing app.
+func (r *Reconciler) diff(ctx context.Context, desired *App, existing runx.AppExtended) ([]Change, error) {
+	var changes []Change
+	addInt := func(field string, want, have *int) {
+		if want != nil && (have == nil || *have != *want) {
+			changes = append(changes, Change{Field: field, From: formatInt(have), To: strconv.Itoa(*want)})
+		}
+	}
+	addInt("cpu", desired.Cpu, existing.Cpu)
+	addInt("ram", desired.Ram, existing.Ram)
+	addInt("disk", desired.Disk, existing.Disk)
+	addInt("gpu", desired.Gpu, existing.Gpu)
+
+	if desired.Env != nil {
+		var have []string
+		if existing.Env != nil {
+			have = *existing.Env
+		}
+		if !sameEnv(desired.Env, have) {
+			changes = append(changes, Change{Field: "env", From: formatEnv(have), To: formatEnv(desired.Env)})
+		}
+	}
+
+	// the command is only returned by GetApp
+	if desired.Cmd != nil {
+		rsp, err := r.client.GetAppWithResponse(ctx, *existing.Id)
+		if err != nil {
+			return nil, err
+		}
+		if err := runx.CheckResponse("GetApp", rsp.HTTPResponse, rsp.Body); err != nil {
+			return nil, err
+		}
+		var have *string
+		if rsp.JSON200 != nil && rsp.JSON200.App != nil {
+			have = rsp.JSON200.App.Cmd
+		}
+		if have == nil || *have != *desired.Cmd {
+			from := "<none>"
+			if have != nil {
+				from = strconv.Quote(*have)
+			}
+			changes = append(changes, Change{Field: "cmd", From: from, To: strconv.Quote(*desired.Cmd)})
+		}
+	}
+	return changes, nil
+}
+
+// Apply performs the actions of plan in order, stopping at the first failure.
+// Creations, including those of the replaced apps, are grouped into a single
+// CreateApp call following the deletions.
+func (r *Reconciler) Apply(ctx context.Context, plan *Plan) error {
+	var creates []runx.AppRequest
+	var replaced []string
+	disabled := map[string]bool{}
+	for _, a := range plan.Actions {
+		if a.Type == ActionCreate || a.Type == ActionReplace {
+			creates = append(creates, a.Desired.AppRequest())
+			if a.Desired.Enabled != nil && !*a.Desired.Enabled {
+				disabled[a.Name] = true
+			}
+		}
+	}
+
+	created := len(creates) == 0
+	for _, a := range plan.Actions {
+		if !created && a.Type != ActionDelete && a.Type != ActionReplace {
+			created = true
+			if err := r.create(ctx, creates, disabled, replaced); err != nil {
+				return err
+			}
+		}
+		switch a.Type {
+		case ActionUpdate:
+			rsp, err := r.client.UpdateAppWithResponse(ctx, a.AppId, a.Desired.UpdateAppRequest())
+			if err == nil {
+				err = runx.CheckResponse("UpdateApp", rsp.HTTPResponse, rsp.Body)
+			}
+			if err != nil {
+				return fmt.Errorf("manifest: update %s: %w", a.Name, err)
+			}
+		case ActionEnable, ActionDisable:
+			if err := r.enable(ctx, a.Name, a.AppId, a.Type == ActionEnable); err != nil {
+				return err
+			}
+		case ActionDelete, ActionReplace:
+			rsp, err := r.client.DeleteAppWithResponse(ctx, a.AppId)
+			if err == nil {
+				err = runx.CheckResponse("DeleteApp", rsp.HTTPResponse, rsp.Body)
+			}
+			if err != nil {
+				return fmt.Errorf("manifest: delete %s: %w", a.Name, err)
+			}
+			if a.Type == ActionReplace {
+				replaced = append(replaced, a.Name)
+			}
+		}
+	}
+	if !created {
+		return r.create(ctx, creates, disabled, replaced)
+	}
+	return nil
+}
+
+// create creates apps, reporting the replaced apps left deleted on failure.
+func (r *Reconciler) create(ctx context.Context, apps []runx.AppRequest, disabled map[string]bool, replaced []string) error {
+	rsp, err := r.client.CreateAppWithResponse(ctx, runx.CreateAppRequest{Apps: apps})
+	if err == nil {
+		err = runx.CheckResponse("CreateApp", rsp.HTTPResponse, rsp.Body)
+	}
+	if err != nil {
+		if len(replaced) > 0 {
+			return fmt.Errorf("manifest: create %s, after deleting %s to replace them: %w", joinNames(apps), strings.Join(replaced, ", "), err)
+		}
+		return fmt.Errorf("manifest: create %s: %w", joinNames(apps), err)
+	}
+	if len(disabled) == 0 || rsp.JSON200 == nil || rsp.JSON200.Apps == nil {
+		return nil
+	}
+	for _, app := range *rsp.JSON200.Apps {
+		if app.Name != nil && app.Id != nil && disabled[*app.Name] {
+			if err := r.enable(ctx, *app.Name, *app.Id, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) enable(ctx context.Context, name, appId string, enabled bool) error {
+	value := runx.False
+	if enabled {
+		value = runx.True
+	}
+	rsp, err := r.client.EnableAppWithResponse(ctx, appId, value)
+	if err == nil {
+		err = runx.CheckResponse("EnableApp", rsp.HTTPResponse, rsp.Body)
+	}
+	if err != nil {
+		return fmt.Errorf("manifest: enable %s: %w", name, err)
+	}
+	return nil
+}
+
+func describe(a *App) string {
+	parts := []string{"app=" + a.App}
+	add := func(field string, v *int) {
+		if v != nil {
+			parts = append(parts, field+"="+strconv.Itoa(*v))
+		}
+	}
+	add("cpu", a.Cpu)
+	add("ram", a.Ram)
+	add("disk", a.Disk)
+	add("gpu", a.Gpu)
+	if a.Cmd != nil {
+		parts = append(parts, "cmd="+strconv.Quote(*a.Cmd))
+	}
+	if a.Env != nil {
+		parts = append(parts, "env="+formatEnv(a.Env))
+	}
+	if a.Enabled != nil {
+		parts = append(parts, "enabled="+strconv.FormatBool(*a.Enabled))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func sameEnv(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+func formatEnv(env []string) string {
+	return "[" + strings.Join(env, " ") + "]"
+}
+
+func formatInt(v *int) string {
+	if v == nil {
+		return "<none>"
+	}
+	return strconv.Itoa(*v)
+}
+
+func joinNames(apps []runx.AppRequest) string {
+	names := make([]string, len(apps))
+	for i, app := range apps {
+		names[i] = app.Name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,220 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	runx "github.com/run-x-app/runx-go"
+	"github.com/run-x-app/runx-go/runxtest"
+)
+
+func mustParse(t *testing.T, data string) *Manifest {
+	t.Helper()
+	m, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func actions(p *Plan) []string {
+	var got []string
+	for _, a := range p.Actions {
+		got = append(got, string(a.Type)+" "+a.Name)
+	}
+	return got
+}
+
+// apply plans m, checks the planned actions, applies them and checks that
+// planning again yields an empty plan.
+func apply(t *testing.T, r *Reconciler, m *Manifest, want ...string) *Plan {
+	t.Helper()
+	ctx := context.Background()
+	plan, err := r.Plan(ctx, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := actions(plan); !slices.Equal(got, want) {
+		t.Fatalf("Plan() = %q, want %q", got, want)
+	}
+	if err := r.Apply(ctx, plan); err != nil {
+		t.Fatal(err)
+	}
+	again, err := r.Plan(ctx, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.Empty() {
+		t.Errorf("Plan() after Apply() = %s", again)
+	}
+	return plan
+}
+
+func serverApps(srv *runxtest.Server) map[string]runx.AppExtended {
+	apps := map[string]runx.AppExtended{}
+	for _, app := range srv.Apps() {
+		apps[*app.Name] = app
+	}
+	return apps
+}
+
+func TestReconcile(t *testing.T) {
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	r := NewReconciler(srv.Client())
+
+	m := mustParse(t, `
+apps:
+  - name: web
+    app: nginx
+    cmd: nginx -g 'daemon off;'
+    cpu: 2
+    env: [PORT=8080, MODE=prod]
+  - name: worker
+    app: postgres
+    enabled: false
+`)
+	plan := apply(t, r, m, "create web", "create worker")
+	if got := plan.String(); !strings.Contains(got, `+ create web (app=nginx, cpu=2, cmd="nginx -g 'daemon off;'", env=[PORT=8080 MODE=prod])`) {
+		t.Errorf("Write() =\n%s", got)
+	}
+	apps := serverApps(srv)
+	if *apps["web"].Cpu != 2 || *apps["web"].Enabled != true || *apps["worker"].Enabled != false {
+		t.Errorf("apps = %+v", apps)
+	}
+
+	// the order of the variables does not matter
+	m.Apps[0].Env = []string{"MODE=prod", "PORT=8080"}
+	if plan, err := r.Plan(context.Background(), m); err != nil || !plan.Empty() {
+		t.Errorf("Plan() = %v, %v, want an empty plan", plan, err)
+	}
+
+	m = mustParse(t, `
+apps:
+  - name: web
+    app: nginx
+    cmd: nginx
+    cpu: 4
+    env: [PORT=8080]
+  - name: worker
+    app: postgres
+    enabled: true
+`)
+	plan = apply(t, r, m, "update web", "enable worker")
+	want := []Change{
+		{Field: "cpu", From: "2", To: "4"},
+		{Field: "env", From: "[PORT=8080 MODE=prod]", To: "[PORT=8080]"},
+		{Field: "cmd", From: `"nginx -g 'daemon off;'"`, To: `"nginx"`},
+	}
+	if !slices.Equal(plan.Actions[0].Changes, want) {
+		t.Errorf("Changes = %+v, want %+v", plan.Actions[0].Changes, want)
+	}
+	if got, want := plan.String(), "~ update web\n    cpu: 2 -> 4\n"; !strings.HasPrefix(got, want) {
+		t.Errorf("Write() =\n%s\nwant it to start with\n%s", got, want)
+	}
+	if !strings.HasSuffix(plan.String(), "~ enable worker\n") {
+		t.Errorf("Write() =\n%s", plan)
+	}
+	if (&Plan{}).String() != "No changes.\n" {
+		t.Errorf("empty plan = %q", (&Plan{}).String())
+	}
+}
+
+func TestReconcilePrune(t *testing.T) {
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	client := srv.Client()
+	m := mustParse(t, "apps:\n  - {name: web, app: nginx}\n")
+	apply(t, NewReconciler(client), m, "create web")
+	if _, err := client.CreateAppWithResponse(context.Background(), runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "manual", App: "nginx"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// unlisted apps are left alone without pruning
+	if plan, err := NewReconciler(client).Plan(context.Background(), m); err != nil || !plan.Empty() {
+		t.Errorf("Plan() = %v, %v, want an empty plan", plan, err)
+	}
+	plan := apply(t, NewReconciler(client, WithPrune()), m, "delete manual")
+	if plan.String() != "- delete manual\n" {
+		t.Errorf("Write() = %q", plan)
+	}
+	if _, ok := serverApps(srv)["manual"]; ok {
+		t.Error("manual app not deleted")
+	}
+}
+
+func TestReconcileReplace(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	client := srv.Client()
+	apply(t, NewReconciler(client), mustParse(t, "apps:\n  - {name: db, app: nginx}\n  - {name: web, app: nginx}\n"), "create db", "create web")
+
+	m := mustParse(t, "apps:\n  - {name: db, app: postgres, cpu: 2}\n  - {name: web, app: nginx}\n")
+	if _, err := NewReconciler(client).Plan(ctx, m); err == nil || !strings.Contains(err.Error(), "replacing it requires pruning") {
+		t.Errorf("Plan() error = %v, want the replacement refused", err)
+	}
+
+	r := NewReconciler(client, WithPrune())
+	plan, err := r.Plan(ctx, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the replacement is reported as destroying the app
+	if got, want := plan.String(), "-/+ replace db (app nginx -> postgres, deleted before being created again: app=postgres, cpu=2)\n"; got != want {
+		t.Errorf("Write() = %q, want %q", got, want)
+	}
+
+	// a failed creation leaves the app deleted, which the error tells
+	srv.InjectFault(runxtest.Fault{Operation: "CreateApp", StatusCode: http.StatusInternalServerError, Times: 1})
+	err = r.Apply(ctx, plan)
+	if err == nil || !strings.Contains(err.Error(), "after deleting db to replace them") {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	var apiErr *runx.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Apply() error = %v, want the CreateApp error", err)
+	}
+	if _, ok := serverApps(srv)["db"]; ok {
+		t.Error("db still exists after its deletion")
+	}
+
+	// the next plan creates the lost app
+	apply(t, r, m, "create db")
+	if app := serverApps(srv)["db"]; *app.App != "postgres" || *app.Cpu != 2 {
+		t.Errorf("db = %s with %d cpu, want postgres with 2", *app.App, *app.Cpu)
+	}
+
+	apply(t, r, mustParse(t, "apps:\n  - {name: db, app: nginx}\n  - {name: web, app: nginx}\n"), "replace db")
+	if app := serverApps(srv)["db"]; *app.App != "nginx" {
+		t.Errorf("db runs %s, want nginx", *app.App)
+	}
+}
+
+func TestApplyStopsAtFailure(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	client := srv.Client()
+	r := NewReconciler(client, WithPrune())
+	apply(t, r, mustParse(t, "apps:\n  - {name: old, app: nginx}\n"), "create old")
+
+	m := mustParse(t, "apps:\n  - {name: web, app: nginx}\n")
+	plan, err := r.Plan(ctx, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.InjectFault(runxtest.Fault{Operation: "DeleteApp", StatusCode: http.StatusInternalServerError, Times: 1})
+	if err := r.Apply(ctx, plan); err == nil || !strings.Contains(err.Error(), "manifest: delete old") {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	// nothing was created after the failed deletion
+	if apps := serverApps(srv); len(apps) != 1 {
+		t.Errorf("apps = %v, want only old", apps)
+	}
+	apply(t, r, m, "delete old", "create web")
+}
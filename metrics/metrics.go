@@ -0,0 +1,194 @@
+// Package metrics exposes Prometheus metrics about the calls made by the
+// Run X client.
+//
+// A Metrics is a prometheus.Collector registered on a registry of the
+// caller, and instruments any number of clients:
+//
+//	m := metrics.New()
+//	registry.MustRegister(m)
+//
+//	client, err := runx.NewClientWithResponses(server, apiKey,
+//		runx.WithRetry(),
+//		m.ClientOption(),
+//	)
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	runx "github.com/run-x-app/runx-go"
+)
+
+// Label values used when the operation or the status code is unknown.
+const (
+	UnknownOperation = "unknown"
+	ErrorCode        = "error"
+)
+
+// Option configures New.
+type Option func(*config)
+
+type config struct {
+	namespace   string
+	buckets     []float64
+	constLabels prometheus.Labels
+}
+
+// WithNamespace sets the prefix of the metric names. It defaults to "runx".
+func WithNamespace(ns string) Option {
+	return func(c *config) {
+		c.namespace = ns
+	}
+}
+
+// WithBuckets sets the buckets of the request duration histogram. They
+// default to prometheus.DefBuckets.
+func WithBuckets(buckets []float64) Option {
+	return func(c *config) {
+		c.buckets = buckets
+	}
+}
+
+// WithConstLabels adds labels with fixed values to every metric, for
+// instance to tell several clients apart.
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(c *config) {
+		c.constLabels = labels
+	}
+}
+
+// Metrics counts and times the requests sent through its middleware. Every
+// metric is labelled with the operation, the ClientInterface method such as
+// "RestartApp":
+//
+//   - requests_total counts the responses by operation and status code,
+//     "error" when no response was received;
+//   - request_duration_seconds times them with the same labels;
+//   - retries_total counts the attempts made after the first one;
+//   - in_flight gauges the requests waiting for their response.
+type Metrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	retries  *prometheus.CounterVec
+	inFlight *prometheus.GaugeVec
+}
+
+// New creates the metrics. They must be registered, see Register.
+func New(opts ...Option) *Metrics {
+	cfg := config{namespace: "runx", buckets: prometheus.DefBuckets}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	return &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   cfg.namespace,
+			Name:        "requests_total",
+			Help:        "Requests sent to the Run X API, by operation and status code.",
+			ConstLabels: cfg.constLabels,
+		}, []string{"operation", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   cfg.namespace,
+			Name:        "request_duration_seconds",
+			Help:        "Duration of the requests sent to the Run X API, by operation and status code.",
+			ConstLabels: cfg.constLabels,
+			Buckets:     cfg.buckets,
+		}, []string{"operation", "code"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   cfg.namespace,
+			Name:        "retries_total",
+			Help:        "Requests to the Run X API retried by the retry policy, by operation.",
+			ConstLabels: cfg.constLabels,
+		}, []string{"operation"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   cfg.namespace,
+			Name:        "in_flight",
+			Help:        "Requests to the Run X API waiting for their response, by operation.",
+			ConstLabels: cfg.constLabels,
+		}, []string{"operation"}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.duration.Describe(ch)
+	m.retries.Describe(ch)
+	m.inFlight.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.duration.Collect(ch)
+	m.retries.Collect(ch)
+	m.inFlight.Collect(ch)
+}
+
+// Register registers the metrics on reg.
+func (m *Metrics) Register(reg prometheus.Registerer) error {
+	return reg.Register(m)
+}
+
+// Middleware returns the Doer middleware recording the requests. Registered
+// after WithRetry, it records every attempt and counts the retries;
+// registered before, it records each call once, retries included.
+func (m *Metrics) Middleware() runx.DoerMiddleware {
+	return func(next runx.HttpRequestDoer) runx.HttpRequestDoer {
+		return runx.DoerFunc(func(req *http.Request) (*http.Response, error) {
+			op := runx.OperationName(req)
+			if op == "" {
+				op = UnknownOperation
+			}
+			if runx.RetryAttempt(req.Context()) > 1 {
+				m.retries.WithLabelValues(op).Inc()
+			}
+			inFlight := m.inFlight.WithLabelValues(op)
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			start := time.Now()
+			rsp, err := next.Do(req)
+			code := statusCode(rsp, err)
+			m.requests.WithLabelValues(op, code).Inc()
+			m.duration.WithLabelValues(op, code).Observe(time.Since(start).Seconds())
+			return rsp, err
+		})
+	}
+}
+
+// ClientOption returns the option registering Middleware on a client.
+func (m *Metrics) ClientOption() runx.ClientOption {
+	return runx.WithDoerMiddleware(m.Middleware())
+}
+
+// WithMetrics creates metrics configured by opts, registers them on reg and
+// instruments the client with them. It fails when metrics of the same names
+// are already registered on reg, in which case a Metrics shared by the
+// clients should be used instead.
+func WithMetrics(reg prometheus.Registerer, opts ...Option) runx.ClientOption {
+	m := New(opts...)
+	return func(c *runx.Client) error {
+		if err := m.Register(reg); err != nil {
+			return err
+		}
+		return m.ClientOption()(c)
+	}
+}
+
+// statusCode returns the code label of a request: the status code of its
+// response, of the *APIError returned by the middlewares of WithAPIErrors,
+// or ErrorCode.
+func statusCode(rsp *http.Response, err error) string {
+	var apiErr *runx.APIError
+	switch {
+	case err == nil && rsp != nil:
+		return strconv.Itoa(rsp.StatusCode)
+	case errors.As(err, &apiErr) && apiErr.StatusCode != 0:
+		return strconv.Itoa(apiErr.StatusCode)
+	}
+	return ErrorCode
+}
@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	runx "github.com/run-x-app/runx-go"
+	"github.com/run-x-app/runx-go/runxtest"
+)
+
+// fastRetry retries without waiting.
+var fastRetry = runx.WithRetryPolicy(runx.RetryPolicy{
+	MaxAttempts:     4,
+	InitialBackoff:  time.Millisecond,
+	MaxBackoff:      time.Millisecond,
+	Multiplier:      1,
+	RetryableStatus: []int{http.StatusServiceUnavailable},
+})
+
+// series returns the label sets of the series of the metric family name,
+// each as "label=value" pairs sorted by label, with the sample count of the
+// histograms.
+func series(t *testing.T, reg *prometheus.Registry, name string) map[string]uint64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]uint64{}
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			var labels string
+			for _, l := range m.GetLabel() {
+				labels += l.GetName() + "=" + l.GetValue() + " "
+			}
+			got[labels] = m.GetHistogram().GetSampleCount()
+		}
+	}
+	return got
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	reg := prometheus.NewRegistry()
+	m := New()
+	if err := m.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+	client := srv.Client(fastRetry, m.ClientOption())
+
+	srv.InjectFault(runxtest.Fault{Operation: "GetApps", StatusCode: http.StatusServiceUnavailable, Times: 2})
+	if rsp, err := client.GetAppsWithResponse(ctx); err != nil || rsp.StatusCode() != http.StatusOK {
+		t.Fatalf("GetApps() = %v, %v", rsp, err)
+	}
+	if _, err := client.GetAppWithResponse(ctx, "missing"); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := []struct {
+		operation, code string
+		want            float64
+	}{
+		{"GetApps", "503", 2},
+		{"GetApps", "200", 1},
+		{"GetApp", "404", 1},
+		{"GetApp", "200", 0},
+	}
+	for _, r := range requests {
+		if got := testutil.ToFloat64(m.requests.WithLabelValues(r.operation, r.code)); got != r.want {
+			t.Errorf("requests_total{operation=%q,code=%q} = %v, want %v", r.operation, r.code, got, r.want)
+		}
+	}
+
+	// the duration histogram has the labels of the counter
+	want := map[string]uint64{
+		"code=200 operation=GetApps ": 1,
+		"code=503 operation=GetApps ": 2,
+		"code=404 operation=GetApp ":  1,
+	}
+	if got := series(t, reg, "runx_request_duration_seconds"); !maps.Equal(got, want) {
+		t.Errorf("request_duration_seconds = %v, want %v", got, want)
+	}
+
+	// the attempts after the first one are retries, the requests done are
+	// no longer in flight
+	if got := testutil.ToFloat64(m.retries.WithLabelValues("GetApps")); got != 2 {
+		t.Errorf("retries_total{operation=GetApps} = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.retries.WithLabelValues("GetApp")); got != 0 {
+		t.Errorf("retries_total{operation=GetApp} = %v, want 0", got)
+	}
+	if n := testutil.CollectAndCount(m, "runx_in_flight"); n != 2 {
+		t.Errorf("%d in_flight series, want one per operation", n)
+	}
+	for _, op := range []string{"GetApps", "GetApp"} {
+		if got := testutil.ToFloat64(m.inFlight.WithLabelValues(op)); got != 0 {
+			t.Errorf("in_flight{operation=%s} = %v, want 0", op, got)
+		}
+	}
+}
+
+func TestMetricsTransportError(t *testing.T) {
+	srv := runxtest.NewServer()
+	srv.Close()
+	m := New(WithNamespace("test"), WithConstLabels(prometheus.Labels{"client": "closed"}))
+	reg := prometheus.NewRegistry()
+	if err := m.Register(reg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.Client(m.ClientOption()).GetAppsWithResponse(context.Background()); err == nil {
+		t.Fatal("GetApps() on a closed server succeeded")
+	}
+	want := map[string]uint64{"client=closed code=error operation=GetApps ": 1}
+	if got := series(t, reg, "test_request_duration_seconds"); !maps.Equal(got, want) {
+		t.Errorf("request_duration_seconds = %v, want %v", got, want)
+	}
+}
+
+func TestWithMetrics(t *testing.T) {
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	reg := prometheus.NewRegistry()
+	client, err := runx.NewClientWithResponses(srv.URL, runxtest.DefaultAPIKey, WithMetrics(reg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetAppsWithResponse(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := series(t, reg, "runx_requests_total"); len(got) != 1 {
+		t.Errorf("requests_total = %v, want the GetApps call", got)
+	}
+	// the metrics of a second client clash with those of the first
+	if _, err := runx.NewClientWithResponses(srv.URL, runxtest.DefaultAPIKey, WithMetrics(reg)); err == nil {
+		t.Error("WithMetrics() registered the same metrics twice")
+	}
+}
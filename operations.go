@@ -0,0 +1,100 @@
+package runx
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// operation describes a route of the Run X API, named after the matching
+// ClientInterface method.
+type operation struct {
+	name     string
+	method   string
+	segments []string
+}
+
+// operations lists every route served by the Run X API. Path parameters are
+// written as {name}.
+var operations = []operation{
+	{name: "GetApps", method: http.MethodGet, segments: []string{"app"}},
+	{name: "CreateApp", method: http.MethodPost, segments: []string{"app"}},
+	{name: "DeleteApp", method: http.MethodDelete, segments: []string{"app", "{appId}"}},
+	{name: "GetApp", method: http.MethodGet, segments: []string{"app", "{appId}"}},
+	{name: "UpdateApp", method: http.MethodPut, segments: []string{"app", "{appId}"}},
+	{name: "EnableApp", method: http.MethodPatch, segments: []string{"app", "{appId}", "enable", "{enabled}"}},
+	{name: "RestartApp", method: http.MethodPatch, segments: []string{"app", "{appId}", "restart"}},
+	{name: "Auth", method: http.MethodPost, segments: []string{"auth"}},
+	{name: "GetCatalogApps", method: http.MethodGet, segments: []string{"catalog"}},
+	{name: "Me", method: http.MethodGet, segments: []string{"me"}},
+	{name: "MeBilling", method: http.MethodGet, segments: []string{"me", "billing"}},
+	{name: "GenerateApiKey", method: http.MethodPost, segments: []string{"me", "key", "generate"}},
+	{name: "RevealNumber", method: http.MethodGet, segments: []string{"me", "number"}},
+	{name: "MeSession", method: http.MethodGet, segments: []string{"me", "session"}},
+	{name: "DeleteSession", method: http.MethodDelete, segments: []string{"me", "session", "{sessionId}"}},
+	{name: "Register", method: http.MethodPost, segments: []string{"register"}},
+}
+
+// matchOperation returns the operation serving req together with its path
+// parameters. The server URL may carry a base path, so routes are matched
+// against the trailing path segments and the longest match wins.
+func matchOperation(req *http.Request) (*operation, map[string]string) {
+	if req == nil || req.URL == nil {
+		return nil, nil
+	}
+	path := strings.Split(strings.Trim(req.URL.EscapedPath(), "/"), "/")
+
+	var best *operation
+	var bestParams map[string]string
+	for i := range operations {
+		op := &operations[i]
+		if op.method != req.Method || len(op.segments) > len(path) {
+			continue
+		}
+		if best != nil && len(best.segments) >= len(op.segments) {
+			continue
+		}
+		tail := path[len(path)-len(op.segments):]
+		params := map[string]string{}
+		matched := true
+		for j, seg := range op.segments {
+			if strings.HasPrefix(seg, "{") {
+				params[strings.Trim(seg, "{}")] = unescapePathSegment(tail[j])
+				continue
+			}
+			if seg != tail[j] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			best, bestParams = op, params
+		}
+	}
+	return best, bestParams
+}
+
+// OperationName returns the name of the ClientInterface method sending req,
+// such as "RestartApp", or an empty string when the request does not target
+// a known route. It lets Doer middlewares label the requests they observe.
+func OperationName(req *http.Request) string {
+	op, _ := matchOperation(req)
+	if op == nil {
+		return ""
+	}
+	return op.name
+}
+
+// OperationParams returns the path parameters of req, such as "appId",
+// keyed by name, or nil when the request does not target a known route.
+func OperationParams(req *http.Request) map[string]string {
+	_, params := matchOperation(req)
+	return params
+}
+
+func unescapePathSegment(s string) string {
+	if u, err := url.PathUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
@@ -0,0 +1,318 @@
+package runx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// rollbackTimeout bounds the deletion of the apps of a failed deployment,
+// which goes on after the context of DeployPack is done.
+const rollbackTimeout = 30 * time.Second
+
+// DeployOption configures ClientWithResponses.DeployPack.
+type DeployOption func(*deployConfig)
+
+type deployConfig struct {
+	overrides map[string]AppRequest
+	prefix    string
+	wait      []WaitOption
+	rollback  bool
+}
+
+// WithAppOverride overrides the request of the pack app whose catalog id is
+// app. The name, the command and the resources set in override replace those
+// of the pack, and its environment variables are added to the pack ones,
+// replacing the variables of the same name.
+func WithAppOverride(app string, override AppRequest) DeployOption {
+	return func(c *deployConfig) {
+		c.overrides[app] = override
+	}
+}
+
+// WithNamePrefix names the apps of the pack prefix-<catalog id> rather than
+// after their catalog id, so that a pack can be deployed more than once.
+func WithNamePrefix(prefix string) DeployOption {
+	return func(c *deployConfig) {
+		c.prefix = prefix
+	}
+}
+
+// WithDeployWait configures the wait for the apps to be running.
+func WithDeployWait(opts ...WaitOption) DeployOption {
+	return func(c *deployConfig) {
+		c.wait = opts
+	}
+}
+
+// WithoutRollback keeps the created apps when the deployment fails.
+func WithoutRollback() DeployOption {
+	return func(c *deployConfig) {
+		c.rollback = false
+	}
+}
+
+// PackDeployment reports the outcome of DeployPack.
+type PackDeployment struct {
+	Pack string `json:"pack"`
+
+	// Requests are the expanded requests sent to CreateApp.
+	Requests []AppRequest `json:"requests"`
+
+	// Apps are the created apps, in their last known state.
+	Apps []AppExtended `json:"apps"`
+
+	// RolledBack lists the ids of the apps deleted after a failure.
+	RolledBack []string `json:"rolled_back,omitempty"`
+}
+
+// ExpandPack returns the requests creating the apps of pack, with the
+// overrides of opts applied. An unknown pack is reported as ValidationErrors.
+func (c *Catalog) ExpandPack(pack string, opts ...DeployOption) ([]AppRequest, error) {
+	cfg := newDeployConfig(opts)
+	if c.Pack(pack) == nil {
+		var errs ValidationErrors
+		errs.add(-1, "pack", "unknown pack %q", pack)
+		return nil, errs
+	}
+	requests, err := c.Requests(CreateAppRequest{Pack: &pack})
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]bool{}
+	for i := range requests {
+		r := &requests[i]
+		known[r.App] = true
+		if cfg.prefix != "" {
+			r.Name = cfg.prefix + "-" + r.App
+		}
+		if o, ok := cfg.overrides[r.App]; ok {
+			applyOverride(r, o)
+		}
+	}
+	var errs ValidationErrors
+	for app := range cfg.overrides {
+		if !known[app] {
+			errs.add(-1, "apps", "override of %q, which is not part of pack %q", app, pack)
+		}
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func applyOverride(r *AppRequest, o AppRequest) {
+	if o.Name != "" {
+		r.Name = o.Name
+	}
+	if o.Cmd != nil {
+		r.Cmd = o.Cmd
+	}
+	if o.Cpu != nil {
+		r.Cpu = o.Cpu
+	}
+	if o.Ram != nil {
+		r.Ram = o.Ram
+	}
+	if o.Disk != nil {
+		r.Disk = o.Disk
+	}
+	if o.Gpu != nil {
+		r.Gpu = o.Gpu
+	}
+	if o.Env != nil {
+		var env []string
+		if r.Env != nil {
+			env = append(env, *r.Env...)
+		}
+		for _, kv := range *o.Env {
+			key, _, _ := strings.Cut(kv, "=")
+			replaced := false
+			for i, existing := range env {
+				if k, _, _ := strings.Cut(existing, "="); k == key {
+					env[i], replaced = kv, true
+				}
+			}
+			if !replaced {
+				env = append(env, kv)
+			}
+		}
+		r.Env = &env
+	}
+}
+
+// DeployPack deploys pack as a unit. It expands the pack into the requests of
+// its apps, applies the overrides, validates the requests with a Validator,
+// creates the apps with a single CreateApp call and polls GetApps until all of
+// them are running. When CreateApp returns fewer apps than requested, an app
+// fails to start or the wait ends, the created apps are deleted, unless
+// WithoutRollback is given, and the error is returned together with the
+// deployment listing what was created and rolled back.
+//
+// The apps are listed once before CreateApp. When CreateApp fails without an
+// answer from the API, or with a 5xx status, the apps may still have been
+// created: they are listed again, and those named after the requests which
+// did not exist before are reported in Apps and rolled back.
+func (c *ClientWithResponses) DeployPack(ctx context.Context, pack string, opts ...DeployOption) (*PackDeployment, error) {
+	cfg := newDeployConfig(opts)
+	v, err := c.NewValidator(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("runx: deploy pack %s: %w", pack, err)
+	}
+	requests, err := v.Catalog.ExpandPack(pack, opts...)
+	if err != nil {
+		return nil, err
+	}
+	body := CreateAppRequest{Apps: requests, Pack: &pack}
+	if err := v.ValidateCreate(body); err != nil {
+		return nil, err
+	}
+	existing, err := c.listApps(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("runx: deploy pack %s: %w", pack, err)
+	}
+
+	d := &PackDeployment{Pack: pack, Requests: requests, Apps: []AppExtended{}}
+	rsp, err := c.CreateAppWithResponse(ctx, body)
+	if err == nil {
+		err = CheckResponse("CreateApp", rsp.HTTPResponse, rsp.Body)
+	}
+	if err != nil {
+		err = fmt.Errorf("runx: deploy pack %s: %w", pack, err)
+		if !unknownOutcome(err) {
+			return d, err
+		}
+		if lerr := c.findCreated(ctx, d, existing); lerr != nil {
+			return d, errors.Join(err, fmt.Errorf("runx: deploy pack %s: looking for the created apps: %w", pack, lerr))
+		}
+		if cfg.rollback && len(d.Apps) > 0 {
+			err = errors.Join(err, c.rollback(ctx, d))
+		}
+		return d, err
+	}
+	var created []App
+	if rsp.JSON200 != nil && rsp.JSON200.Apps != nil {
+		created = *rsp.JSON200.Apps
+	}
+	for _, app := range created {
+		d.Apps = append(d.Apps, AppExtended{Id: app.Id, ShortId: app.ShortId, Name: app.Name, App: app.App})
+	}
+	if len(created) != len(requests) {
+		err := fmt.Errorf("runx: deploy pack %s: %d app(s) created out of %d", pack, len(created), len(requests))
+		if cfg.rollback {
+			err = errors.Join(err, c.rollback(ctx, d))
+		}
+		return d, err
+	}
+
+	if err := c.waitRunning(ctx, d, newWaitConfig(cfg.wait)); err != nil {
+		err = fmt.Errorf("runx: deploy pack %s: %w", pack, err)
+		if cfg.rollback {
+			err = errors.Join(err, c.rollback(ctx, d))
+		}
+		return d, err
+	}
+	return d, nil
+}
+
+// waitRunning polls GetApps until every app of d is running, keeping d.Apps in
+// their last known state. It stops at the first poll where an app is missing
+// or failed, returning an error for each of them.
+func (c *ClientWithResponses) waitRunning(ctx context.Context, d *PackDeployment, cfg *waitConfig) error {
+	_, err := c.poll(ctx, cfg, func(apps []AppExtended) (*AppExtended, bool, error) {
+		var errs []error
+		running := 0
+		for i := range d.Apps {
+			app := findApp(apps, deref(d.Apps[i].Id))
+			if app == nil {
+				errs = append(errs, fmt.Errorf("runx: app %s: %w", appLabel(&d.Apps[i]), ErrNotFound))
+				continue
+			}
+			d.Apps[i] = *app
+			status := deref(app.Status)
+			switch {
+			case matchStatus(status, []string{AppStatusRunning}):
+				running++
+			case matchStatus(status, cfg.failures):
+				errs = append(errs, &AppFailedError{App: &d.Apps[i], Status: status})
+			}
+		}
+		return nil, running == len(d.Apps), errors.Join(errs...)
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrAppFailed) {
+		var pending []string
+		for i := range d.Apps {
+			if status := deref(d.Apps[i].Status); !matchStatus(status, []string{AppStatusRunning}) {
+				pending = append(pending, fmt.Sprintf("%s (%q)", appLabel(&d.Apps[i]), status))
+			}
+		}
+		err = fmt.Errorf("runx: waiting for apps %s to be running: %w", strings.Join(pending, ", "), err)
+	}
+	return err
+}
+
+// unknownOutcome reports whether the failed CreateApp call may still have
+// created apps: the API did not answer, or answered with a 5xx status.
+func unknownOutcome(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500
+	}
+	return true
+}
+
+// findCreated lists the apps, even when ctx is done, and adds to d.Apps those
+// named after the requests of d which are not in existing.
+func (c *ClientWithResponses) findCreated(ctx context.Context, d *PackDeployment, existing []AppExtended) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+	apps, err := c.listApps(ctx)
+	if err != nil {
+		return err
+	}
+	names := map[string]bool{}
+	for _, r := range d.Requests {
+		names[r.Name] = true
+	}
+	for _, app := range apps {
+		if names[deref(app.Name)] && findApp(existing, deref(app.Id)) == nil {
+			d.Apps = append(d.Apps, app)
+		}
+	}
+	return nil
+}
+
+// rollback deletes the apps of d, even when ctx is done, giving up after
+// rollbackTimeout.
+func (c *ClientWithResponses) rollback(ctx context.Context, d *PackDeployment) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+	var errs []error
+	for _, app := range d.Apps {
+		if app.Id == nil {
+			continue
+		}
+		id := *app.Id
+		rsp, err := c.DeleteAppWithResponse(ctx, id)
+		if err == nil {
+			err = CheckResponse("DeleteApp", rsp.HTTPResponse, rsp.Body)
+		}
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			errs = append(errs, fmt.Errorf("runx: roll back %s: %w", appLabel(&app), err))
+			continue
+		}
+		d.RolledBack = append(d.RolledBack, id)
+	}
+	return errors.Join(errs...)
+}
+
+func newDeployConfig(opts []DeployOption) *deployConfig {
+	cfg := &deployConfig{overrides: map[string]AppRequest{}, rollback: true}
+	for _, o := range opts {
+		o(cfg)
+	}
+	return cfg
+}
@@ -0,0 +1,518 @@
+package runx_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	runx "github.com/run-x-app/runx-go"
+	"github.com/run-x-app/runx-go/runxtest"
+)
+
+// fastWait polls the fake server often enough for the tests.
+var fastWait = runx.WithDeployWait(runx.WithPollInterval(5*time.Millisecond), runx.WithMaxPollInterval(5*time.Millisecond), runx.WithWaitTimeout(time.Second))
+
+// onCreateApp calls edit with the decoded response of CreateApp, which it
+// may modify before the client reads it.
+func onCreateApp(edit func(body map[string]any)) runx.ClientOption {
+	return runx.WithDoerMiddleware(func(next runx.HttpRequestDoer) runx.HttpRequestDoer {
+		return runx.DoerFunc(func(req *http.Request) (*http.Response, error) {
+			rsp, err := next.Do(req)
+			if err != nil || runx.OperationName(req) != "CreateApp" || rsp.StatusCode != http.StatusOK {
+				return rsp, err
+			}
+			var body map[string]any
+			data, err := io.ReadAll(rsp.Body)
+			rsp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(data, &body); err != nil {
+				return nil, err
+			}
+			edit(body)
+			data, _ = json.Marshal(body)
+			rsp.Body = io.NopCloser(bytes.NewReader(data))
+			rsp.ContentLength = int64(len(data))
+			return rsp, nil
+		})
+	})
+}
+
+func appNames(apps []runx.AppExtended) []string {
+	var names []string
+	for _, app := range apps {
+		names = append(names, *app.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestDeployPack(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer(runxtest.WithStartDelay(20 * time.Millisecond))
+	defer srv.Close()
+	client := srv.Client()
+
+	d, err := client.DeployPack(ctx, "web", fastWait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := appNames(d.Apps); !slices.Equal(got, []string{"nginx", "postgres"}) {
+		t.Errorf("apps = %v, want the catalog ids", got)
+	}
+	for _, app := range d.Apps {
+		if *app.Status != runx.AppStatusRunning {
+			t.Errorf("%s: status %q, want running", *app.Name, *app.Status)
+		}
+	}
+	if len(d.RolledBack) != 0 {
+		t.Errorf("RolledBack = %v after a successful deployment", d.RolledBack)
+	}
+
+	// the names of the first deployment are taken
+	if _, err := client.DeployPack(ctx, "web", fastWait); !errors.Is(err, runx.ErrConflict) {
+		t.Errorf("second DeployPack() error = %v, want %v", err, runx.ErrConflict)
+	}
+	d, err = client.DeployPack(ctx, "web", runx.WithNamePrefix("staging"), fastWait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := appNames(d.Apps); !slices.Equal(got, []string{"staging-nginx", "staging-postgres"}) {
+		t.Errorf("apps = %v, want the prefixed names", got)
+	}
+	if got := appNames(srv.Apps()); len(got) != 4 {
+		t.Errorf("server apps = %v, want both deployments", got)
+	}
+}
+
+func TestExpandPack(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	catalog, err := srv.Client().GetCatalog(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ram, cpu := 1024, 2
+	base := []string{"POSTGRES_USER=app", "POSTGRES_DB=app"}
+
+	tests := []struct {
+		name    string
+		pack    string
+		opts    []runx.DeployOption
+		want    map[string]runx.AppRequest
+		wantErr bool
+	}{
+		{
+			name: "pack",
+			pack: "web",
+			want: map[string]runx.AppRequest{
+				"nginx":    {Name: "nginx", App: "nginx"},
+				"postgres": {Name: "postgres", App: "postgres"},
+			},
+		},
+		{
+			name: "prefix",
+			pack: "web",
+			opts: []runx.DeployOption{runx.WithNamePrefix("dev")},
+			want: map[string]runx.AppRequest{
+				"nginx":    {Name: "dev-nginx", App: "nginx"},
+				"postgres": {Name: "dev-postgres", App: "postgres"},
+			},
+		},
+		{
+			name: "overrides",
+			pack: "web",
+			opts: []runx.DeployOption{
+				runx.WithNamePrefix("dev"),
+				runx.WithAppOverride("postgres", runx.AppRequest{Name: "db", Ram: &ram, Env: &base}),
+				runx.WithAppOverride("nginx", runx.AppRequest{Cpu: &cpu}),
+			},
+			want: map[string]runx.AppRequest{
+				"nginx":    {Name: "dev-nginx", App: "nginx", Cpu: &cpu},
+				"postgres": {Name: "db", App: "postgres", Ram: &ram, Env: &base},
+			},
+		},
+		{
+			name:    "override outside of the pack",
+			pack:    "web",
+			opts:    []runx.DeployOption{runx.WithAppOverride("jupyter", runx.AppRequest{Cpu: &cpu})},
+			wantErr: true,
+		},
+		{
+			name:    "unknown pack",
+			pack:    "data",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests, err := catalog.ExpandPack(tt.pack, tt.opts...)
+			if tt.wantErr {
+				var verrs runx.ValidationErrors
+				if !errors.As(err, &verrs) {
+					t.Fatalf("ExpandPack() error = %v, want ValidationErrors", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(requests) != len(tt.want) {
+				t.Fatalf("ExpandPack() = %+v, want %d requests", requests, len(tt.want))
+			}
+			for _, got := range requests {
+				want := tt.want[got.App]
+				if got.Name != want.Name || !equalPtr(got.Cpu, want.Cpu) || !equalPtr(got.Ram, want.Ram) || !equalEnv(got.Env, want.Env) {
+					t.Errorf("%s: request = %+v, want %+v", got.App, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPackOverrideEnv(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	catalog, err := srv.Client().GetCatalog(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the variables of an override replace the earlier ones of the same name
+	first, err := catalog.ExpandPack("web", runx.WithAppOverride("postgres", runx.AppRequest{Env: &[]string{"A=1", "B=2"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var postgres runx.AppRequest
+	for _, r := range first {
+		if r.App == "postgres" {
+			postgres = r
+		}
+	}
+	if !equalEnv(postgres.Env, &[]string{"A=1", "B=2"}) {
+		t.Fatalf("env = %v", *postgres.Env)
+	}
+	d, err := srv.Client().DeployPack(ctx, "web",
+		runx.WithAppOverride("postgres", runx.AppRequest{Env: &[]string{"A=1", "B=2", "B=3", "C=4"}}),
+		fastWait,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range d.Requests {
+		if r.App == "postgres" && !equalEnv(r.Env, &[]string{"A=1", "B=3", "C=4"}) {
+			t.Errorf("env = %v, want B replaced", *r.Env)
+		}
+	}
+	for _, app := range srv.Apps() {
+		if *app.App == "postgres" && !equalEnv(app.Env, &[]string{"A=1", "B=3", "C=4"}) {
+			t.Errorf("server env = %v", app.Env)
+		}
+	}
+}
+
+func TestDeployPackRollback(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// setup returns the options of the client and of DeployPack
+		setup        func(srv *runxtest.Server) ([]runx.ClientOption, []runx.DeployOption)
+		wantErr      error
+		wantCreated  int
+		wantRolled   int
+		wantRemained int
+	}{
+		{
+			name: "failed app",
+			setup: func(srv *runxtest.Server) ([]runx.ClientOption, []runx.DeployOption) {
+				return []runx.ClientOption{onCreateApp(func(body map[string]any) {
+					apps := body["apps"].([]any)
+					srv.SetAppStatus(apps[1].(map[string]any)["id"].(string), runx.AppStatusFailed)
+				})}, []runx.DeployOption{runx.WithDeployWait(runx.WithPollInterval(5*time.Millisecond), runx.WithWaitTimeout(30*time.Millisecond))}
+			},
+			wantErr:     runx.ErrAppFailed,
+			wantCreated: 2,
+			wantRolled:  2,
+		},
+		{
+			name: "wait timeout",
+			setup: func(srv *runxtest.Server) ([]runx.ClientOption, []runx.DeployOption) {
+				return nil, []runx.DeployOption{runx.WithDeployWait(runx.WithPollInterval(5*time.Millisecond), runx.WithWaitTimeout(30*time.Millisecond))}
+			},
+			wantErr:     context.DeadlineExceeded,
+			wantCreated: 2,
+			wantRolled:  2,
+		},
+		{
+			name: "without rollback",
+			setup: func(srv *runxtest.Server) ([]runx.ClientOption, []runx.DeployOption) {
+				return nil, []runx.DeployOption{runx.WithoutRollback(), runx.WithDeployWait(runx.WithPollInterval(5*time.Millisecond), runx.WithWaitTimeout(30*time.Millisecond))}
+			},
+			wantErr:      context.DeadlineExceeded,
+			wantCreated:  2,
+			wantRemained: 2,
+		},
+		{
+			name: "fewer apps returned",
+			setup: func(srv *runxtest.Server) ([]runx.ClientOption, []runx.DeployOption) {
+				return []runx.ClientOption{onCreateApp(func(body map[string]any) {
+					body["apps"] = body["apps"].([]any)[:1]
+				})}, []runx.DeployOption{fastWait}
+			},
+			wantCreated: 1,
+			wantRolled:  1,
+			// the app missing from the response cannot be deleted
+			wantRemained: 1,
+		},
+		{
+			name: "no apps returned",
+			setup: func(srv *runxtest.Server) ([]runx.ClientOption, []runx.DeployOption) {
+				return []runx.ClientOption{onCreateApp(func(body map[string]any) {
+					delete(body, "apps")
+				})}, []runx.DeployOption{fastWait}
+			},
+			wantRemained: 2,
+		},
+		{
+			name: "failed deletion",
+			setup: func(srv *runxtest.Server) ([]runx.ClientOption, []runx.DeployOption) {
+				srv.InjectFault(runxtest.Fault{Operation: "DeleteApp", StatusCode: http.StatusInternalServerError, Times: 1})
+				return nil, []runx.DeployOption{runx.WithDeployWait(runx.WithPollInterval(5*time.Millisecond), runx.WithWaitTimeout(30*time.Millisecond))}
+			},
+			wantErr:      runx.ErrServer,
+			wantCreated:  2,
+			wantRolled:   1,
+			wantRemained: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := runxtest.NewServer(runxtest.WithStartDelay(time.Hour))
+			defer srv.Close()
+			clientOpts, deployOpts := tt.setup(srv)
+			d, err := srv.Client(clientOpts...).DeployPack(ctx, "web", deployOpts...)
+			if err == nil {
+				t.Fatal("DeployPack() succeeded")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("DeployPack() error = %v, want %v", err, tt.wantErr)
+			}
+			if d == nil {
+				t.Fatalf("DeployPack() returned no deployment, error %v", err)
+			}
+			if len(d.Apps) != tt.wantCreated || len(d.RolledBack) != tt.wantRolled {
+				t.Errorf("%d apps created, %d rolled back, want %d and %d", len(d.Apps), len(d.RolledBack), tt.wantCreated, tt.wantRolled)
+			}
+			if n := len(srv.Apps()); n != tt.wantRemained {
+				t.Errorf("%d apps left on the server, want %d", n, tt.wantRemained)
+			}
+		})
+	}
+
+	t.Run("canceled", func(t *testing.T) {
+		srv := runxtest.NewServer(runxtest.WithStartDelay(time.Hour))
+		defer srv.Close()
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		d, err := srv.Client().DeployPack(ctx, "web", runx.WithDeployWait(runx.WithPollInterval(5*time.Millisecond)))
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("DeployPack() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+		// the rollback goes on once the context is done
+		if len(d.RolledBack) != 2 || len(srv.Apps()) != 0 {
+			t.Errorf("%d apps rolled back, %d left, want 2 and 0", len(d.RolledBack), len(srv.Apps()))
+		}
+	})
+}
+
+// onOperation calls f after the server has answered a request of the
+// operation op, and returns its response or error to the client instead.
+func onOperation(op string, f func(rsp *http.Response) (*http.Response, error)) runx.ClientOption {
+	return runx.WithDoerMiddleware(func(next runx.HttpRequestDoer) runx.HttpRequestDoer {
+		return runx.DoerFunc(func(req *http.Request) (*http.Response, error) {
+			rsp, err := next.Do(req)
+			if err != nil || runx.OperationName(req) != op {
+				return rsp, err
+			}
+			return f(rsp)
+		})
+	})
+}
+
+// serverError replaces a response with a 502.
+func serverError(rsp *http.Response) (*http.Response, error) {
+	rsp.Body.Close()
+	rsp.StatusCode, rsp.Status = http.StatusBadGateway, "502 Bad Gateway"
+	rsp.Body = io.NopCloser(bytes.NewReader([]byte(`{"error":"bad gateway"}`)))
+	return rsp, nil
+}
+
+func TestDeployPackPolls(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer(runxtest.WithStartDelay(time.Hour))
+	defer srv.Close()
+	// the apps start before the third GetApps call: the one listing the
+	// apps before CreateApp, then one per poll
+	calls := 0
+	count := runx.WithDoerMiddleware(func(next runx.HttpRequestDoer) runx.HttpRequestDoer {
+		return runx.DoerFunc(func(req *http.Request) (*http.Response, error) {
+			if runx.OperationName(req) == "GetApps" {
+				if calls++; calls == 3 {
+					for _, app := range srv.Apps() {
+						srv.SetAppStatus(*app.Id, runx.AppStatusRunning)
+					}
+				}
+			}
+			return next.Do(req)
+		})
+	})
+	d, err := srv.Client(count).DeployPack(ctx, "web", fastWait)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Errorf("%d GetApps calls, want 3", calls)
+	}
+	for _, app := range d.Apps {
+		if *app.Status != runx.AppStatusRunning {
+			t.Errorf("%s: status %q, want running", *app.Name, *app.Status)
+		}
+	}
+}
+
+func TestDeployPackUnknownOutcome(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// setup returns the options of the client and of DeployPack
+		setup        func(srv *runxtest.Server) ([]runx.ClientOption, []runx.DeployOption)
+		wantErr      error
+		wantCreated  int
+		wantRolled   int
+		wantRemained int
+	}{
+		{
+			name: "server error after the creation",
+			setup: func(srv *runxtest.Server) ([]runx.ClientOption, []runx.DeployOption) {
+				return []runx.ClientOption{onOperation("CreateApp", serverError)}, nil
+			},
+			wantErr:     runx.ErrServer,
+			wantCreated: 2,
+			wantRolled:  2,
+		},
+		{
+			name: "transport error after the creation",
+			setup: func(srv *runxtest.Server) ([]runx.ClientOption, []runx.DeployOption) {
+				return []runx.ClientOption{onOperation("CreateApp", func(rsp *http.Response) (*http.Response, error) {
+					rsp.Body.Close()
+					return nil, io.ErrUnexpectedEOF
+				})}, nil
+			},
+			wantErr:     io.ErrUnexpectedEOF,
+			wantCreated: 2,
+			wantRolled:  2,
+		},
+		{
+			name: "without rollback",
+			setup: func(srv *runxtest.Server) ([]runx.ClientOption, []runx.DeployOption) {
+				return []runx.ClientOption{onOperation("CreateApp", serverError)}, []runx.DeployOption{runx.WithoutRollback()}
+			},
+			wantErr:      runx.ErrServer,
+			wantCreated:  2,
+			wantRemained: 2,
+		},
+		{
+			name: "server error before the creation",
+			setup: func(srv *runxtest.Server) ([]runx.ClientOption, []runx.DeployOption) {
+				srv.InjectFault(runxtest.Fault{Operation: "CreateApp", StatusCode: http.StatusServiceUnavailable, Times: 1})
+				return nil, nil
+			},
+			wantErr: runx.ErrServer,
+		},
+		{
+			name: "app of the same name kept",
+			setup: func(srv *runxtest.Server) ([]runx.ClientOption, []runx.DeployOption) {
+				if err := createApps(ctx, srv.Client(), runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "nginx", App: "nginx"}}}); err != nil {
+					t.Fatal(err)
+				}
+				// the conflict is hidden behind a 502
+				return []runx.ClientOption{onOperation("CreateApp", serverError)}, nil
+			},
+			wantErr:      runx.ErrServer,
+			wantRemained: 1,
+		},
+		{
+			name: "failed listing",
+			setup: func(srv *runxtest.Server) ([]runx.ClientOption, []runx.DeployOption) {
+				return []runx.ClientOption{onOperation("CreateApp", func(rsp *http.Response) (*http.Response, error) {
+					srv.InjectFault(runxtest.Fault{Operation: "GetApps", StatusCode: http.StatusInternalServerError, Times: 1})
+					return serverError(rsp)
+				})}, nil
+			},
+			wantErr:      runx.ErrServer,
+			wantRemained: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := runxtest.NewServer()
+			defer srv.Close()
+			clientOpts, deployOpts := tt.setup(srv)
+			d, err := srv.Client(clientOpts...).DeployPack(ctx, "web", append(deployOpts, fastWait)...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("DeployPack() error = %v, want %v", err, tt.wantErr)
+			}
+			if d == nil {
+				t.Fatalf("DeployPack() returned no deployment, error %v", err)
+			}
+			if len(d.Apps) != tt.wantCreated || len(d.RolledBack) != tt.wantRolled {
+				t.Errorf("%d apps created, %d rolled back, want %d and %d", len(d.Apps), len(d.RolledBack), tt.wantCreated, tt.wantRolled)
+			}
+			if n := len(srv.Apps()); n != tt.wantRemained {
+				t.Errorf("%d apps left on the server, want %d", n, tt.wantRemained)
+			}
+		})
+	}
+
+	// a client error leaves nothing to look for
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	lists := 0
+	client := srv.Client(onOperation("GetApps", func(rsp *http.Response) (*http.Response, error) {
+		lists++
+		return rsp, nil
+	}))
+	if _, err := client.DeployPack(ctx, "web", fastWait); err != nil {
+		t.Fatal(err)
+	}
+	lists = 0
+	if _, err := client.DeployPack(ctx, "web", fastWait); !errors.Is(err, runx.ErrConflict) {
+		t.Fatalf("DeployPack() error = %v, want %v", err, runx.ErrConflict)
+	}
+	if lists != 1 {
+		t.Errorf("%d GetApps calls after a conflict, want only the one before CreateApp", lists)
+	}
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalEnv(a, b *[]string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return slices.Equal(*a, *b)
+}
@@ -0,0 +1,135 @@
+package runx
+
+import (
+	"context"
+	"fmt"
+)
+
+// Number of hours used to turn an hourly price into a daily or monthly cost.
+// A month is an average month of 365 / 12 days.
+const (
+	HoursPerDay   = 24
+	HoursPerMonth = 730
+)
+
+// Cost is an amount spent per hour, day and month.
+type Cost struct {
+	Hourly  float64 `json:"hourly"`
+	Daily   float64 `json:"daily"`
+	Monthly float64 `json:"monthly"`
+}
+
+// HourlyCost returns the cost of an app billed price per hour.
+func HourlyCost(price float64) Cost {
+	return Cost{Hourly: price, Daily: price * HoursPerDay, Monthly: price * HoursPerMonth}
+}
+
+// Add returns the sum of c and o.
+func (c Cost) Add(o Cost) Cost {
+	return Cost{Hourly: c.Hourly + o.Hourly, Daily: c.Daily + o.Daily, Monthly: c.Monthly + o.Monthly}
+}
+
+// Catalog is the content of a GetCatalogApps response: the apps and packs
+// that can be deployed and the resources of the account.
+type Catalog struct {
+	Apps  []CatalogApp `json:"catalog"`
+	Packs []Pack       `json:"packs"`
+
+	// Limit and Threshold are the maximum and minimum values of the cpu,
+	// ram, disk and gpu resources of an app.
+	Limit     map[string]int `json:"limit"`
+	Threshold map[string]int `json:"threshold"`
+
+	AvailableGpus int  `json:"availableGpus"`
+	GpuAuthorized bool `json:"gpuAuthorized"`
+}
+
+// GetCatalog fetches the catalog through GetCatalogApps.
+func (c *ClientWithResponses) GetCatalog(ctx context.Context) (*Catalog, error) {
+	rsp, err := c.GetCatalogAppsWithResponse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckResponse("GetCatalogApps", rsp.HTTPResponse, rsp.Body); err != nil {
+		return nil, err
+	}
+	cat := &Catalog{Limit: map[string]int{}, Threshold: map[string]int{}}
+	if body := rsp.JSON200; body != nil {
+		if body.Catalog != nil {
+			cat.Apps = *body.Catalog
+		}
+		if body.Packs != nil {
+			cat.Packs = *body.Packs
+		}
+		if body.Limit != nil {
+			cat.Limit = *body.Limit
+		}
+		if body.Threshold != nil {
+			cat.Threshold = *body.Threshold
+		}
+		if body.AvailableGpus != nil {
+			cat.AvailableGpus = *body.AvailableGpus
+		}
+		if body.GpuAuthorized != nil {
+			cat.GpuAuthorized = *body.GpuAuthorized
+		}
+	}
+	return cat, nil
+}
+
+// App returns the catalog app identified by id, or nil.
+func (c *Catalog) App(id string) *CatalogApp {
+	for i := range c.Apps {
+		if c.Apps[i].Id != nil && *c.Apps[i].Id == id {
+			return &c.Apps[i]
+		}
+	}
+	return nil
+}
+
+// Pack returns the pack identified by id, or nil.
+func (c *Catalog) Pack(id string) *Pack {
+	for i := range c.Packs {
+		if c.Packs[i].Id != nil && *c.Packs[i].Id == id {
+			return &c.Packs[i]
+		}
+	}
+	return nil
+}
+
+// Price returns the hourly price of the catalog app identified by id.
+func (c *Catalog) Price(id string) (float64, bool) {
+	app := c.App(id)
+	if app == nil || app.Price == nil {
+		return 0, false
+	}
+	return value(app.Price), true
+}
+
+// Requests returns the apps created by req: its Apps, or the apps of its Pack
+// when Apps is empty, each named after its catalog id as done by the server.
+func (c *Catalog) Requests(req CreateAppRequest) ([]AppRequest, error) {
+	if req.Pack == nil || len(req.Apps) > 0 {
+		return req.Apps, nil
+	}
+	pack := c.Pack(*req.Pack)
+	if pack == nil {
+		return nil, fmt.Errorf("runx: pack %s: %w", *req.Pack, ErrNotFound)
+	}
+	var apps []AppRequest
+	if pack.Apps != nil {
+		for _, id := range *pack.Apps {
+			apps = append(apps, AppRequest{App: id, Name: id})
+		}
+	}
+	return apps, nil
+}
+
+// billed reports whether app is consuming credit, that is enabled, or
+// running when the server does not report whether it is enabled.
+func billed(app AppExtended) bool {
+	if app.Enabled != nil {
+		return *app.Enabled
+	}
+	return app.Status != nil && *app.Status == AppStatusRunning
+}
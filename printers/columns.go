@@ -0,0 +1,210 @@
+package printers
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	runx "github.com/run-x-app/runx-go"
+)
+
+// Column is a column of a table or CSV output. Value receives a model value,
+// never a pointer, and returns an empty string for missing fields.
+type Column struct {
+	Header string
+	Value  func(v any) string
+}
+
+func column[T any](header string, value func(T) string) Column {
+	return Column{
+		Header: header,
+		Value: func(v any) string {
+			return value(v.(T))
+		},
+	}
+}
+
+var defaultColumns = map[reflect.Type][]Column{
+	reflect.TypeOf(runx.AppExtended{}): {
+		column("SHORT ID", func(a runx.AppExtended) string { return str(a.ShortId) }),
+		column("NAME", func(a runx.AppExtended) string { return str(a.Name) }),
+		column("APP", func(a runx.AppExtended) string { return str(a.App) }),
+		column("STATUS", func(a runx.AppExtended) string { return str(a.Status) }),
+		column("CPU", func(a runx.AppExtended) string { return num(a.Cpu) }),
+		column("RAM", func(a runx.AppExtended) string { return num(a.Ram) }),
+		column("GPU", func(a runx.AppExtended) string { return num(a.Gpu) }),
+		column("HOST", func(a runx.AppExtended) string { return str(a.Host) }),
+	},
+	reflect.TypeOf(runx.App{}): {
+		column("SHORT ID", func(a runx.App) string { return str(a.ShortId) }),
+		column("NAME", func(a runx.App) string { return str(a.Name) }),
+		column("APP", func(a runx.App) string { return str(a.App) }),
+		column("CPU", func(a runx.App) string { return num(a.Cpu) }),
+		column("RAM", func(a runx.App) string { return num(a.Ram) }),
+		column("GPU", func(a runx.App) string { return num(a.Gpu) }),
+		column("HOST", func(a runx.App) string { return str(a.Host) }),
+		column("PRICE", func(a runx.App) string { return amount(a.Price) }),
+	},
+	reflect.TypeOf(runx.CatalogApp{}): {
+		column("ID", func(a runx.CatalogApp) string { return str(a.Id) }),
+		column("NAME", func(a runx.CatalogApp) string { return str(a.Name) }),
+		column("PRICE", func(a runx.CatalogApp) string { return amount(a.Price) }),
+		column("GPU", func(a runx.CatalogApp) string { return num(a.Gpu) }),
+		column("LEVEL", func(a runx.CatalogApp) string { return num(a.Level) }),
+		column("ENABLED", func(a runx.CatalogApp) string { return boolean(a.Enabled) }),
+	},
+	reflect.TypeOf(runx.Pack{}): {
+		column("ID", func(p runx.Pack) string { return str(p.Id) }),
+		column("NAME", func(p runx.Pack) string { return str(p.Name) }),
+		column("APPS", func(p runx.Pack) string { return list(p.Apps) }),
+		column("LEVEL", func(p runx.Pack) string { return num(p.Level) }),
+		column("ENABLED", func(p runx.Pack) string { return boolean(p.Enabled) }),
+	},
+	reflect.TypeOf(runx.Payment{}): {
+		column("ID", func(p runx.Payment) string { return str(p.Id) }),
+		column("AMOUNT", func(p runx.Payment) string { return amount(p.Amount) }),
+		column("CREATED", func(p runx.Payment) string { return timestamp(p.CreatedAt) }),
+	},
+	reflect.TypeOf(runx.SessionInfo{}): {
+		column("ID", func(s runx.SessionInfo) string { return str(s.Id) }),
+		column("IP", func(s runx.SessionInfo) string { return str(s.Ip) }),
+		column("USER AGENT", func(s runx.SessionInfo) string { return str(s.UserAgent) }),
+		column("CREATED", func(s runx.SessionInfo) string { return timestamp(s.CreatedAt) }),
+	},
+	reflect.TypeOf(runx.SessionAudit{}): {
+		column("ID", func(a runx.SessionAudit) string { return a.Id }),
+		column("CURRENT", func(a runx.SessionAudit) string {
+			if a.Current {
+				return "*"
+			}
+			return ""
+		}),
+		column("IP", func(a runx.SessionAudit) string { return a.Ip }),
+		column("USER AGENT", func(a runx.SessionAudit) string { return a.UserAgent }),
+		column("CREATED", func(a runx.SessionAudit) string { return timestamp(a.CreatedAt) }),
+		column("REASONS", func(a runx.SessionAudit) string { return strings.Join(a.Reasons, ",") }),
+	},
+	reflect.TypeOf(runx.Consumption{}): {
+		column("DATE", func(c runx.Consumption) string { return timestamp(c.Date) }),
+		column("VALUE", func(c runx.Consumption) string { return amount(c.Value) }),
+		column("LIMIT", func(c runx.Consumption) string { return amount(c.Limit) }),
+	},
+	reflect.TypeOf(runx.BillingPeriod{}): {
+		column("START", func(p runx.BillingPeriod) string { return p.Start.Format(time.RFC3339) }),
+		column("END", func(p runx.BillingPeriod) string { return p.End.Format(time.RFC3339) }),
+		column("PAYMENTS", func(p runx.BillingPeriod) string { return money(p.Payments) }),
+		column("COUNT", func(p runx.BillingPeriod) string { return strconv.Itoa(p.PaymentCount) }),
+		column("CONSUMPTION", func(p runx.BillingPeriod) string { return money(p.Consumption) }),
+		column("BALANCE", func(p runx.BillingPeriod) string { return money(p.Balance) }),
+	},
+	reflect.TypeOf(runx.AppEstimate{}): {
+		column("NAME", func(e runx.AppEstimate) string { return e.Name }),
+		column("APP", func(e runx.AppEstimate) string { return e.App }),
+		column("HOURLY", func(e runx.AppEstimate) string { return money(e.Cost.Hourly) }),
+		column("DAILY", func(e runx.AppEstimate) string { return money(e.Cost.Daily) }),
+		column("MONTHLY", func(e runx.AppEstimate) string { return money(e.Cost.Monthly) }),
+	},
+	reflect.TypeOf(runx.FilteredUser{}): {
+		column("ID", func(u runx.FilteredUser) string { return str(u.Id) }),
+		column("EMAIL", func(u runx.FilteredUser) string { return str(u.Email) }),
+		column("LEVEL", func(u runx.FilteredUser) string { return num(u.Level) }),
+		column("CREDIT", func(u runx.FilteredUser) string { return amount(u.Credit) }),
+		column("LIMIT", func(u runx.FilteredUser) string { return amount(u.Limit) }),
+		column("APPS", func(u runx.FilteredUser) string { return num(u.TotalApps) }),
+	},
+}
+
+// DefaultColumns returns the columns used for values of type t, or nil when
+// the type is not a known model.
+func DefaultColumns(t reflect.Type) []Column {
+	return defaultColumns[t]
+}
+
+// RegisterColumns sets the default columns for the type of sample. It is not
+// safe to call concurrently with printing.
+func RegisterColumns(sample any, columns []Column) {
+	defaultColumns[reflect.TypeOf(sample)] = columns
+}
+
+// rows flattens v, a model value, a pointer to one or a slice of either, into
+// its elements and returns their type.
+func rows(v any) (reflect.Type, []any, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil, fmt.Errorf("printers: nothing to print")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return rv.Type(), []any{rv.Interface()}, nil
+	}
+
+	t := rv.Type().Elem()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	items := make([]any, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		item := rv.Index(i)
+		for item.Kind() == reflect.Pointer {
+			if item.IsNil() {
+				break
+			}
+			item = item.Elem()
+		}
+		if item.Kind() == reflect.Pointer {
+			continue
+		}
+		items = append(items, item.Interface())
+	}
+	return t, items, nil
+}
+
+func str(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
+
+func num(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+func amount(v *float32) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(float64(*v), 'f', 2, 32)
+}
+
+func money(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+func boolean(v *bool) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatBool(*v)
+}
+
+func timestamp(v *time.Time) string {
+	if v == nil {
+		return ""
+	}
+	return v.Format(time.RFC3339)
+}
+
+func list(v *[]string) string {
+	if v == nil {
+		return ""
+	}
+	return strings.Join(*v, ",")
+}
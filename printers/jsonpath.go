@@ -0,0 +1,392 @@
+package printers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// JSONPath prints the values selected by a JSONPath template. It supports a
+// subset of the kubectl syntax operating on the JSON representation of the
+// printed value:
+//
+//	{.name}                      field access
+//	{[0].name} {[*].name}        index and wildcard
+//	{[1:3].name} {[-2:].name}    slice, with an exclusive end
+//	{[?(@.status=="running")]}   filter on equality (== or !=)
+//	{range [*]}{.id}{"\n"}{end}  iteration
+//
+// Text outside of the braces is printed as is. Several values selected by a
+// single expression are separated by a space.
+type JSONPath struct {
+	nodes []jpNode
+}
+
+type jpNode struct {
+	text  string
+	path  []jpStep
+	isRaw bool
+	body  []jpNode // set for range
+	loop  bool
+}
+
+type jpStep struct {
+	field    string
+	index    int
+	wildcard bool
+	isIndex  bool
+	filter   *jpFilter
+	slice    *jpSlice
+}
+
+// jpSlice selects the elements from start up to end, excluded. Negative
+// bounds count from the end and nil ones from the ends of the list.
+type jpSlice struct {
+	start, end *int
+}
+
+type jpFilter struct {
+	path  []jpStep
+	equal bool
+	value any
+}
+
+// NewJSONPath parses a JSONPath template.
+func NewJSONPath(text string) (*JSONPath, error) {
+	if text == "" {
+		return nil, fmt.Errorf("printers: empty jsonpath")
+	}
+	nodes, rest, err := parseJSONPath(text, false)
+	if err != nil {
+		return nil, fmt.Errorf("printers: jsonpath: %w", err)
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("printers: jsonpath: unexpected {end}")
+	}
+	return &JSONPath{nodes: nodes}, nil
+}
+
+// parseJSONPath parses text up to the end or, within a range, up to the
+// matching {end}, and returns the remaining text.
+func parseJSONPath(text string, inRange bool) ([]jpNode, string, error) {
+	var nodes []jpNode
+	for text != "" {
+		open := strings.IndexByte(text, '{')
+		if open < 0 {
+			nodes = append(nodes, jpNode{text: text, isRaw: true})
+			return nodes, "", nil
+		}
+		if open > 0 {
+			nodes = append(nodes, jpNode{text: text[:open], isRaw: true})
+		}
+		end := strings.IndexByte(text[open:], '}')
+		if end < 0 {
+			return nil, "", fmt.Errorf("unclosed action in %q", text)
+		}
+		action := strings.TrimSpace(text[open+1 : open+end])
+		text = text[open+end+1:]
+
+		switch {
+		case action == "end":
+			if !inRange {
+				return nodes, "end", nil
+			}
+			return nodes, text, nil
+		case strings.HasPrefix(action, "range "):
+			path, err := parsePath(strings.TrimSpace(strings.TrimPrefix(action, "range ")))
+			if err != nil {
+				return nil, "", err
+			}
+			body, rest, err := parseJSONPath(text, true)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, jpNode{path: path, body: body, loop: true})
+			text = rest
+			continue
+		case strings.HasPrefix(action, `"`):
+			s, err := strconv.Unquote(action)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid string %s", action)
+			}
+			nodes = append(nodes, jpNode{text: s, isRaw: true})
+		default:
+			path, err := parsePath(action)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, jpNode{path: path})
+		}
+	}
+	if inRange {
+		return nil, "", fmt.Errorf("range without {end}")
+	}
+	return nodes, "", nil
+}
+
+// parsePath parses an expression such as .apps[*].name.
+func parsePath(expr string) ([]jpStep, error) {
+	expr = strings.TrimPrefix(expr, "$")
+	expr = strings.TrimPrefix(expr, "@")
+	var steps []jpStep
+	for expr != "" {
+		switch expr[0] {
+		case '.':
+			expr = expr[1:]
+			n := strings.IndexAny(expr, ".[")
+			if n < 0 {
+				n = len(expr)
+			}
+			if n > 0 {
+				steps = append(steps, jpStep{field: expr[:n]})
+			}
+			expr = expr[n:]
+		case '[':
+			end := matchingBracket(expr)
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in %q", expr)
+			}
+			inner := strings.TrimSpace(expr[1:end])
+			expr = expr[end+1:]
+			switch {
+			case inner == "*":
+				steps = append(steps, jpStep{wildcard: true})
+			case strings.HasPrefix(inner, "?(") && strings.HasSuffix(inner, ")"):
+				filter, err := parseFilter(inner[2 : len(inner)-1])
+				if err != nil {
+					return nil, err
+				}
+				steps = append(steps, jpStep{filter: filter})
+			case strings.HasPrefix(inner, "'") || strings.HasPrefix(inner, `"`):
+				steps = append(steps, jpStep{field: strings.Trim(inner, `'"`)})
+			case strings.Contains(inner, ":"):
+				slice, err := parseSlice(inner)
+				if err != nil {
+					return nil, err
+				}
+				steps = append(steps, jpStep{slice: slice})
+			default:
+				i, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q", inner)
+				}
+				steps = append(steps, jpStep{index: i, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q in expression", expr)
+		}
+	}
+	return steps, nil
+}
+
+// parseSlice parses start:end, where both bounds are optional.
+func parseSlice(expr string) (*jpSlice, error) {
+	start, end, _ := strings.Cut(expr, ":")
+	bound := func(s string) (*int, error) {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return nil, nil
+		}
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid slice %q", expr)
+		}
+		return &i, nil
+	}
+	var slice jpSlice
+	var err error
+	if slice.start, err = bound(start); err != nil {
+		return nil, err
+	}
+	if slice.end, err = bound(end); err != nil {
+		return nil, err
+	}
+	return &slice, nil
+}
+
+func matchingBracket(expr string) int {
+	depth := 0
+	quote := byte(0)
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func parseFilter(expr string) (*jpFilter, error) {
+	op := "=="
+	lhs, rhs, ok := strings.Cut(expr, "==")
+	if !ok {
+		op = "!="
+		lhs, rhs, ok = strings.Cut(expr, "!=")
+	}
+	if !ok {
+		return nil, fmt.Errorf("unsupported filter %q", expr)
+	}
+	path, err := parsePath(strings.TrimSpace(lhs))
+	if err != nil {
+		return nil, err
+	}
+	rhs = strings.TrimSpace(rhs)
+	var value any
+	if strings.HasPrefix(rhs, "'") {
+		rhs = `"` + strings.Trim(rhs, "'") + `"`
+	}
+	if err := json.Unmarshal([]byte(rhs), &value); err != nil {
+		return nil, fmt.Errorf("invalid filter value %q", rhs)
+	}
+	return &jpFilter{path: path, equal: op == "==", value: value}, nil
+}
+
+// Print implements Printer.
+func (j *JSONPath) Print(w io.Writer, v any) error {
+	data, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+	return executeJSONPath(w, j.nodes, data)
+}
+
+func executeJSONPath(w io.Writer, nodes []jpNode, data any) error {
+	for _, n := range nodes {
+		switch {
+		case n.isRaw:
+			if _, err := io.WriteString(w, n.text); err != nil {
+				return err
+			}
+		case n.loop:
+			for _, item := range evalPath(n.path, data) {
+				if err := executeJSONPath(w, n.body, item); err != nil {
+					return err
+				}
+			}
+		default:
+			values := evalPath(n.path, data)
+			parts := make([]string, len(values))
+			for i, v := range values {
+				parts[i] = formatGeneric(v)
+			}
+			if _, err := io.WriteString(w, strings.Join(parts, " ")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// evalPath returns the values selected by steps. Missing fields select nothing.
+func evalPath(steps []jpStep, data any) []any {
+	current := []any{data}
+	for _, step := range steps {
+		var next []any
+		for _, v := range current {
+			switch {
+			case step.field != "":
+				if m, ok := v.(map[string]any); ok {
+					if f, ok := m[step.field]; ok {
+						next = append(next, f)
+					}
+				}
+			case step.wildcard:
+				switch t := v.(type) {
+				case []any:
+					next = append(next, t...)
+				case map[string]any:
+					// in the order of the keys, for a stable output
+					for _, k := range slices.Sorted(maps.Keys(t)) {
+						next = append(next, t[k])
+					}
+				}
+			case step.isIndex:
+				if s, ok := v.([]any); ok {
+					i := step.index
+					if i < 0 {
+						i += len(s)
+					}
+					if i >= 0 && i < len(s) {
+						next = append(next, s[i])
+					}
+				}
+			case step.slice != nil:
+				if s, ok := v.([]any); ok {
+					next = append(next, step.slice.apply(s)...)
+				}
+			case step.filter != nil:
+				if s, ok := v.([]any); ok {
+					for _, item := range s {
+						if step.filter.match(item) {
+							next = append(next, item)
+						}
+					}
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+func (s *jpSlice) apply(items []any) []any {
+	bound := func(b *int, def int) int {
+		if b == nil {
+			return def
+		}
+		i := *b
+		if i < 0 {
+			i += len(items)
+		}
+		return max(0, min(i, len(items)))
+	}
+	start, end := bound(s.start, 0), bound(s.end, len(items))
+	if start >= end {
+		return nil
+	}
+	return items[start:end]
+}
+
+func (f *jpFilter) match(item any) bool {
+	for _, v := range evalPath(f.path, item) {
+		if (formatGeneric(v) == formatGeneric(f.value)) == f.equal {
+			return true
+		}
+	}
+	return false
+}
+
+// formatGeneric prints scalars as plain text and anything else as JSON.
+func formatGeneric(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
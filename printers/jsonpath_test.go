@@ -0,0 +1,101 @@
+package printers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	data := map[string]any{
+		"apps": []map[string]any{
+			{"id": "a1", "name": "web", "status": "running", "cpu": 1, "env": []string{"A=1"}},
+			{"id": "a2", "name": "db", "status": "stopped", "cpu": 2},
+			{"id": "a3", "name": "cache", "status": "running", "cpu": 1.5, "enabled": false},
+		},
+		"user": map[string]any{"b": 2, "a": 1, "c": "three"},
+	}
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"{.user.c}", "three"},
+		{"name={.apps[0].name}", "name=web"},
+		{"{$.apps[0]['name']}", "web"},
+		{"{.apps[-1].name}", "cache"},
+		{"{.apps[5].name}", ""},
+		{"{.missing.field}", ""},
+		{"{.apps[0].env}", `["A=1"]`},
+		{"{.apps[2].enabled}", "false"},
+		{"{.apps[2].cpu}", "1.5"},
+
+		// wildcard
+		{"{.apps[*].id}", "a1 a2 a3"},
+		{"{.user[*]}", "1 2 three"},
+		{"{.apps[*].env}", `["A=1"]`},
+
+		// slice
+		{"{.apps[0:2].name}", "web db"},
+		{"{.apps[1:].name}", "db cache"},
+		{"{.apps[:1].name}", "web"},
+		{"{.apps[-2:].name}", "db cache"},
+		{"{.apps[:-1].name}", "web db"},
+		{"{.apps[1:10].name}", "db cache"},
+		{"{.apps[2:1].name}", ""},
+		{"{.apps[:].name}", "web db cache"},
+
+		// filter
+		{`{.apps[?(@.status=="running")].name}`, "web cache"},
+		{`{.apps[?(@.status != 'running')].name}`, "db"},
+		{"{.apps[?(@.cpu==1)].id}", "a1"},
+		{"{.apps[?(@.enabled==false)].id}", "a3"},
+		{`{.apps[?(@.status=="crashed")].id}`, ""},
+		{`{.apps[?(@.name=="a[1]")].id}`, ""},
+
+		// range
+		{`{range .apps[*]}{.id}:{.cpu}{"\n"}{end}`, "a1:1\na2:2\na3:1.5\n"},
+		{`{range .apps[?(@.status=="running")]}[{.name}]{end}`, "[web][cache]"},
+		{`{range .apps[:2]}{range .env[*]}{@}{end};{end}`, "A=1;;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			p, err := NewJSONPath(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b strings.Builder
+			if err := p.Print(&b, data); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("Print() = %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONPathErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr string
+	}{
+		{"", "empty jsonpath"},
+		{"{.name", "unclosed action"},
+		{"{.apps[0}", "unclosed ["},
+		{"{.apps[x]}", `invalid index "x"`},
+		{"{.apps[1:x]}", `invalid slice "1:x"`},
+		{"{.apps[?(@.cpu>1)]}", "unsupported filter"},
+		{"{.apps[?(@.name==web)]}", "invalid filter value"},
+		{`{"unterminated}`, "invalid string"},
+		{"{range .apps[*]}{.id}", "range without {end}"},
+		{"{.id}{end}", "unexpected {end}"},
+		{"{name}", "unexpected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := NewJSONPath(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewJSONPath() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
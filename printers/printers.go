@@ -0,0 +1,99 @@
+// Package printers renders the Run X models as tables, JSON, YAML, CSV, Go
+// templates or JSONPath expressions.
+package printers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Printer writes a value to w.
+type Printer interface {
+	Print(w io.Writer, v any) error
+}
+
+// PrinterFunc is an adapter to allow the use of ordinary functions as Printer.
+type PrinterFunc func(w io.Writer, v any) error
+
+// Print calls f(w, v).
+func (f PrinterFunc) Print(w io.Writer, v any) error {
+	return f(w, v)
+}
+
+// Formats accepted by New, besides "template=<template>" and "jsonpath=<expression>".
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+	FormatCSV   = "csv"
+)
+
+// New returns the Printer for format, which is one of table, json, yaml, csv,
+// template=<Go template> or jsonpath=<JSONPath expression>. An empty format
+// selects the table.
+func New(format string) (Printer, error) {
+	name, arg, _ := strings.Cut(format, "=")
+	switch name {
+	case "", FormatTable:
+		return &Table{}, nil
+	case FormatJSON:
+		return PrinterFunc(PrintJSON), nil
+	case FormatYAML:
+		return PrinterFunc(PrintYAML), nil
+	case FormatCSV:
+		return &CSV{}, nil
+	case "template", "go-template":
+		return NewTemplate(arg)
+	case "jsonpath":
+		return NewJSONPath(arg)
+	}
+	return nil, fmt.Errorf("printers: unknown format %q", format)
+}
+
+// Print writes v to w in the given format.
+func Print(w io.Writer, format string, v any) error {
+	p, err := New(format)
+	if err != nil {
+		return err
+	}
+	return p.Print(w, v)
+}
+
+// PrintJSON writes v as indented JSON.
+func PrintJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// PrintYAML writes v as YAML, using the same field names as the JSON encoding.
+func PrintYAML(w io.Writer, v any) error {
+	generic, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(generic); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// toGeneric converts v to its JSON representation made of maps, slices and
+// scalars, leaving out the nil fields.
+func toGeneric(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
@@ -0,0 +1,149 @@
+package printers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	runx "github.com/run-x-app/runx-go"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    Printer
+		wantErr bool
+	}{
+		{format: "", want: &Table{}},
+		{format: "table", want: &Table{}},
+		{format: "csv", want: &CSV{}},
+		{format: "json", want: PrinterFunc(PrintJSON)},
+		{format: "yaml", want: PrinterFunc(PrintYAML)},
+		{format: "template={{.id}}", want: &Template{}},
+		{format: "go-template={{.id}}", want: &Template{}},
+		{format: "jsonpath={.id}", want: &JSONPath{}},
+		{format: "template=", wantErr: true},
+		{format: "jsonpath", wantErr: true},
+		{format: "xml", wantErr: true},
+		{format: "JSON", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := New(tt.format)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("New() = %T, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reflect.TypeOf(got) != reflect.TypeOf(tt.want) {
+				t.Errorf("New() = %T, want %T", got, tt.want)
+			}
+		})
+	}
+}
+
+var testApps = []runx.AppExtended{
+	{ShortId: ptr("a1"), Name: ptr("web"), App: ptr("nginx"), Status: ptr("running"), Cpu: ptr(2), Ram: ptr(512), Gpu: ptr(0), Host: ptr("a1.run-x.app")},
+	{ShortId: ptr("a2"), Name: ptr("my\tdb"), App: ptr("postgres")},
+}
+
+func render(t *testing.T, format string, v any) string {
+	t.Helper()
+	var b strings.Builder
+	if err := Print(&b, format, v); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestPrintJSON(t *testing.T) {
+	want := "{\n  \"app\": \"postgres\",\n  \"name\": \"my\\tdb\",\n  \"short_id\": \"a2\"\n}\n"
+	if got := render(t, FormatJSON, testApps[1]); got != want {
+		t.Errorf("Print() = %q, want %q", got, want)
+	}
+}
+
+func TestPrintYAML(t *testing.T) {
+	want := "- app: nginx\n  cpu: 2\n  gpu: 0\n  host: a1.run-x.app\n  name: web\n  ram: 512\n  short_id: a1\n  status: running\n" +
+		"- app: postgres\n  name: \"my\\tdb\"\n  short_id: a2\n"
+	if got := render(t, FormatYAML, testApps); got != want {
+		t.Errorf("Print() = %q, want %q", got, want)
+	}
+}
+
+func TestTable(t *testing.T) {
+	want := "" +
+		"SHORT ID   NAME    APP        STATUS    CPU   RAM   GPU   HOST\n" +
+		"a1         web     nginx      running   2     512   0     a1.run-x.app\n" +
+		"a2         my db   postgres   -         -     -     -     -\n"
+	if got := render(t, FormatTable, testApps); got != want {
+		t.Errorf("Print() =\n%s\nwant\n%s", got, want)
+	}
+	// pointers and single values print like slices
+	if got := render(t, FormatTable, []*runx.AppExtended{&testApps[0], nil}); strings.Count(got, "\n") != 2 || !strings.HasSuffix(got, "a1.run-x.app\n") {
+		t.Errorf("Print() =\n%s", got)
+	}
+	if got := render(t, FormatTable, &testApps[0]); !strings.HasSuffix(got, "a1.run-x.app\n") {
+		t.Errorf("Print() =\n%s", got)
+	}
+
+	table := &Table{
+		NoHeaders: true,
+		Columns: []Column{
+			column("NAME", func(a runx.AppExtended) string { return str(a.Name) }),
+			column("APP", func(a runx.AppExtended) string { return str(a.App) }),
+		},
+	}
+	var b strings.Builder
+	if err := table.Print(&b, testApps); err != nil {
+		t.Fatal(err)
+	}
+	if want := "web     nginx\nmy db   postgres\n"; b.String() != want {
+		t.Errorf("Print() = %q, want %q", b.String(), want)
+	}
+
+	// types without columns are printed as YAML
+	if got := render(t, FormatTable, map[string]int{"count": 2}); got != "count: 2\n" {
+		t.Errorf("Print() = %q, want YAML", got)
+	}
+	if err := Print(&b, FormatTable, (*runx.AppExtended)(nil)); err == nil {
+		t.Error("Print(nil) succeeded")
+	}
+}
+
+func TestCSV(t *testing.T) {
+	want := "" +
+		"SHORT ID,NAME,APP,STATUS,CPU,RAM,GPU,HOST\n" +
+		"a1,web,nginx,running,2,512,0,a1.run-x.app\n" +
+		"a2,my\tdb,postgres,,,,,\n"
+	if got := render(t, FormatCSV, testApps); got != want {
+		t.Errorf("Print() = %q, want %q", got, want)
+	}
+
+	catalog := []runx.CatalogApp{{Id: ptr("nginx"), Name: ptr("Nginx, the web server"), Price: ptr(float32(0.01)), Enabled: ptr(true)}}
+	want = "ID,NAME,PRICE,GPU,LEVEL,ENABLED\nnginx,\"Nginx, the web server\",0.01,,,true\n"
+	if got := render(t, FormatCSV, catalog); got != want {
+		t.Errorf("Print() = %q, want %q", got, want)
+	}
+
+	if err := Print(&strings.Builder{}, FormatCSV, map[string]int{"count": 2}); err == nil || !strings.Contains(err.Error(), "no CSV columns") {
+		t.Errorf("Print() error = %v, want no CSV columns", err)
+	}
+}
+
+func TestDefaultColumns(t *testing.T) {
+	// every column accepts the zero value of its type
+	for typ, columns := range defaultColumns {
+		zero := reflect.Zero(typ).Interface()
+		for _, c := range columns {
+			if c.Header == "" {
+				t.Errorf("%s: column without header", typ)
+			}
+			c.Value(zero)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package printers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Table prints values as aligned columns. Missing fields are shown as "-".
+type Table struct {
+	// Columns overrides the default columns of the printed type.
+	Columns []Column
+
+	// NoHeaders leaves out the header line.
+	NoHeaders bool
+}
+
+// Print implements Printer. Values of unknown types are printed as YAML.
+func (t *Table) Print(w io.Writer, v any) error {
+	typ, items, err := rows(v)
+	if err != nil {
+		return err
+	}
+	columns := t.Columns
+	if columns == nil {
+		columns = DefaultColumns(typ)
+	}
+	if columns == nil {
+		return PrintYAML(w, v)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 3, ' ', 0)
+	if !t.NoHeaders {
+		headers := make([]string, len(columns))
+		for i, c := range columns {
+			headers[i] = c.Header
+		}
+		fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	}
+	for _, item := range items {
+		cells := make([]string, len(columns))
+		for i, c := range columns {
+			cells[i] = c.Value(item)
+			if cells[i] == "" {
+				cells[i] = "-"
+			}
+			// keep the columns aligned
+			cells[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(cells[i])
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+// CSV prints values as comma-separated values with a header line. Missing
+// fields are left empty.
+type CSV struct {
+	// Columns overrides the default columns of the printed type.
+	Columns []Column
+}
+
+// Print implements Printer.
+func (p *CSV) Print(w io.Writer, v any) error {
+	typ, items, err := rows(v)
+	if err != nil {
+		return err
+	}
+	columns := p.Columns
+	if columns == nil {
+		columns = DefaultColumns(typ)
+	}
+	if columns == nil {
+		return fmt.Errorf("printers: no CSV columns for %s", typ)
+	}
+
+	cw := csv.NewWriter(w)
+	record := make([]string, len(columns))
+	for i, c := range columns {
+		record[i] = c.Header
+	}
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+	for _, item := range items {
+		for i, c := range columns {
+			record[i] = c.Value(item)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
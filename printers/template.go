@@ -0,0 +1,71 @@
+package printers
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// Template prints values through a Go template.
+type Template struct {
+	tmpl *template.Template
+}
+
+// NewTemplate parses text as a Go template. Like JSONPath, the template
+// receives the JSON representation of the printed value, so fields are
+// named as in JSON and hold plain values. A missing field prints as
+// "<no value>", which the deref and default functions take care of:
+//
+//	{{range .}}{{.name}} {{default "unknown" .host}}{{"\n"}}{{end}}
+func NewTemplate(text string) (*Template, error) {
+	if text == "" {
+		return nil, fmt.Errorf("printers: empty template")
+	}
+	tmpl, err := template.New("output").Funcs(template.FuncMap{
+		"deref":   deref,
+		"default": defaultValue,
+		"join":    strings.Join,
+		"upper":   strings.ToUpper,
+		"lower":   strings.ToLower,
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("printers: %w", err)
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+// Print implements Printer.
+func (t *Template) Print(w io.Writer, v any) error {
+	data, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+	return t.tmpl.Execute(w, data)
+}
+
+// deref returns the value v points to, or an empty string when v is nil or
+// missing.
+func deref(v any) any {
+	rv := reflect.ValueOf(v)
+	for rv.IsValid() && rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return ""
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return ""
+	}
+	return rv.Interface()
+}
+
+// defaultValue returns v dereferenced, or def when v is nil or empty.
+func defaultValue(def any, v any) any {
+	d := deref(v)
+	if d == "" || d == nil {
+		return def
+	}
+	return d
+}
@@ -0,0 +1,75 @@
+package printers
+
+import (
+	"strings"
+	"testing"
+
+	runx "github.com/run-x-app/runx-go"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestTemplate(t *testing.T) {
+	apps := []runx.AppExtended{
+		{Id: ptr("a1"), Name: ptr("web"), Status: ptr("running"), Cpu: ptr(2), Host: ptr("web.run-x.app")},
+		{Id: ptr("a2"), Cpu: ptr(1)},
+	}
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"json field names", `{{range .}}{{.id}}={{.cpu}};{{end}}`, "a1=2;a2=1;"},
+		{"nil field", `{{(index . 1).name}}`, "<no value>"},
+		{"deref", `{{range .}}[{{deref .name}}]{{end}}`, "[web][]"},
+		{"default", `{{range .}}{{default "-" .host}} {{end}}`, "web.run-x.app - "},
+		{"default on empty string", `{{default "none" ""}}`, "none"},
+		{"comparison", `{{range .}}{{if eq .status "running"}}{{.name}}{{end}}{{end}}`, "web"},
+		{"functions", `{{upper (index . 0).name}} {{lower "WEB"}}`, "WEB web"},
+		{"len", `{{len .}}`, "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewTemplate(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b strings.Builder
+			if err := p.Print(&b, apps); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("Print() = %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+
+	// a single value and a pointer to it print the same
+	p, err := NewTemplate(`{{.name}} {{.cpu}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []any{apps[0], &apps[0]} {
+		var b strings.Builder
+		if err := p.Print(&b, v); err != nil || b.String() != "web 2" {
+			t.Errorf("Print(%T) = %q, %v, want %q", v, b.String(), err, "web 2")
+		}
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	for _, text := range []string{"", "{{.name", "{{frobnicate .}}"} {
+		if _, err := NewTemplate(text); err == nil {
+			t.Errorf("NewTemplate(%q) succeeded", text)
+		}
+	}
+	p, err := NewTemplate("{{.name.first}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Print(&strings.Builder{}, map[string]string{"name": "web"}); err == nil {
+		t.Error("Print() succeeded on a field of a string")
+	}
+}
@@ -0,0 +1,267 @@
+package runx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limiter throttles the requests sent by a client with a token bucket and
+// caps the number of requests in flight. A single Limiter may be shared by
+// several clients through WithLimiter.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  int
+	tokens float64
+	last   time.Time
+	slots  chan struct{}
+
+	stats LimiterStats
+}
+
+// LimiterStats reports how much a Limiter is holding requests back.
+type LimiterStats struct {
+	// Waiting is the number of requests currently waiting for the limiter.
+	Waiting int
+
+	// InFlight is the number of requests currently holding a slot.
+	InFlight int
+
+	// Requests is the total number of requests admitted by the limiter.
+	Requests int64
+
+	// Throttled is the number of requests which had to wait.
+	Throttled int64
+
+	// TotalWait is the cumulated time spent waiting.
+	TotalWait time.Duration
+
+	// MaxWait is the longest time a single request has waited.
+	MaxWait time.Duration
+}
+
+// errSharedLimiter is returned when WithLimiter is combined with
+// WithRateLimit, WithMaxInFlight or another WithLimiter.
+var errSharedLimiter = errors.New("runx: WithLimiter cannot be combined with another limiter option, configure the Limiter through NewLimiter instead")
+
+// NewLimiter creates a Limiter allowing rps requests per second with bursts
+// of up to burst requests, and at most maxInFlight concurrent requests. A
+// zero rps or maxInFlight disables the matching limit.
+func NewLimiter(rps float64, burst int, maxInFlight int) *Limiter {
+	l := &Limiter{}
+	l.setRate(rps, burst)
+	l.setMaxInFlight(maxInFlight)
+	return l
+}
+
+// setRate and setMaxInFlight configure a limiter which is not used yet, see
+// WithRateLimit and WithMaxInFlight.
+func (l *Limiter) setRate(rps float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = rps
+	l.burst = burst
+	l.tokens = float64(burst)
+	l.last = time.Now()
+}
+
+func (l *Limiter) setMaxInFlight(n int) {
+	var slots chan struct{}
+	if n > 0 {
+		slots = make(chan struct{}, n)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.slots = slots
+}
+
+// Stats returns a snapshot of the limiter statistics.
+func (l *Limiter) Stats() LimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
+
+// Wait blocks until a request may be sent, or ctx is done. The returned
+// function releases the in-flight slot and must be called once the request
+// has completed.
+func (l *Limiter) Wait(ctx context.Context) (func(), error) {
+	start := time.Now()
+	l.mu.Lock()
+	l.stats.Waiting++
+	// the slot is released to the channel it was acquired from
+	slots := l.slots
+	l.mu.Unlock()
+
+	err := l.waitToken(ctx)
+	if err == nil && slots != nil {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+
+	waited := time.Since(start)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stats.Waiting--
+	if err != nil {
+		return nil, err
+	}
+	l.stats.Requests++
+	l.stats.InFlight++
+	// anything over a millisecond means the request was held back
+	if waited > time.Millisecond {
+		l.stats.Throttled++
+		l.stats.TotalWait += waited
+		if waited > l.stats.MaxWait {
+			l.stats.MaxWait = waited
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if slots != nil {
+				<-slots
+			}
+			l.mu.Lock()
+			l.stats.InFlight--
+			l.mu.Unlock()
+		})
+	}, nil
+}
+
+func (l *Limiter) waitToken(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.rate <= 0 {
+			l.mu.Unlock()
+			return nil
+		}
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > float64(l.burst) {
+			l.tokens = float64(l.burst)
+		}
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Middleware returns a DoerMiddleware sending every request through l.
+func (l *Limiter) Middleware() DoerMiddleware {
+	return func(next HttpRequestDoer) HttpRequestDoer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			release, err := l.Wait(req.Context())
+			if err != nil {
+				return nil, err
+			}
+			rsp, err := next.Do(req)
+			if err != nil || rsp.Body == nil {
+				release()
+				return rsp, err
+			}
+			// the slot is held until the body has been consumed
+			rsp.Body = &releasingBody{ReadCloser: rsp.Body, release: release}
+			return rsp, nil
+		})
+	}
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// WithLimiter sends every request through l, which may be shared with other
+// clients. It cannot be combined with WithRateLimit or WithMaxInFlight, nor
+// given twice.
+func WithLimiter(l *Limiter) ClientOption {
+	return func(c *Client) error {
+		if c.limiter != nil {
+			return errSharedLimiter
+		}
+		if err := WithDoerMiddleware(l.Middleware())(c); err != nil {
+			return err
+		}
+		c.limiter, c.sharedLimiter = l, true
+		return nil
+	}
+}
+
+// WithRateLimit allows at most rps requests per second with bursts of up to
+// burst requests. It may be combined with WithMaxInFlight, the statistics
+// being available through Client.Limiter.
+func WithRateLimit(rps float64, burst int) ClientOption {
+	return func(c *Client) error {
+		l, err := c.ownLimiter()
+		if err != nil {
+			return err
+		}
+		l.setRate(rps, burst)
+		return nil
+	}
+}
+
+// WithMaxInFlight allows at most n concurrent requests.
+func WithMaxInFlight(n int) ClientOption {
+	return func(c *Client) error {
+		l, err := c.ownLimiter()
+		if err != nil {
+			return err
+		}
+		l.setMaxInFlight(n)
+		return nil
+	}
+}
+
+// ownLimiter returns the limiter of the client, created on first use. Its
+// middleware only runs once the client is built, so it can still be
+// configured; a limiter given through WithLimiter cannot.
+func (c *Client) ownLimiter() (*Limiter, error) {
+	if c.sharedLimiter {
+		return nil, errSharedLimiter
+	}
+	if c.limiter == nil {
+		c.limiter = NewLimiter(0, 0, 0)
+		if err := WithDoerMiddleware(c.limiter.Middleware())(c); err != nil {
+			return nil, err
+		}
+	}
+	return c.limiter, nil
+}
+
+// Limiter returns the limiter configured through WithRateLimit,
+// WithMaxInFlight or WithLimiter, or nil.
+func (c *Client) Limiter() *Limiter {
+	return c.limiter
+}
@@ -0,0 +1,235 @@
+package runx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		rps      float64
+		burst    int
+		requests int
+		min, max time.Duration
+	}{
+		{"unlimited", 0, 0, 20, 0, 20 * time.Millisecond},
+		{"within burst", 10, 5, 5, 0, 20 * time.Millisecond},
+		// the burst is spent, then one request every 20ms
+		{"beyond burst", 50, 2, 6, 80 * time.Millisecond, 200 * time.Millisecond},
+		{"burst of one", 50, 0, 4, 60 * time.Millisecond, 200 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLimiter(tt.rps, tt.burst, 0)
+			start := time.Now()
+			for range tt.requests {
+				release, err := l.Wait(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				release()
+			}
+			elapsed := time.Since(start)
+			if elapsed < tt.min || elapsed > tt.max {
+				t.Errorf("%d requests took %v, want between %v and %v", tt.requests, elapsed, tt.min, tt.max)
+			}
+			stats := l.Stats()
+			if stats.Requests != int64(tt.requests) || stats.InFlight != 0 || stats.Waiting != 0 {
+				t.Errorf("Stats() = %+v", stats)
+			}
+			if tt.min > 0 && stats.Throttled == 0 {
+				t.Errorf("Stats().Throttled = 0, want throttled requests")
+			}
+		})
+	}
+}
+
+func TestLimiterCanceled(t *testing.T) {
+	tests := []struct {
+		name    string
+		limiter *Limiter
+	}{
+		{"rate", NewLimiter(1, 1, 0)},
+		{"max in flight", NewLimiter(0, 0, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// takes the only token or slot
+			if _, err := tt.limiter.Wait(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			if _, err := tt.limiter.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+			}
+			if stats := tt.limiter.Stats(); stats.Waiting != 0 || stats.Requests != 1 {
+				t.Errorf("Stats() = %+v", stats)
+			}
+		})
+	}
+}
+
+// concurrencyServer blocks every request until release is closed, and
+// records the largest number of requests it handled at once.
+type concurrencyServer struct {
+	*httptest.Server
+	release  chan struct{}
+	current  atomic.Int32
+	max      atomic.Int32
+	requests atomic.Int32
+}
+
+func newConcurrencyServer(t *testing.T) *concurrencyServer {
+	s := &concurrencyServer{release: make(chan struct{})}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := s.current.Add(1)
+		defer s.current.Add(-1)
+		s.requests.Add(1)
+		for {
+			m := s.max.Load()
+			if n <= m || s.max.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		<-s.release
+		_, _ = io.WriteString(w, `{}`)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestLimiterMaxInFlight(t *testing.T) {
+	tests := []struct {
+		name    string
+		clients int
+		opts    func(shared *Limiter) []ClientOption
+	}{
+		{"own limiter", 1, func(*Limiter) []ClientOption { return []ClientOption{WithMaxInFlight(2)} }},
+		{"combined with rate", 1, func(*Limiter) []ClientOption {
+			return []ClientOption{WithRateLimit(1000, 100), WithMaxInFlight(2)}
+		}},
+		{"shared limiter", 3, func(l *Limiter) []ClientOption { return []ClientOption{WithLimiter(l)} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newConcurrencyServer(t)
+			shared := NewLimiter(0, 0, 2)
+			var clients []*Client
+			for range tt.clients {
+				c, err := NewClient(srv.URL, "token", tt.opts(shared)...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				clients = append(clients, c)
+			}
+			limiter := clients[0].Limiter()
+
+			const requests = 8
+			var wg sync.WaitGroup
+			for i := range requests {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					rsp, err := getApp(clients[i%len(clients)])
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					_, _ = io.Copy(io.Discard, rsp.Body)
+					rsp.Body.Close()
+				}()
+			}
+			// waits for two requests to reach the server and the others to queue
+			deadline := time.Now().Add(time.Second)
+			for time.Now().Before(deadline) && (srv.current.Load() < 2 || limiter.Stats().Waiting < requests-2) {
+				time.Sleep(time.Millisecond)
+			}
+			if n := srv.current.Load(); n != 2 {
+				t.Errorf("%d requests in flight, want 2", n)
+			}
+			if stats := limiter.Stats(); stats.InFlight != 2 || stats.Waiting != requests-2 {
+				t.Errorf("Stats() = %+v, want 2 in flight and %d waiting", stats, requests-2)
+			}
+			close(srv.release)
+			wg.Wait()
+
+			if n := srv.max.Load(); n != 2 {
+				t.Errorf("at most %d requests in flight, want 2", n)
+			}
+			if n := srv.requests.Load(); n != requests {
+				t.Errorf("%d requests handled, want %d", n, requests)
+			}
+			if stats := limiter.Stats(); stats.InFlight != 0 || stats.Requests != requests {
+				t.Errorf("Stats() = %+v after the requests", stats)
+			}
+		})
+	}
+}
+
+func TestLimiterReleaseOnce(t *testing.T) {
+	l := NewLimiter(0, 0, 1)
+	release, err := l.Wait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	release()
+	if stats := l.Stats(); stats.InFlight != 0 {
+		t.Fatalf("Stats().InFlight = %d after a double release, want 0", stats.InFlight)
+	}
+	// a double release must not free a slot held by another request
+	if _, err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Wait(ctx); err == nil {
+		t.Fatal("Wait() acquired a second slot of a limiter of one")
+	}
+}
+
+func TestLimiterOptions(t *testing.T) {
+	shared := NewLimiter(5, 1, 1)
+	tests := []struct {
+		name    string
+		opts    []ClientOption
+		wantErr bool
+	}{
+		{"rate then max in flight", []ClientOption{WithRateLimit(5, 1), WithMaxInFlight(1)}, false},
+		{"shared", []ClientOption{WithLimiter(shared)}, false},
+		{"shared then rate", []ClientOption{WithLimiter(shared), WithRateLimit(100, 10)}, true},
+		{"shared then max in flight", []ClientOption{WithLimiter(shared), WithMaxInFlight(10)}, true},
+		{"rate then shared", []ClientOption{WithRateLimit(100, 10), WithLimiter(shared)}, true},
+		{"shared twice", []ClientOption{WithLimiter(shared), WithLimiter(NewLimiter(1, 1, 1))}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewClient("https://api.example.com", "token", tt.opts...)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("NewClient() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Limiter() == nil {
+				t.Fatal("Limiter() = nil")
+			}
+		})
+	}
+	// the shared limiter was not reconfigured by the rejected options
+	if shared.rate != 5 || shared.burst != 1 || cap(shared.slots) != 1 {
+		t.Errorf("shared limiter reconfigured: rate %v, burst %d, %d slots", shared.rate, shared.burst, cap(shared.slots))
+	}
+}
@@ -0,0 +1,239 @@
+package runx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// IdempotencyKeyHeader is the header carrying the idempotency key of a request.
+// POST requests are only retried when it is set.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RetryPolicy configures how failed requests are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	MaxAttempts int
+
+	// MaxElapsed bounds the total time spent on a request including waits.
+	// Zero disables the budget.
+	MaxElapsed time.Duration
+
+	// InitialBackoff is the wait before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the wait between two attempts.
+	MaxBackoff time.Duration
+
+	// Multiplier grows the backoff after every attempt.
+	Multiplier float64
+
+	// Jitter randomizes each wait by up to this fraction, between 0 and 1.
+	Jitter float64
+
+	// RetryableStatus lists the HTTP status codes worth retrying.
+	RetryableStatus []int
+}
+
+// DefaultRetryPolicy returns the policy used by WithRetry.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		MaxElapsed:     time.Minute,
+		InitialBackoff: 250 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryableStatus: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// WithRetry retries failed requests following DefaultRetryPolicy.
+func WithRetry() ClientOption {
+	return WithRetryPolicy(DefaultRetryPolicy())
+}
+
+// WithRetryPolicy retries failed requests following policy. GET, PUT and
+// DELETE requests are retried on transport errors and on the retryable
+// status codes, other methods such as POST /app or POST /register only when
+// an idempotency key is set, see WithIdempotencyKey.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return WithDoerMiddleware(func(next HttpRequestDoer) HttpRequestDoer {
+		return &retryDoer{next: next, policy: policy}
+	})
+}
+
+// WithIdempotencyKey returns a RequestEditorFn setting the idempotency key of
+// a request, which makes it safe to retry a CreateApp or Register call.
+func WithIdempotencyKey(key string) RequestEditorFn {
+	return func(ctx context.Context, req *http.Request) error {
+		req.Header.Set(IdempotencyKeyHeader, key)
+		return nil
+	}
+}
+
+type retryAttemptKey struct{}
+
+// RetryAttempt returns the attempt number, starting at 1, of a request sent
+// by WithRetryPolicy. It is meant for Doer middlewares registered after the
+// retry policy; it returns 1 when the request is not being retried.
+func RetryAttempt(ctx context.Context) int {
+	if n, ok := ctx.Value(retryAttemptKey{}).(int); ok {
+		return n
+	}
+	return 1
+}
+
+type retryDoer struct {
+	next   HttpRequestDoer
+	policy RetryPolicy
+}
+
+func (d *retryDoer) Do(req *http.Request) (*http.Response, error) {
+	if !d.retryable(req) || d.policy.MaxAttempts <= 1 {
+		return d.next.Do(req)
+	}
+	if err := rewindable(req); err != nil {
+		return nil, err
+	}
+
+	ctx := req.Context()
+	start := time.Now()
+	backoff := d.policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		attemptReq := req.Clone(context.WithValue(ctx, retryAttemptKey{}, attempt))
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq.Body = body
+		}
+
+		rsp, err := d.next.Do(attemptReq)
+		if attempt >= d.policy.MaxAttempts || !d.shouldRetry(ctx, rsp, err) {
+			return rsp, err
+		}
+
+		wait := d.jitter(backoff)
+		if after, ok := retryAfter(rsp); ok {
+			wait = after
+		}
+		if d.policy.MaxElapsed > 0 && time.Since(start)+wait > d.policy.MaxElapsed {
+			return rsp, err
+		}
+		if rsp != nil {
+			_, _ = io.Copy(io.Discard, rsp.Body)
+			_ = rsp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		backoff = time.Duration(float64(backoff) * d.policy.Multiplier)
+		if d.policy.MaxBackoff > 0 && backoff > d.policy.MaxBackoff {
+			backoff = d.policy.MaxBackoff
+		}
+	}
+}
+
+// retryable reports whether req may be sent more than once.
+func (d *retryDoer) retryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get(IdempotencyKeyHeader) != ""
+}
+
+func (d *retryDoer) shouldRetry(ctx context.Context, rsp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	status := 0
+	switch {
+	case err != nil:
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			// transport errors such as a connection reset
+			return true
+		}
+		status = apiErr.StatusCode
+	case rsp != nil:
+		status = rsp.StatusCode
+	}
+	for _, s := range d.policy.RetryableStatus {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *retryDoer) jitter(wait time.Duration) time.Duration {
+	if d.policy.Jitter <= 0 || wait <= 0 {
+		return wait
+	}
+	delta := d.policy.Jitter * float64(wait)
+	return time.Duration(float64(wait) - delta + rand.Float64()*2*delta)
+}
+
+// rewindable makes sure the body of req can be replayed for every attempt.
+// Bodies built from bytes or strings, as done by NewCreateAppRequest and
+// NewUpdateAppRequest, already are; any other reader given to the *WithBody
+// builders is buffered in memory.
+func rewindable(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return nil
+	}
+	buf, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return err
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf)), nil
+	}
+	req.Body, _ = req.GetBody()
+	req.ContentLength = int64(len(buf))
+	return nil
+}
+
+// retryAfter parses the Retry-After header of rsp, given either in seconds
+// or as an HTTP date.
+func retryAfter(rsp *http.Response) (time.Duration, bool) {
+	if rsp == nil {
+		return 0, false
+	}
+	value := rsp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		wait := time.Until(at)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
@@ -0,0 +1,356 @@
+package runx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// attemptServer answers the requests with statuses in turn, repeating the
+// last one, and records the body of every attempt.
+type attemptServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	header   http.Header
+	bodies   []string
+}
+
+func newAttemptServer(t *testing.T, header http.Header, statuses ...int) *attemptServer {
+	s := &attemptServer{statuses: statuses, header: header}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		status := s.statuses[min(len(s.bodies), len(s.statuses)-1)]
+		s.bodies = append(s.bodies, string(body))
+		s.mu.Unlock()
+		for k, v := range s.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, `{}`)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *attemptServer) attempts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.bodies)
+}
+
+func testPolicy() RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.InitialBackoff = time.Millisecond
+	p.MaxBackoff = 5 * time.Millisecond
+	p.Jitter = 0
+	return p
+}
+
+func TestRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       func(*RetryPolicy)
+		header       http.Header
+		statuses     []int
+		call         func(*Client) (*http.Response, error)
+		wantAttempts int
+		wantStatus   int
+		minElapsed   time.Duration
+		maxElapsed   time.Duration
+	}{
+		{
+			name:         "success",
+			statuses:     []int{200},
+			call:         getApp,
+			wantAttempts: 1,
+			wantStatus:   200,
+		},
+		{
+			name:         "recovers after retryable status",
+			statuses:     []int{503, 502, 200},
+			call:         getApp,
+			wantAttempts: 3,
+			wantStatus:   200,
+		},
+		{
+			name:         "gives up after max attempts",
+			statuses:     []int{500},
+			call:         getApp,
+			wantAttempts: 4,
+			wantStatus:   500,
+		},
+		{
+			name:         "does not retry client errors",
+			statuses:     []int{404},
+			call:         getApp,
+			wantAttempts: 1,
+			wantStatus:   404,
+		},
+		{
+			name: "exponential backoff",
+			policy: func(p *RetryPolicy) {
+				p.InitialBackoff = 20 * time.Millisecond
+				p.MaxBackoff = time.Second
+			},
+			statuses:     []int{503, 503, 200},
+			call:         getApp,
+			wantAttempts: 3,
+			wantStatus:   200,
+			// 20ms then 40ms
+			minElapsed: 60 * time.Millisecond,
+		},
+		{
+			name: "backoff capped by max backoff",
+			policy: func(p *RetryPolicy) {
+				p.InitialBackoff = 20 * time.Millisecond
+				p.MaxBackoff = 20 * time.Millisecond
+				p.Multiplier = 10
+			},
+			statuses:     []int{503, 503, 200},
+			call:         getApp,
+			wantAttempts: 3,
+			wantStatus:   200,
+			minElapsed:   40 * time.Millisecond,
+			maxElapsed:   150 * time.Millisecond,
+		},
+		{
+			name:         "retry-after overrides backoff",
+			header:       http.Header{"Retry-After": {"1"}},
+			statuses:     []int{429, 200},
+			call:         getApp,
+			wantAttempts: 2,
+			wantStatus:   200,
+			minElapsed:   time.Second,
+		},
+		{
+			name:         "retry-after beyond max elapsed",
+			policy:       func(p *RetryPolicy) { p.MaxElapsed = 500 * time.Millisecond },
+			header:       http.Header{"Retry-After": {"2"}},
+			statuses:     []int{429, 200},
+			call:         getApp,
+			wantAttempts: 1,
+			wantStatus:   429,
+			maxElapsed:   500 * time.Millisecond,
+		},
+		{
+			name: "backoff beyond max elapsed",
+			policy: func(p *RetryPolicy) {
+				p.InitialBackoff = 40 * time.Millisecond
+				p.MaxBackoff = time.Second
+				p.MaxElapsed = 100 * time.Millisecond
+			},
+			statuses: []int{503},
+			call:     getApp,
+			// waits 40ms, then 80ms would exceed the budget
+			wantAttempts: 2,
+			wantStatus:   503,
+			maxElapsed:   100 * time.Millisecond,
+		},
+		{
+			name:         "post without idempotency key",
+			statuses:     []int{503, 200},
+			call:         createApp(),
+			wantAttempts: 1,
+			wantStatus:   503,
+		},
+		{
+			name:         "post with idempotency key",
+			statuses:     []int{503, 500, 200},
+			call:         createApp(WithIdempotencyKey("key-1")),
+			wantAttempts: 3,
+			wantStatus:   200,
+		},
+		{
+			name:         "delete is idempotent",
+			statuses:     []int{502, 200},
+			call:         func(c *Client) (*http.Response, error) { return c.DeleteApp(context.Background(), "app-1") },
+			wantAttempts: 2,
+			wantStatus:   200,
+		},
+		{
+			name:         "single attempt policy",
+			policy:       func(p *RetryPolicy) { p.MaxAttempts = 1 },
+			statuses:     []int{503, 200},
+			call:         getApp,
+			wantAttempts: 1,
+			wantStatus:   503,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newAttemptServer(t, tt.header, tt.statuses...)
+			policy := testPolicy()
+			if tt.policy != nil {
+				tt.policy(&policy)
+			}
+			client, err := NewClient(srv.URL, "token", WithRetryPolicy(policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+			rsp, err := tt.call(client)
+			elapsed := time.Since(start)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rsp.Body.Close()
+			if rsp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rsp.StatusCode, tt.wantStatus)
+			}
+			if n := srv.attempts(); n != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", n, tt.wantAttempts)
+			}
+			if elapsed < tt.minElapsed {
+				t.Errorf("elapsed = %v, want at least %v", elapsed, tt.minElapsed)
+			}
+			if tt.maxElapsed > 0 && elapsed > tt.maxElapsed {
+				t.Errorf("elapsed = %v, want at most %v", elapsed, tt.maxElapsed)
+			}
+		})
+	}
+}
+
+func getApp(c *Client) (*http.Response, error) {
+	return c.GetApp(context.Background(), "app-1")
+}
+
+func createApp(editors ...RequestEditorFn) func(*Client) (*http.Response, error) {
+	return func(c *Client) (*http.Response, error) {
+		body := CreateAppJSONRequestBody{Apps: []AppRequest{}}
+		return c.CreateApp(context.Background(), body, editors...)
+	}
+}
+
+func TestRetryRewindsBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body func() io.Reader
+	}{
+		{"bytes", func() io.Reader { return strings.NewReader(`{"apps":[]}`) }},
+		// hides the concrete type, so that the request has no GetBody
+		{"stream", func() io.Reader { return io.MultiReader(strings.NewReader(`{"apps":`), strings.NewReader(`[]}`)) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newAttemptServer(t, nil, 503, 503, 200)
+			client, err := NewClient(srv.URL, "token", WithRetryPolicy(testPolicy()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rsp, err := client.CreateAppWithBody(context.Background(), "application/json", tt.body(), WithIdempotencyKey("key-1"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			rsp.Body.Close()
+			if rsp.StatusCode != 200 {
+				t.Fatalf("status = %d, want 200", rsp.StatusCode)
+			}
+			if len(srv.bodies) != 3 {
+				t.Fatalf("attempts = %d, want 3", len(srv.bodies))
+			}
+			for i, body := range srv.bodies {
+				if body != `{"apps":[]}` {
+					t.Errorf("attempt %d body = %q", i+1, body)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryCanceled(t *testing.T) {
+	srv := newAttemptServer(t, http.Header{"Retry-After": {"10"}}, 503)
+	client, err := NewClient(srv.URL, "token", WithRetryPolicy(testPolicy()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetApp(ctx, "app-1"); err != context.DeadlineExceeded {
+		t.Fatalf("GetApp() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if n := srv.attempts(); n != 1 {
+		t.Errorf("attempts = %d, want 1", n)
+	}
+}
+
+func TestRetryAttempt(t *testing.T) {
+	var mu sync.Mutex
+	var seen []int
+	srv := newAttemptServer(t, nil, 503, 503, 200)
+	client, err := NewClient(srv.URL, "token",
+		WithRetryPolicy(testPolicy()),
+		WithDoerMiddleware(func(next HttpRequestDoer) HttpRequestDoer {
+			return DoerFunc(func(req *http.Request) (*http.Response, error) {
+				mu.Lock()
+				seen = append(seen, RetryAttempt(req.Context()))
+				mu.Unlock()
+				return next.Do(req)
+			})
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp, err := getApp(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+	if len(seen) != 3 || seen[0] != 1 || seen[1] != 2 || seen[2] != 3 {
+		t.Errorf("attempts seen by the middleware = %v, want [1 2 3]", seen)
+	}
+	if n := RetryAttempt(context.Background()); n != 1 {
+		t.Errorf("RetryAttempt() = %d outside of a retry, want 1", n)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"missing", "", 0, false},
+		{"seconds", "3", 3 * time.Second, true},
+		{"zero", "0", 0, true},
+		{"negative", "-1", 0, false},
+		{"past date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+		{"invalid", "soon", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rsp := &http.Response{Header: http.Header{}}
+			if tt.value != "" {
+				rsp.Header.Set("Retry-After", tt.value)
+			}
+			got, ok := retryAfter(rsp)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("retryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	rsp := &http.Response{Header: http.Header{"Retry-After": {time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)}}}
+	if got, ok := retryAfter(rsp); !ok || got <= 58*time.Second || got > time.Minute {
+		t.Errorf("retryAfter(date in a minute) = %v, %v", got, ok)
+	}
+}
+
+func TestRetryJitter(t *testing.T) {
+	d := &retryDoer{policy: RetryPolicy{Jitter: 0.2}}
+	for range 100 {
+		if got := d.jitter(time.Second); got < 800*time.Millisecond || got > 1200*time.Millisecond {
+			t.Fatalf("jitter(1s) = %v, want within 20%%", got)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package runx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MutableCredentials is a CredentialProvider whose token can be replaced
+// while clients are using it, as done by RotateAPIKey.
+type MutableCredentials struct {
+	mu    sync.RWMutex
+	token string
+}
+
+// NewMutableCredentials returns a MutableCredentials holding token.
+func NewMutableCredentials(token string) *MutableCredentials {
+	return &MutableCredentials{token: token}
+}
+
+// Token implements CredentialProvider.
+func (m *MutableCredentials) Token(context.Context) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.token == "" {
+		return "", ErrNoCredentials
+	}
+	return m.token, nil
+}
+
+// Set replaces the token used by the next requests.
+func (m *MutableCredentials) Set(token string) {
+	m.mu.Lock()
+	m.token = token
+	m.mu.Unlock()
+}
+
+// CredentialStore persists the API key so that the next processes use it.
+type CredentialStore interface {
+	StoreAPIKey(key string) error
+}
+
+// ConfigStore returns a CredentialStore updating the key of the current
+// profile of the configuration file at path, or at DefaultConfigPath when
+// path is empty, see ProfileStore.
+func ConfigStore(path string) CredentialStore {
+	return ProfileStore(path, "")
+}
+
+// ProfileStore returns a CredentialStore updating the key of the named
+// profile of the configuration file at path, or at DefaultConfigPath when
+// path is empty. An empty name selects the profile named by RUNX_PROFILE,
+// then the current context. The api_key entry is rewritten, keeping the other
+// entries and the comments, unless the profile reads its key from a file,
+// which is rewritten instead. Both are replaced atomically.
+func ProfileStore(path, name string) CredentialStore {
+	return profileStore{path: path, name: name}
+}
+
+type profileStore struct {
+	path string
+	name string
+}
+
+func (s profileStore) StoreAPIKey(key string) error {
+	var keyFile string
+	err := updateConfig(s.path, func(cfg *Config, root *yaml.Node) error {
+		name := s.name
+		if name == "" {
+			name = os.Getenv(EnvProfile)
+		}
+		p, err := cfg.Profile(name)
+		if err != nil {
+			return err
+		}
+		switch {
+		case p.ApiKey == "" && p.ApiKeyFile != "":
+			keyFile = expandHome(p.ApiKeyFile)
+			return nil
+		case p.ApiKey == "" && p.ApiKeyEnv != "":
+			return fmt.Errorf("runx: profile %s reads its key from $%s, which cannot be updated", p.Name, p.ApiKeyEnv)
+		case len(cfg.Profiles) == 0:
+			setMappingValue(root, "api_key", key)
+			return nil
+		}
+		profiles := mappingValue(root, "profiles")
+		if profiles == nil || profiles.Kind != yaml.MappingNode {
+			return fmt.Errorf("runx: profiles is not a mapping")
+		}
+		node := mappingValue(profiles, p.Name)
+		if node == nil || node.Kind != yaml.MappingNode {
+			return fmt.Errorf("runx: profile %s is not a mapping", p.Name)
+		}
+		setMappingValue(node, "api_key", key)
+		return nil
+	})
+	if err != nil || keyFile == "" {
+		return err
+	}
+	return writeFileAtomic(keyFile, []byte(key+"\n"), 0o600)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path, so readers see either the old or the new content.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("runx: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("runx: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("runx: %w", err)
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return fmt.Errorf("runx: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("runx: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("runx: %w", err)
+	}
+	return nil
+}
+
+// Fingerprint identifies an API key without revealing it: the first 16 hex
+// digits of its SHA-256 digest, prefixed with "sha256:".
+func Fingerprint(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:])[:16]
+}
+
+// KeyRotation reports the outcome of RotateAPIKey.
+type KeyRotation struct {
+	// NewKey is the generated API key.
+	NewKey string
+
+	// OldFingerprint and NewFingerprint identify the previous and the new keys.
+	OldFingerprint string
+	NewFingerprint string
+
+	// OldKeyRevoked is true when a request made with the previous key was
+	// rejected with a 401 after the rotation.
+	OldKeyRevoked bool
+
+	// RotatedAt is the time the new key was generated.
+	RotatedAt time.Time
+}
+
+// RotateOption configures RotateAPIKey.
+type RotateOption func(*rotateConfig)
+
+type rotateConfig struct {
+	store CredentialStore
+}
+
+// WithCredentialStore persists the new key in store once it is verified.
+func WithCredentialStore(store CredentialStore) RotateOption {
+	return func(c *rotateConfig) {
+		c.store = store
+	}
+}
+
+// RotateAPIKey replaces the API key of the account without interrupting the
+// users of client, which must be authenticated through creds, see
+// NewClientWithCredentials. It generates a new key, verifies it by calling Me
+// with it, saves it in the credential store if any, swaps it into creds and
+// finally checks whether the previous key was revoked.
+//
+// When an error occurs after the key was generated, the returned KeyRotation
+// still holds NewKey so that it is not lost.
+func RotateAPIKey(ctx context.Context, client *Client, creds *MutableCredentials, opts ...RotateOption) (*KeyRotation, error) {
+	var cfg rotateConfig
+	for _, o := range opts {
+		o(&cfg)
+	}
+	oldKey, err := creds.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp, err := (&ClientWithResponses{client}).GenerateApiKeyWithResponse(ctx)
+	if err == nil {
+		err = CheckResponse("GenerateApiKey", rsp.HTTPResponse, rsp.Body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("runx: rotate API key: %w", err)
+	}
+	if rsp.JSON200 == nil || rsp.JSON200.ApiKey == nil || *rsp.JSON200.ApiKey == "" {
+		return nil, fmt.Errorf("runx: rotate API key: no API key returned")
+	}
+	rotation := &KeyRotation{
+		NewKey:         *rsp.JSON200.ApiKey,
+		OldFingerprint: Fingerprint(oldKey),
+		NewFingerprint: Fingerprint(*rsp.JSON200.ApiKey),
+		RotatedAt:      time.Now(),
+	}
+
+	if err := checkKey(ctx, client, rotation.NewKey); err != nil {
+		return rotation, fmt.Errorf("runx: rotate API key: verify new key %s: %w", rotation.NewFingerprint, err)
+	}
+	if cfg.store != nil {
+		if err := cfg.store.StoreAPIKey(rotation.NewKey); err != nil {
+			return rotation, fmt.Errorf("runx: rotate API key: store new key %s: %w", rotation.NewFingerprint, err)
+		}
+	}
+	creds.Set(rotation.NewKey)
+
+	var apiErr *APIError
+	if err := checkKey(ctx, client, oldKey); errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+		rotation.OldKeyRevoked = true
+	}
+	return rotation, nil
+}
+
+// checkKey calls Me through client with key as the bearer token.
+func checkKey(ctx context.Context, client *Client, key string) error {
+	withKey := func(ctx context.Context, req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+key)
+		return nil
+	}
+	rsp, err := (&ClientWithResponses{client}).MeWithResponse(ctx, withKey)
+	if err == nil {
+		err = CheckResponse("Me", rsp.HTTPResponse, rsp.Body)
+	}
+	if err == nil && (rsp.JSON200 == nil || rsp.JSON200.User == nil) {
+		err = fmt.Errorf("no user returned")
+	}
+	return err
+}
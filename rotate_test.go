@@ -0,0 +1,227 @@
+package runx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	runx "github.com/run-x-app/runx-go"
+	"github.com/run-x-app/runx-go/runxtest"
+)
+
+// storeFunc adapts a function to a runx.CredentialStore.
+type storeFunc func(key string) error
+
+func (f storeFunc) StoreAPIKey(key string) error {
+	return f(key)
+}
+
+func newRotationClient(t *testing.T, srv *runxtest.Server) (*runx.Client, *runx.MutableCredentials) {
+	t.Helper()
+	creds := runx.NewMutableCredentials(runxtest.DefaultAPIKey)
+	client, err := runx.NewClientWithCredentials(srv.URL, creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, creds
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	client, creds := newRotationClient(t, srv)
+
+	// the key is persisted while the client still uses the old one, before
+	// anything could fail with it
+	var stored []string
+	store := storeFunc(func(key string) error {
+		if token, _ := creds.Token(ctx); token != runxtest.DefaultAPIKey {
+			t.Errorf("credentials hold %q when the key is stored, want the old key", token)
+		}
+		stored = append(stored, key)
+		return nil
+	})
+	rotation, err := runx.RotateAPIKey(ctx, client, creds, runx.WithCredentialStore(store))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0] != rotation.NewKey || rotation.NewKey == runxtest.DefaultAPIKey {
+		t.Errorf("stored %q, want the new key %q", stored, rotation.NewKey)
+	}
+	if rotation.OldFingerprint != runx.Fingerprint(runxtest.DefaultAPIKey) || rotation.NewFingerprint != runx.Fingerprint(rotation.NewKey) {
+		t.Errorf("fingerprints %s and %s", rotation.OldFingerprint, rotation.NewFingerprint)
+	}
+	if !rotation.OldKeyRevoked || rotation.RotatedAt.IsZero() {
+		t.Errorf("RotateAPIKey() = %+v, want the old key revoked", rotation)
+	}
+	if token, _ := creds.Token(ctx); token != rotation.NewKey {
+		t.Errorf("credentials hold %q, want the new key", token)
+	}
+	if rsp, err := (&runx.ClientWithResponses{ClientInterface: client}).MeWithResponse(ctx); err != nil || rsp.StatusCode() != http.StatusOK {
+		t.Errorf("Me() after the rotation = %v, %v", rsp, err)
+	}
+}
+
+func TestRotateAPIKeyFailures(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("store failure", func(t *testing.T) {
+		srv := runxtest.NewServer()
+		defer srv.Close()
+		client, creds := newRotationClient(t, srv)
+		failing := storeFunc(func(string) error { return errors.New("disk full") })
+		rotation, err := runx.RotateAPIKey(ctx, client, creds, runx.WithCredentialStore(failing))
+		if err == nil || !strings.Contains(err.Error(), "store new key") || !strings.Contains(err.Error(), "disk full") {
+			t.Fatalf("RotateAPIKey() error = %v", err)
+		}
+		// the key is returned so that it is not lost, and not used yet
+		if rotation == nil || rotation.NewKey == "" {
+			t.Fatalf("RotateAPIKey() = %+v, want the new key", rotation)
+		}
+		if token, _ := creds.Token(ctx); token != runxtest.DefaultAPIKey {
+			t.Errorf("credentials hold %q, want the old key", token)
+		}
+	})
+
+	t.Run("unverified key", func(t *testing.T) {
+		srv := runxtest.NewServer()
+		defer srv.Close()
+		client, creds := newRotationClient(t, srv)
+		srv.InjectFault(runxtest.Fault{Operation: "Me", StatusCode: http.StatusInternalServerError, Times: 1})
+		store := storeFunc(func(string) error {
+			t.Error("unverified key stored")
+			return nil
+		})
+		rotation, err := runx.RotateAPIKey(ctx, client, creds, runx.WithCredentialStore(store))
+		if !errors.Is(err, runx.ErrServer) || !strings.Contains(err.Error(), "verify new key") || rotation == nil || rotation.NewKey == "" {
+			t.Errorf("RotateAPIKey() = %+v, %v", rotation, err)
+		}
+	})
+
+	t.Run("generation failure", func(t *testing.T) {
+		srv := runxtest.NewServer()
+		defer srv.Close()
+		client, creds := newRotationClient(t, srv)
+		srv.InjectFault(runxtest.Fault{Operation: "GenerateApiKey", StatusCode: http.StatusInternalServerError, Times: 1})
+		if rotation, err := runx.RotateAPIKey(ctx, client, creds); !errors.Is(err, runx.ErrServer) || rotation != nil {
+			t.Errorf("RotateAPIKey() = %+v, %v", rotation, err)
+		}
+	})
+}
+
+func TestProfileStore(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config")
+	keyFile := filepath.Join(dir, "key")
+	writeFile(t, config, `# runx configuration
+current-context: production
+profiles:
+  production:
+    server: https://api.run-x.app
+    api_key: old-key # rotated monthly
+  file:
+    api_key_file: `+keyFile+`
+  env:
+    api_key_env: RUNX_TEST_KEY
+`)
+	writeFile(t, keyFile, "old-file-key\n")
+
+	if err := runx.ProfileStore(config, "").StoreAPIKey("new-key"); err != nil {
+		t.Fatal(err)
+	}
+	got := readFile(t, config)
+	for _, want := range []string{"# runx configuration", "api_key: new-key # rotated monthly", "server: https://api.run-x.app", "api_key_env: RUNX_TEST_KEY"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("config =\n%s\nwant %q", got, want)
+		}
+	}
+	if p, err := runx.LoadProfile(config, "production"); err != nil || p.ApiKey != "new-key" {
+		t.Errorf("LoadProfile() = %+v, %v", p, err)
+	}
+
+	if err := runx.ProfileStore(config, "file").StoreAPIKey("new-file-key"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, keyFile); got != "new-file-key\n" {
+		t.Errorf("key file = %q", got)
+	}
+	if strings.Contains(readFile(t, config), "new-file-key") {
+		t.Error("key of the file profile written to the config")
+	}
+
+	// both files stay readable by the owner only
+	for _, path := range []string{config, keyFile} {
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+			t.Errorf("%s mode = %v, %v, want 0600", path, info.Mode(), err)
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("directory holds %d files, want no temporary file left", len(entries))
+	}
+
+	before := readFile(t, config)
+	if err := runx.ProfileStore(config, "env").StoreAPIKey("key"); err == nil || !strings.Contains(err.Error(), "$RUNX_TEST_KEY") {
+		t.Errorf("StoreAPIKey() error = %v, want the environment refused", err)
+	}
+	if err := runx.ProfileStore(config, "staging").StoreAPIKey("key"); err == nil {
+		t.Error("StoreAPIKey() of an unknown profile succeeded")
+	}
+	if readFile(t, config) != before {
+		t.Error("config changed by a failed update")
+	}
+}
+
+func TestProfileStoreFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+
+	// the temporary file cannot be created next to a key file whose name
+	// is already close to the limit, even by root
+	keyFile := filepath.Join(dir, strings.Repeat("k", 250))
+	config := filepath.Join(dir, "config")
+	writeFile(t, config, "profiles:\n  default:\n    api_key_file: "+keyFile+"\n")
+	writeFile(t, keyFile, "old-key\n")
+	if err := runx.ProfileStore(config, "default").StoreAPIKey("new-key"); err == nil {
+		t.Fatal("StoreAPIKey() succeeded")
+	}
+	if got := readFile(t, keyFile); got != "old-key\n" {
+		t.Errorf("key file = %q after a failed write, want the old key", got)
+	}
+
+	// the rename over a directory fails once the temporary file is written
+	keyDir := filepath.Join(dir, "key")
+	if err := os.MkdirAll(filepath.Join(keyDir, "old"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, config, "profiles:\n  default:\n    api_key_file: "+keyDir+"\n")
+	if err := runx.ProfileStore(config, "default").StoreAPIKey("new-key"); err == nil {
+		t.Fatal("StoreAPIKey() succeeded")
+	}
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			t.Errorf("temporary file %s left behind", e.Name())
+		}
+	}
+}
@@ -0,0 +1,673 @@
+package runxtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	runx "github.com/run-x-app/runx-go"
+)
+
+var numberPattern = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// handlerFunc serves an operation on behalf of user, which is nil for the
+// anonymous operations. It is called with the server lock held.
+type handlerFunc func(w http.ResponseWriter, r *http.Request, user *userState)
+
+// routes returns the handler serving every path of the Run X API.
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	s.handle(mux, "GET /app", "GetApps", true, s.getApps)
+	s.handle(mux, "POST /app", "CreateApp", true, s.createApp)
+	s.handle(mux, "DELETE /app/{appId}", "DeleteApp", true, s.deleteApp)
+	s.handle(mux, "GET /app/{appId}", "GetApp", true, s.getApp)
+	s.handle(mux, "PUT /app/{appId}", "UpdateApp", true, s.updateApp)
+	s.handle(mux, "PATCH /app/{appId}/enable/{enabled}", "EnableApp", true, s.enableApp)
+	s.handle(mux, "PATCH /app/{appId}/restart", "RestartApp", true, s.restartApp)
+	s.handle(mux, "POST /auth", "Auth", false, s.auth)
+	s.handle(mux, "GET /catalog", "GetCatalogApps", true, s.getCatalog)
+	s.handle(mux, "GET /me", "Me", true, s.me)
+	s.handle(mux, "GET /me/billing", "MeBilling", true, s.meBilling)
+	s.handle(mux, "POST /me/key/generate", "GenerateApiKey", true, s.generateApiKey)
+	s.handle(mux, "GET /me/number", "RevealNumber", true, s.revealNumber)
+	s.handle(mux, "GET /me/session", "MeSession", true, s.meSession)
+	s.handle(mux, "DELETE /me/session/{sessionId}", "DeleteSession", true, s.deleteSession)
+	s.handle(mux, "POST /register", "Register", false, s.register)
+	return mux
+}
+
+func (s *Server) handle(mux *http.ServeMux, pattern, operation string, authenticated bool, h handlerFunc) {
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		if f, ok := s.fault(operation); ok {
+			if f.Latency > 0 {
+				t := time.NewTimer(f.Latency)
+				select {
+				case <-t.C:
+				case <-r.Context().Done():
+					t.Stop()
+					return
+				}
+			}
+			if f.StatusCode != 0 {
+				writeError(w, f.StatusCode, strings.ToLower(http.StatusText(f.StatusCode)))
+				return
+			}
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.charge()
+		var user *userState
+		if authenticated {
+			if user = s.authenticate(r); user == nil {
+				writeError(w, http.StatusUnauthorized, "invalid or missing credentials")
+				return
+			}
+		}
+		h(w, r, user)
+	})
+}
+
+// fault returns the first fault matching operation and consumes one of its
+// occurrences.
+func (s *Server) fault(operation string) (Fault, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, f := range s.faults {
+		if f.Operation != "" && f.Operation != operation {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return *f, true
+	}
+	return Fault{}, false
+}
+
+// authenticate returns the user owning the bearer token of r, either an API
+// key or a session token.
+func (s *Server) authenticate(r *http.Request) *userState {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil
+	}
+	if session := s.sessions[token]; session != nil {
+		return s.users[session.owner]
+	}
+	return s.userByKey(token)
+}
+
+// ownedApp returns the app named by the appId path parameter, writing a 404
+// when it does not exist and a 401 when it belongs to another user.
+func (s *Server) ownedApp(w http.ResponseWriter, r *http.Request, user *userState) *appState {
+	a := s.apps[r.PathValue("appId")]
+	if a == nil {
+		writeError(w, http.StatusNotFound, "app not found")
+		return nil
+	}
+	if a.owner != user.Id {
+		writeError(w, http.StatusUnauthorized, "app belongs to another user")
+		return nil
+	}
+	return a
+}
+
+func (s *Server) getApps(w http.ResponseWriter, r *http.Request, user *userState) {
+	apps := []runx.AppExtended{}
+	for _, a := range s.userApps(user) {
+		apps = append(apps, s.extended(a))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"apps": apps})
+}
+
+func (s *Server) createApp(w http.ResponseWriter, r *http.Request, user *userState) {
+	var body runx.CreateAppRequest
+	if !decode(w, r, &body) {
+		return
+	}
+	if body.Pack != nil {
+		pack := s.pack(*body.Pack)
+		if pack == nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("pack %s not found", *body.Pack))
+			return
+		}
+		if len(body.Apps) == 0 && pack.Apps != nil {
+			for _, id := range *pack.Apps {
+				body.Apps = append(body.Apps, runx.AppRequest{Name: id, App: id})
+			}
+		}
+	}
+	if len(body.Apps) == 0 {
+		writeError(w, http.StatusBadRequest, "no app requested")
+		return
+	}
+	if user.Credit <= 0 {
+		writeError(w, http.StatusBadRequest, "insufficient credit")
+		return
+	}
+
+	names := map[string]bool{}
+	for _, a := range s.userApps(user) {
+		names[*a.app.Name] = true
+	}
+	gpus := 0
+	for _, req := range body.Apps {
+		if req.Name == "" || req.App == "" {
+			writeError(w, http.StatusBadRequest, "name and app are required")
+			return
+		}
+		entry := s.catalogApp(req.App)
+		if entry == nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("app %s not found in catalog", req.App))
+			return
+		}
+		if entry.Level != nil && *entry.Level > user.Level {
+			writeError(w, http.StatusUnauthorized, fmt.Sprintf("app %s requires level %d", req.App, *entry.Level))
+			return
+		}
+		if names[req.Name] {
+			writeError(w, http.StatusConflict, fmt.Sprintf("an app named %s already exists", req.Name))
+			return
+		}
+		names[req.Name] = true
+		if status, msg := s.checkResources(req.Cpu, req.Ram, req.Disk, req.Gpu, req.Env); status != 0 {
+			writeError(w, status, msg)
+			return
+		}
+		if req.Gpu != nil {
+			gpus += *req.Gpu
+		}
+	}
+	if gpus > s.freeGpus() {
+		writeError(w, http.StatusConflict, "not enough GPUs available")
+		return
+	}
+
+	now := s.now()
+	created := []runx.App{}
+	for _, req := range body.Apps {
+		entry := s.catalogApp(req.App)
+		shortId := randomHex(4)
+		a := &appState{
+			app: runx.App{
+				Id:        ptr(s.newId("app")),
+				ShortId:   &shortId,
+				Name:      ptr(req.Name),
+				App:       ptr(req.App),
+				Cmd:       req.Cmd,
+				Cpu:       s.orThreshold(req.Cpu, "cpu"),
+				Ram:       s.orThreshold(req.Ram, "ram"),
+				Disk:      s.orThreshold(req.Disk, "disk"),
+				Gpu:       s.orThreshold(req.Gpu, "gpu"),
+				Env:       req.Env,
+				Host:      ptr(shortId + ".runxtest.local"),
+				Paths:     entry.Paths,
+				Price:     entry.Price,
+				User:      ptr(user.Id),
+				CreatedAt: &now,
+			},
+			owner:     user.Id,
+			updatedAt: now,
+		}
+		a.start(now, s.startDelay, "app created")
+		s.apps[*a.app.Id] = a
+		created = append(created, a.app)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"apps": created, "message": fmt.Sprintf("%d app(s) created", len(created))})
+}
+
+func (s *Server) getApp(w http.ResponseWriter, r *http.Request, user *userState) {
+	a := s.ownedApp(w, r, user)
+	if a == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"app": a.app, "log": strings.Join(a.log, "\n")})
+}
+
+func (s *Server) updateApp(w http.ResponseWriter, r *http.Request, user *userState) {
+	a := s.ownedApp(w, r, user)
+	if a == nil {
+		return
+	}
+	var body runx.UpdateAppRequest
+	if !decode(w, r, &body) {
+		return
+	}
+	if body.Name != nil && *body.Name != *a.app.Name {
+		for _, other := range s.userApps(user) {
+			if *other.app.Name == *body.Name {
+				writeError(w, http.StatusConflict, fmt.Sprintf("an app named %s already exists", *body.Name))
+				return
+			}
+		}
+	}
+	if status, msg := s.checkResources(body.Cpu, body.Ram, body.Disk, body.Gpu, body.Env); status != 0 {
+		writeError(w, status, msg)
+		return
+	}
+	if body.Gpu != nil && a.enabled && a.app.Gpu != nil && *body.Gpu-*a.app.Gpu > s.freeGpus() {
+		writeError(w, http.StatusConflict, "not enough GPUs available")
+		return
+	}
+
+	if body.Name != nil {
+		a.app.Name = body.Name
+	}
+	if body.Cmd != nil {
+		a.app.Cmd = body.Cmd
+	}
+	if body.Cpu != nil {
+		a.app.Cpu = body.Cpu
+	}
+	if body.Ram != nil {
+		a.app.Ram = body.Ram
+	}
+	if body.Disk != nil {
+		a.app.Disk = body.Disk
+	}
+	if body.Gpu != nil {
+		a.app.Gpu = body.Gpu
+	}
+	if body.Env != nil {
+		a.app.Env = body.Env
+	}
+	now := s.now()
+	a.updatedAt = now
+	if a.enabled {
+		a.start(now, s.startDelay, "app updated")
+	}
+	writeJSON(w, http.StatusOK, message("app updated"))
+}
+
+func (s *Server) deleteApp(w http.ResponseWriter, r *http.Request, user *userState) {
+	a := s.ownedApp(w, r, user)
+	if a == nil {
+		return
+	}
+	delete(s.apps, *a.app.Id)
+	writeJSON(w, http.StatusOK, message("app deleted"))
+}
+
+func (s *Server) enableApp(w http.ResponseWriter, r *http.Request, user *userState) {
+	a := s.ownedApp(w, r, user)
+	if a == nil {
+		return
+	}
+	now := s.now()
+	switch runx.EnableAppParamsEnabled(r.PathValue("enabled")) {
+	case runx.True:
+		if user.Credit <= 0 {
+			writeError(w, http.StatusBadRequest, "insufficient credit")
+			return
+		}
+		if !a.enabled && a.app.Gpu != nil && *a.app.Gpu > s.freeGpus() {
+			writeError(w, http.StatusConflict, "not enough GPUs available")
+			return
+		}
+		a.start(now, s.startDelay, "app enabled")
+		writeJSON(w, http.StatusOK, message("app enabled"))
+	case runx.False:
+		a.stop(now, "app disabled")
+		writeJSON(w, http.StatusOK, message("app disabled"))
+	default:
+		writeError(w, http.StatusBadRequest, "enabled must be true or false")
+	}
+}
+
+func (s *Server) restartApp(w http.ResponseWriter, r *http.Request, user *userState) {
+	a := s.ownedApp(w, r, user)
+	if a == nil {
+		return
+	}
+	if user.Credit <= 0 {
+		writeError(w, http.StatusBadRequest, "insufficient credit")
+		return
+	}
+	a.start(s.now(), s.startDelay, "app restarted")
+	writeJSON(w, http.StatusOK, message("app restarted"))
+}
+
+func (s *Server) auth(w http.ResponseWriter, r *http.Request, _ *userState) {
+	var body runx.AuthRequest
+	if !decode(w, r, &body) {
+		return
+	}
+	if !numberPattern.MatchString(body.Number) {
+		writeError(w, http.StatusNotAcceptable, "invalid phone number")
+		return
+	}
+	for _, u := range s.users {
+		if u.Number == body.Number {
+			writeJSON(w, http.StatusOK, map[string]any{"session": s.newSession(r, u)})
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "user not found")
+}
+
+func (s *Server) register(w http.ResponseWriter, r *http.Request, _ *userState) {
+	if s.authenticate(r) != nil {
+		writeError(w, http.StatusConflict, "user already registered")
+		return
+	}
+	u := &userState{
+		User:         User{Id: s.newId("user"), ApiKey: newAPIKey(), Level: 0},
+		createdAt:    s.now(),
+		consumptions: map[string]*runx.Consumption{},
+	}
+	s.users[u.Id] = u
+	writeJSON(w, http.StatusOK, map[string]any{"session": s.newSession(r, u)})
+}
+
+func (s *Server) getCatalog(w http.ResponseWriter, r *http.Request, user *userState) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"catalog":       s.catalog,
+		"packs":         s.packs,
+		"limit":         s.limit,
+		"threshold":     s.threshold,
+		"availableGpus": s.freeGpus(),
+		"gpuAuthorized": s.gpuAuthorized,
+	})
+}
+
+func (s *Server) me(w http.ResponseWriter, r *http.Request, user *userState) {
+	consumptions := []runx.Consumption{}
+	for _, c := range user.consumptions {
+		consumptions = append(consumptions, *c)
+	}
+	sort.Slice(consumptions, func(i, j int) bool { return consumptions[i].Date.Before(*consumptions[j].Date) })
+	writeJSON(w, http.StatusOK, map[string]any{
+		"user": runx.FilteredUser{
+			Id:              ptr(user.Id),
+			ApiKey:          ptr(user.ApiKey),
+			Email:           ptr(user.Email),
+			Level:           ptr(user.Level),
+			Credit:          ptr(user.Credit),
+			Limit:           ptr(user.Limit),
+			CreatedAt:       ptr(user.createdAt),
+			UpdatedAt:       ptr(user.createdAt),
+			LastLogin:       user.lastLogin,
+			FirstConnection: ptr(user.lastLogin == nil),
+			ServicesHealth:  ptr("ok"),
+			TotalApps:       ptr(len(s.userApps(user))),
+		},
+		"consumptions": consumptions,
+	})
+}
+
+func (s *Server) meBilling(w http.ResponseWriter, r *http.Request, user *userState) {
+	payments := append([]runx.Payment{}, user.payments...)
+	writeJSON(w, http.StatusOK, map[string]any{"payments": payments})
+}
+
+func (s *Server) generateApiKey(w http.ResponseWriter, r *http.Request, user *userState) {
+	user.ApiKey = newAPIKey()
+	writeJSON(w, http.StatusOK, map[string]any{"api_key": user.ApiKey})
+}
+
+func (s *Server) revealNumber(w http.ResponseWriter, r *http.Request, user *userState) {
+	writeJSON(w, http.StatusOK, map[string]any{"number": user.Number})
+}
+
+func (s *Server) meSession(w http.ResponseWriter, r *http.Request, user *userState) {
+	sessions := []runx.SessionInfo{}
+	for _, session := range s.sessions {
+		if session.owner == user.Id {
+			sessions = append(sessions, session.info)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.Before(*sessions[j].CreatedAt) })
+	writeJSON(w, http.StatusOK, map[string]any{"sessions": sessions})
+}
+
+func (s *Server) deleteSession(w http.ResponseWriter, r *http.Request, user *userState) {
+	session := s.sessions[r.PathValue("sessionId")]
+	if session == nil || session.owner != user.Id {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	delete(s.sessions, *session.info.Id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// start schedules the app to be running once delay has elapsed.
+func (a *appState) start(now time.Time, delay time.Duration, event string) {
+	a.enabled = true
+	a.forced = ""
+	a.readyAt = now.Add(delay)
+	a.charged = a.readyAt
+	a.updatedAt = now
+	a.logf(now, "%s, starting", event)
+}
+
+func (a *appState) stop(now time.Time, event string) {
+	a.enabled = false
+	a.forced = ""
+	a.updatedAt = now
+	a.logf(now, "%s, stopped", event)
+}
+
+func (a *appState) logf(now time.Time, format string, args ...any) {
+	a.log = append(a.log, now.Format(time.RFC3339)+" "+fmt.Sprintf(format, args...))
+}
+
+// status returns the status of a at now.
+func (a *appState) status(now time.Time) string {
+	switch {
+	case a.forced != "":
+		return a.forced
+	case !a.enabled:
+		return runx.AppStatusStopped
+	case now.Before(a.readyAt):
+		return runx.AppStatusPending
+	default:
+		return runx.AppStatusRunning
+	}
+}
+
+func (s *Server) extended(a *appState) runx.AppExtended {
+	return runx.AppExtended{
+		Id:        a.app.Id,
+		ShortId:   a.app.ShortId,
+		Name:      a.app.Name,
+		App:       a.app.App,
+		Cpu:       a.app.Cpu,
+		Ram:       a.app.Ram,
+		Disk:      a.app.Disk,
+		Gpu:       a.app.Gpu,
+		Env:       a.app.Env,
+		Host:      a.app.Host,
+		Paths:     a.app.Paths,
+		CreatedAt: a.app.CreatedAt,
+		UpdatedAt: ptr(a.updatedAt),
+		Enabled:   ptr(a.enabled),
+		Status:    ptr(a.status(s.now())),
+	}
+}
+
+// charge bills the running apps up to now, splitting the consumption by
+// day, and stops the apps of the users left without credit.
+func (s *Server) charge() {
+	now := s.now()
+	for _, a := range s.apps {
+		u := s.users[a.owner]
+		if u == nil || a.status(now) != runx.AppStatusRunning || a.app.Price == nil {
+			continue
+		}
+		from := a.charged
+		for from.Before(now) {
+			end := from.Truncate(24 * time.Hour).Add(24 * time.Hour)
+			if end.After(now) {
+				end = now
+			}
+			u.consume(from, *a.app.Price*float32(end.Sub(from).Hours()))
+			from = end
+		}
+		a.charged = now
+	}
+	for _, a := range s.apps {
+		if u := s.users[a.owner]; u != nil && u.Credit <= 0 && a.enabled {
+			a.stop(now, "insufficient credit")
+		}
+	}
+}
+
+func (u *userState) consume(at time.Time, cost float32) {
+	day := at.Truncate(24 * time.Hour)
+	key := day.Format(time.DateOnly)
+	c := u.consumptions[key]
+	if c == nil {
+		c = &runx.Consumption{Date: &day, Limit: ptr(u.Limit), Value: ptr[float32](0)}
+		u.consumptions[key] = c
+	}
+	*c.Value += cost
+	u.Credit -= cost
+}
+
+func (s *Server) userApps(user *userState) []*appState {
+	var apps []*appState
+	for _, a := range s.apps {
+		if a.owner == user.Id {
+			apps = append(apps, a)
+		}
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		if !apps[i].app.CreatedAt.Equal(*apps[j].app.CreatedAt) {
+			return apps[i].app.CreatedAt.Before(*apps[j].app.CreatedAt)
+		}
+		return *apps[i].app.Id < *apps[j].app.Id
+	})
+	return apps
+}
+
+func (s *Server) catalogApp(id string) *runx.CatalogApp {
+	for i := range s.catalog {
+		if s.catalog[i].Id != nil && *s.catalog[i].Id == id {
+			return &s.catalog[i]
+		}
+	}
+	return nil
+}
+
+func (s *Server) pack(id string) *runx.Pack {
+	for i := range s.packs {
+		if s.packs[i].Id != nil && *s.packs[i].Id == id {
+			return &s.packs[i]
+		}
+	}
+	return nil
+}
+
+// freeGpus returns the number of GPUs not used by enabled apps.
+func (s *Server) freeGpus() int {
+	free := s.availableGpus
+	for _, a := range s.apps {
+		if a.enabled && a.app.Gpu != nil {
+			free -= *a.app.Gpu
+		}
+	}
+	return max(free, 0)
+}
+
+// checkResources validates requested resources against the limits and
+// thresholds, returning the status code and message of the error if any.
+func (s *Server) checkResources(cpu, ram, disk, gpu *int, env *[]string) (int, string) {
+	for _, r := range []struct {
+		name  string
+		value *int
+	}{{"cpu", cpu}, {"ram", ram}, {"disk", disk}, {"gpu", gpu}} {
+		if r.value == nil {
+			continue
+		}
+		if limit, ok := s.limit[r.name]; ok && *r.value > limit {
+			return http.StatusBadRequest, fmt.Sprintf("%s exceeds the limit of %d", r.name, limit)
+		}
+		if threshold, ok := s.threshold[r.name]; ok && *r.value < threshold {
+			return http.StatusBadRequest, fmt.Sprintf("%s is below the minimum of %d", r.name, threshold)
+		}
+	}
+	if gpu != nil && *gpu > 0 && !s.gpuAuthorized {
+		return http.StatusUnauthorized, "GPU usage is not authorized"
+	}
+	if env != nil {
+		for _, kv := range *env {
+			if k, _, ok := strings.Cut(kv, "="); !ok || k == "" {
+				return http.StatusBadRequest, fmt.Sprintf("invalid environment variable %q", kv)
+			}
+		}
+	}
+	return 0, ""
+}
+
+func (s *Server) orThreshold(v *int, name string) *int {
+	if v != nil {
+		return v
+	}
+	return ptr(s.threshold[name])
+}
+
+func (s *Server) newSession(r *http.Request, u *userState) string {
+	token := randomHex(16)
+	now := s.now()
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	s.sessions[token] = &sessionState{
+		owner: u.Id,
+		info: runx.SessionInfo{
+			Id:        &token,
+			User:      ptr(u.Id),
+			Ip:        &ip,
+			UserAgent: ptr(r.UserAgent()),
+			CreatedAt: &now,
+		},
+	}
+	u.lastLogin = &now
+	return token
+}
+
+func newAPIKey() string {
+	return "rx_" + randomHex(16)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func message(msg string) map[string]any {
+	return map[string]any{"message": msg}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, runx.ErrorResponse{Error: &msg})
+}
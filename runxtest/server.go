@@ -0,0 +1,293 @@
+// Package runxtest provides an in-memory fake of the Run X API for tests.
+//
+// The fake keeps users, apps, sessions and payments in memory and mimics the
+// behaviour of the real service closely enough for client code to be tested
+// without the network:
+//
+//	srv := runxtest.NewServer()
+//	defer srv.Close()
+//
+//	client := srv.Client()
+//	rsp, err := client.CreateAppWithResponse(ctx, runx.CreateAppRequest{...})
+package runxtest
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"time"
+
+	runx "github.com/run-x-app/runx-go"
+)
+
+// DefaultAPIKey is the API key of the user created by NewServer.
+const DefaultAPIKey = "runxtest-api-key"
+
+// DefaultNumber is the phone number of the user created by NewServer.
+const DefaultNumber = "+33600000000"
+
+// User is an account of the fake server.
+type User struct {
+	Id     string
+	ApiKey string
+	Number string
+	Email  string
+	Level  int
+	Credit float32
+	Limit  float32
+}
+
+// Fault makes the server misbehave for matching requests.
+type Fault struct {
+	// Operation restricts the fault to a ClientInterface method such as
+	// "GetApp". An empty operation matches every request.
+	Operation string
+
+	// StatusCode is returned instead of the normal response when non-zero.
+	StatusCode int
+
+	// Latency delays the response.
+	Latency time.Duration
+
+	// Times is the number of requests affected, zero meaning every request.
+	Times int
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithStartDelay sets how long apps stay pending after being created,
+// enabled or restarted before running. It defaults to zero.
+func WithStartDelay(d time.Duration) Option {
+	return func(s *Server) {
+		s.startDelay = d
+	}
+}
+
+// WithCatalog replaces the default catalog.
+func WithCatalog(apps []runx.CatalogApp, packs []runx.Pack) Option {
+	return func(s *Server) {
+		s.catalog = apps
+		s.packs = packs
+	}
+}
+
+// WithLimits sets the per-app resource limits and thresholds returned by
+// GetCatalogApps and enforced by CreateApp and UpdateApp.
+func WithLimits(limit, threshold map[string]int) Option {
+	return func(s *Server) {
+		s.limit = limit
+		s.threshold = threshold
+	}
+}
+
+// WithGpus sets the number of available GPUs and whether users may request them.
+func WithGpus(available int, authorized bool) Option {
+	return func(s *Server) {
+		s.availableGpus = available
+		s.gpuAuthorized = authorized
+	}
+}
+
+// Server is a fake Run X API server.
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	offset        time.Duration
+	startDelay    time.Duration
+	catalog       []runx.CatalogApp
+	packs         []runx.Pack
+	limit         map[string]int
+	threshold     map[string]int
+	availableGpus int
+	gpuAuthorized bool
+
+	users    map[string]*userState
+	apps     map[string]*appState
+	sessions map[string]*sessionState
+	faults   []*Fault
+	nextId   int
+}
+
+type userState struct {
+	User
+	createdAt    time.Time
+	lastLogin    *time.Time
+	payments     []runx.Payment
+	consumptions map[string]*runx.Consumption
+}
+
+type appState struct {
+	app       runx.App
+	owner     string
+	enabled   bool
+	readyAt   time.Time
+	updatedAt time.Time
+	charged   time.Time
+	forced    string
+	log       []string
+}
+
+type sessionState struct {
+	info  runx.SessionInfo
+	owner string
+}
+
+// NewServer starts a fake server with a default user, identified by
+// DefaultAPIKey and DefaultNumber, and a small default catalog.
+func NewServer(opts ...Option) *Server {
+	s := &Server{
+		catalog: []runx.CatalogApp{
+			{Id: ptr("nginx"), Name: ptr("Nginx"), Price: ptr[float32](0.01), Level: ptr(0), Gpu: ptr(0), Enabled: ptr(true)},
+			{Id: ptr("postgres"), Name: ptr("PostgreSQL"), Price: ptr[float32](0.02), Level: ptr(0), Gpu: ptr(0), Enabled: ptr(true)},
+			{Id: ptr("jupyter"), Name: ptr("Jupyter"), Price: ptr[float32](0.5), Level: ptr(1), Gpu: ptr(1), Enabled: ptr(true)},
+		},
+		packs: []runx.Pack{
+			{Id: ptr("web"), Name: ptr("Web stack"), Apps: &[]string{"nginx", "postgres"}, Level: ptr(0), Enabled: ptr(true)},
+		},
+		limit:         map[string]int{"cpu": 8, "ram": 32768, "disk": 102400, "gpu": 1},
+		threshold:     map[string]int{"cpu": 1, "ram": 256, "disk": 1024, "gpu": 0},
+		availableGpus: 2,
+		gpuAuthorized: true,
+		users:         map[string]*userState{},
+		apps:          map[string]*appState{},
+		sessions:      map[string]*sessionState{},
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	s.AddUser(User{ApiKey: DefaultAPIKey, Number: DefaultNumber, Email: "user@example.com", Level: 1, Credit: 100, Limit: 1000})
+	s.Server = httptest.NewServer(s.routes())
+	return s
+}
+
+// Client returns a client authenticated as the default user.
+func (s *Server) Client(opts ...runx.ClientOption) *runx.ClientWithResponses {
+	return s.ClientFor(DefaultAPIKey, opts...)
+}
+
+// ClientFor returns a client authenticated with token, either an API key or
+// a session token.
+func (s *Server) ClientFor(token string, opts ...runx.ClientOption) *runx.ClientWithResponses {
+	client, err := runx.NewClientWithResponses(s.URL, token, opts...)
+	if err != nil {
+		panic(fmt.Sprintf("runxtest: %v", err))
+	}
+	return client
+}
+
+// AddUser creates an account and returns its id.
+func (s *Server) AddUser(u User) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u.Id == "" {
+		u.Id = s.newId("user")
+	}
+	if u.ApiKey == "" {
+		u.ApiKey = newAPIKey()
+	}
+	s.users[u.Id] = &userState{User: u, createdAt: s.now(), consumptions: map[string]*runx.Consumption{}}
+	return u.Id
+}
+
+// AddPayment records a payment credited to the user owning apiKey.
+func (s *Server) AddPayment(apiKey string, amount float32, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := s.userByKey(apiKey)
+	if u == nil {
+		panic("runxtest: unknown API key " + apiKey)
+	}
+	u.Credit += amount
+	u.payments = append(u.payments, runx.Payment{Id: ptr(s.newId("payment")), Amount: &amount, CreatedAt: &at, User: ptr(u.Id)})
+}
+
+// Apps returns the apps of every user as listed by GetApps.
+func (s *Server) Apps() []runx.AppExtended {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.charge()
+	apps := make([]runx.AppExtended, 0, len(s.apps))
+	for _, a := range s.apps {
+		apps = append(apps, s.extended(a))
+	}
+	sort.Slice(apps, func(i, j int) bool { return *apps[i].Id < *apps[j].Id })
+	return apps
+}
+
+// SetAppStatus forces the status of an app, for instance to "failed", until
+// it is next enabled, disabled or restarted.
+func (s *Server) SetAppStatus(appId, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.charge()
+	if a := s.apps[appId]; a != nil {
+		a.forced = status
+		a.updatedAt = s.now()
+	}
+}
+
+// AppendLog adds lines to the log returned by GetApp.
+func (s *Server) AppendLog(appId string, lines ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a := s.apps[appId]; a != nil {
+		a.log = append(a.log, lines...)
+	}
+}
+
+// SetLog replaces the log returned by GetApp, e.g. to simulate a rotation.
+func (s *Server) SetLog(appId string, lines ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a := s.apps[appId]; a != nil {
+		a.log = append([]string(nil), lines...)
+	}
+}
+
+// Advance moves the clock of the server forward, charging the running apps
+// for the elapsed time and completing pending starts.
+func (s *Server) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offset += d
+	s.charge()
+}
+
+// InjectFault registers a fault. Faults are evaluated in registration order.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults removes every registered fault.
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+func (s *Server) now() time.Time {
+	return time.Now().Add(s.offset).UTC()
+}
+
+func (s *Server) newId(prefix string) string {
+	s.nextId++
+	return fmt.Sprintf("%s-%d", prefix, s.nextId)
+}
+
+func (s *Server) userByKey(apiKey string) *userState {
+	for _, u := range s.users {
+		if u.ApiKey == apiKey {
+			return u
+		}
+	}
+	return nil
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,396 @@
+package runxtest_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	runx "github.com/run-x-app/runx-go"
+	"github.com/run-x-app/runx-go/runxtest"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// createApp creates an app of the catalog entry app and returns its id.
+func createApp(t *testing.T, client *runx.ClientWithResponses, req runx.AppRequest) string {
+	t.Helper()
+	rsp, err := client.CreateAppWithResponse(context.Background(), runx.CreateAppRequest{Apps: []runx.AppRequest{req}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.StatusCode() != http.StatusOK || rsp.JSON200 == nil || rsp.JSON200.Apps == nil || len(*rsp.JSON200.Apps) != 1 {
+		t.Fatalf("CreateApp() = %d %s", rsp.StatusCode(), rsp.Body)
+	}
+	return *(*rsp.JSON200.Apps)[0].Id
+}
+
+// appStatus returns the status of the app as listed by GetApps.
+func appStatus(t *testing.T, client *runx.ClientWithResponses, id string) string {
+	t.Helper()
+	rsp, err := client.GetAppsWithResponse(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.JSON200 == nil || rsp.JSON200.Apps == nil {
+		t.Fatalf("GetApps() = %d %s", rsp.StatusCode(), rsp.Body)
+	}
+	for _, app := range *rsp.JSON200.Apps {
+		if *app.Id == id {
+			return *app.Status
+		}
+	}
+	t.Fatalf("app %s not listed", id)
+	return ""
+}
+
+func TestFaults(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	client := srv.Client()
+
+	tests := []struct {
+		name  string
+		fault runxtest.Fault
+		// statuses of successive GetApps and Me calls
+		getApps []int
+		me      []int
+	}{
+		{
+			name:    "operation",
+			fault:   runxtest.Fault{Operation: "GetApps", StatusCode: http.StatusServiceUnavailable},
+			getApps: []int{503, 503, 503},
+			me:      []int{200},
+		},
+		{
+			name:    "times",
+			fault:   runxtest.Fault{Operation: "GetApps", StatusCode: http.StatusInternalServerError, Times: 2},
+			getApps: []int{500, 500, 200},
+			me:      []int{200},
+		},
+		{
+			name:    "every operation",
+			fault:   runxtest.Fault{StatusCode: http.StatusTooManyRequests},
+			getApps: []int{429, 429},
+			me:      []int{429},
+		},
+		{
+			name:    "latency only",
+			fault:   runxtest.Fault{Operation: "Me", Latency: time.Millisecond},
+			getApps: []int{200},
+			me:      []int{200, 200},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv.InjectFault(tt.fault)
+			defer srv.ClearFaults()
+			for i, want := range tt.getApps {
+				rsp, err := client.GetAppsWithResponse(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if rsp.StatusCode() != want {
+					t.Errorf("GetApps() #%d = %d, want %d", i+1, rsp.StatusCode(), want)
+				}
+			}
+			for i, want := range tt.me {
+				rsp, err := client.MeWithResponse(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if rsp.StatusCode() != want {
+					t.Errorf("Me() #%d = %d, want %d", i+1, rsp.StatusCode(), want)
+				}
+			}
+		})
+	}
+
+	t.Run("latency", func(t *testing.T) {
+		srv.InjectFault(runxtest.Fault{Operation: "GetApps", Latency: time.Second})
+		defer srv.ClearFaults()
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		if _, err := client.GetAppsWithResponse(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("GetApps() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("api errors", func(t *testing.T) {
+		srv.InjectFault(runxtest.Fault{Operation: "GetApps", StatusCode: http.StatusBadGateway, Times: 1})
+		_, err := srv.Client(runx.WithAPIErrors()).GetAppsWithResponse(ctx)
+		if !errors.Is(err, runx.ErrServer) {
+			t.Fatalf("GetApps() error = %v, want %v", err, runx.ErrServer)
+		}
+	})
+}
+
+func TestAppStatus(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer(runxtest.WithStartDelay(time.Minute))
+	defer srv.Close()
+	client := srv.Client()
+	id := createApp(t, client, runx.AppRequest{Name: "web", App: "nginx"})
+
+	steps := []struct {
+		name string
+		do   func() (int, error)
+		want string
+	}{
+		{"created", nil, runx.AppStatusPending},
+		{"started", advance(srv, time.Minute), runx.AppStatusRunning},
+		{"disabled", enable(client, id, runx.False), runx.AppStatusStopped},
+		{"still disabled", advance(srv, time.Hour), runx.AppStatusStopped},
+		{"enabled", enable(client, id, runx.True), runx.AppStatusPending},
+		{"started again", advance(srv, time.Minute), runx.AppStatusRunning},
+		{"restarted", func() (int, error) {
+			rsp, err := client.RestartAppWithResponse(ctx, id)
+			if err != nil {
+				return 0, err
+			}
+			return rsp.StatusCode(), nil
+		}, runx.AppStatusPending},
+		{"running after restart", advance(srv, time.Minute), runx.AppStatusRunning},
+		{"updated", func() (int, error) {
+			rsp, err := client.UpdateAppWithResponse(ctx, id, runx.UpdateAppRequest{Cpu: ptr(2)})
+			if err != nil {
+				return 0, err
+			}
+			return rsp.StatusCode(), nil
+		}, runx.AppStatusPending},
+		{"running after update", advance(srv, time.Minute), runx.AppStatusRunning},
+		{"forced", func() (int, error) {
+			srv.SetAppStatus(id, "failed")
+			return http.StatusOK, nil
+		}, "failed"},
+		{"forced until restart", advance(srv, time.Hour), "failed"},
+		{"enabled after failure", enable(client, id, runx.True), runx.AppStatusPending},
+	}
+	for _, step := range steps {
+		if step.do != nil {
+			status, err := step.do()
+			if err != nil {
+				t.Fatalf("%s: %v", step.name, err)
+			}
+			if status != http.StatusOK {
+				t.Fatalf("%s: status code %d", step.name, status)
+			}
+		}
+		if got := appStatus(t, client, id); got != step.want {
+			t.Errorf("%s: status = %q, want %q", step.name, got, step.want)
+		}
+	}
+}
+
+func advance(srv *runxtest.Server, d time.Duration) func() (int, error) {
+	return func() (int, error) {
+		srv.Advance(d)
+		return http.StatusOK, nil
+	}
+}
+
+func enable(client *runx.ClientWithResponses, id string, enabled runx.EnableAppParamsEnabled) func() (int, error) {
+	return func() (int, error) {
+		rsp, err := client.EnableAppWithResponse(context.Background(), id, enabled)
+		if err != nil {
+			return 0, err
+		}
+		return rsp.StatusCode(), nil
+	}
+}
+
+func TestGpus(t *testing.T) {
+	ctx := context.Background()
+	availableGpus := func(t *testing.T, client *runx.ClientWithResponses) int {
+		t.Helper()
+		rsp, err := client.GetCatalogAppsWithResponse(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *rsp.JSON200.AvailableGpus
+	}
+
+	t.Run("accounting", func(t *testing.T) {
+		srv := runxtest.NewServer(runxtest.WithGpus(1, true))
+		defer srv.Close()
+		client := srv.Client()
+
+		if n := availableGpus(t, client); n != 1 {
+			t.Fatalf("availableGpus = %d, want 1", n)
+		}
+		first := createApp(t, client, runx.AppRequest{Name: "notebook", App: "jupyter", Gpu: ptr(1)})
+		if n := availableGpus(t, client); n != 0 {
+			t.Errorf("availableGpus = %d after a GPU app, want 0", n)
+		}
+
+		rsp, err := client.CreateAppWithResponse(ctx, runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "other", App: "jupyter", Gpu: ptr(1)}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rsp.StatusCode() != http.StatusConflict {
+			t.Errorf("CreateApp() without free GPU = %d, want 409", rsp.StatusCode())
+		}
+		// apps without GPU are not affected
+		createApp(t, client, runx.AppRequest{Name: "web", App: "nginx"})
+
+		if status, err := enable(client, first, runx.False)(); err != nil || status != http.StatusOK {
+			t.Fatalf("EnableApp(false) = %d, %v", status, err)
+		}
+		if n := availableGpus(t, client); n != 1 {
+			t.Errorf("availableGpus = %d after disabling the GPU app, want 1", n)
+		}
+		second := createApp(t, client, runx.AppRequest{Name: "other", App: "jupyter", Gpu: ptr(1)})
+		if status, err := enable(client, first, runx.True)(); err != nil || status != http.StatusConflict {
+			t.Errorf("EnableApp(true) without free GPU = %d, %v, want 409", status, err)
+		}
+
+		del, err := client.DeleteAppWithResponse(ctx, second)
+		if err != nil || del.StatusCode() != http.StatusOK {
+			t.Fatalf("DeleteApp() = %v, %v", del, err)
+		}
+		if status, err := enable(client, first, runx.True)(); err != nil || status != http.StatusOK {
+			t.Errorf("EnableApp(true) after deleting the other app = %d, %v, want 200", status, err)
+		}
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		srv := runxtest.NewServer(runxtest.WithGpus(2, false))
+		defer srv.Close()
+		rsp, err := srv.Client().CreateAppWithResponse(ctx, runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "notebook", App: "jupyter", Gpu: ptr(1)}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rsp.StatusCode() != http.StatusUnauthorized {
+			t.Errorf("CreateApp() = %d, want 401", rsp.StatusCode())
+		}
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		srv := runxtest.NewServer(runxtest.WithGpus(4, true))
+		defer srv.Close()
+		rsp, err := srv.Client().CreateAppWithResponse(ctx, runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "notebook", App: "jupyter", Gpu: ptr(2)}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rsp.StatusCode() != http.StatusBadRequest {
+			t.Errorf("CreateApp() beyond the GPU limit = %d, want 400", rsp.StatusCode())
+		}
+	})
+}
+
+func TestBilling(t *testing.T) {
+	ctx := context.Background()
+	me := func(t *testing.T, client *runx.ClientWithResponses) (credit float64, consumed float64, days int) {
+		t.Helper()
+		rsp, err := client.MeWithResponse(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range *rsp.JSON200.Consumptions {
+			consumed += float64(*c.Value)
+		}
+		return float64(*rsp.JSON200.User.Credit), consumed, len(*rsp.JSON200.Consumptions)
+	}
+	near := func(a, b float64) bool {
+		return math.Abs(a-b) < 1e-3
+	}
+
+	t.Run("running apps", func(t *testing.T) {
+		srv := runxtest.NewServer(runxtest.WithStartDelay(time.Hour))
+		defer srv.Close()
+		client := srv.Client()
+		web := createApp(t, client, runx.AppRequest{Name: "web", App: "nginx"})
+		createApp(t, client, runx.AppRequest{Name: "db", App: "postgres"})
+
+		// pending apps are not charged
+		srv.Advance(59 * time.Minute)
+		if credit, consumed, _ := me(t, client); !near(credit, 100) || consumed != 0 {
+			t.Fatalf("credit %v, consumed %v while pending", credit, consumed)
+		}
+		// 10h at 0.01 + 0.02 per hour
+		srv.Advance(10*time.Hour + time.Minute)
+		if credit, consumed, _ := me(t, client); !near(credit, 99.7) || !near(consumed, 0.3) {
+			t.Errorf("credit %v, consumed %v after 10h, want 99.7 and 0.3", credit, consumed)
+		}
+		// stopped apps are not charged
+		if status, err := enable(client, web, runx.False)(); err != nil || status != http.StatusOK {
+			t.Fatalf("EnableApp(false) = %d, %v", status, err)
+		}
+		srv.Advance(10 * time.Hour)
+		if credit, consumed, _ := me(t, client); !near(credit, 99.5) || !near(consumed, 0.5) {
+			t.Errorf("credit %v, consumed %v after disabling, want 99.5 and 0.5", credit, consumed)
+		}
+	})
+
+	t.Run("daily consumptions", func(t *testing.T) {
+		srv := runxtest.NewServer()
+		defer srv.Close()
+		client := srv.Client()
+		createApp(t, client, runx.AppRequest{Name: "web", App: "nginx"})
+		srv.Advance(72 * time.Hour)
+		credit, consumed, days := me(t, client)
+		if !near(consumed, 0.72) || !near(credit, 100-0.72) {
+			t.Errorf("credit %v, consumed %v after 72h, want %v and 0.72", credit, consumed, 100-0.72)
+		}
+		// three days and the parts of the first and last ones
+		if days < 3 || days > 4 {
+			t.Errorf("%d daily consumptions over 72h, want 3 or 4", days)
+		}
+	})
+
+	t.Run("payments", func(t *testing.T) {
+		srv := runxtest.NewServer()
+		defer srv.Close()
+		client := srv.Client()
+		at := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+		srv.AddPayment(runxtest.DefaultAPIKey, 25, at)
+
+		if credit, _, _ := me(t, client); !near(credit, 125) {
+			t.Errorf("credit %v after a payment, want 125", credit)
+		}
+		rsp, err := client.MeBillingWithResponse(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payments := *rsp.JSON200.Payments
+		if len(payments) != 1 || *payments[0].Amount != 25 || !payments[0].CreatedAt.Equal(at) {
+			t.Errorf("MeBilling() payments = %+v", payments)
+		}
+	})
+
+	t.Run("exhausted credit", func(t *testing.T) {
+		srv := runxtest.NewServer()
+		defer srv.Close()
+		key := "poor-api-key"
+		srv.AddUser(runxtest.User{ApiKey: key, Level: 1, Credit: 0.05, Limit: 10})
+		client := srv.ClientFor(key, runx.WithAPIErrors())
+		id := createApp(t, client, runx.AppRequest{Name: "db", App: "postgres"})
+
+		srv.Advance(3 * time.Hour)
+		if credit, _, _ := me(t, client); credit > 0 {
+			t.Errorf("credit %v after 3h at 0.02, want exhausted", credit)
+		}
+		if got := appStatus(t, client, id); got != runx.AppStatusStopped {
+			t.Errorf("status = %q once the credit is exhausted, want %q", got, runx.AppStatusStopped)
+		}
+		if _, err := client.RestartAppWithResponse(ctx, id); !errors.Is(err, runx.ErrBadRequest) {
+			t.Errorf("RestartApp() error = %v, want %v", err, runx.ErrBadRequest)
+		}
+		_, err := client.CreateAppWithResponse(ctx, runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "web", App: "nginx"}}})
+		if !errors.Is(err, runx.ErrBadRequest) {
+			t.Errorf("CreateApp() error = %v, want %v", err, runx.ErrBadRequest)
+		}
+		// the consumption is not charged past the exhaustion
+		srv.Advance(10 * time.Hour)
+		credit, _, _ := me(t, client)
+		srv.AddPayment(key, 1, time.Now())
+		if after, _, _ := me(t, client); !near(after, credit+1) {
+			t.Errorf("credit %v after a payment of 1, want %v", after, credit+1)
+		}
+	})
+}
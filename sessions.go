@@ -0,0 +1,323 @@
+package runx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoCurrentSession is returned when the token of the client is not one of
+// the sessions of the account, e.g. when it is an API key.
+var ErrNoCurrentSession = errors.New("runx: token is not a session of the account")
+
+// ListSessions returns the sessions of the authenticated user, oldest first.
+func (c *ClientWithResponses) ListSessions(ctx context.Context) ([]SessionInfo, error) {
+	rsp, err := c.MeSessionWithResponse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckResponse("MeSession", rsp.HTTPResponse, rsp.Body); err != nil {
+		return nil, err
+	}
+	if rsp.JSON200 == nil || rsp.JSON200.Sessions == nil {
+		return nil, nil
+	}
+	sessions := *rsp.JSON200.Sessions
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessionCreatedAt(sessions[i]).Before(sessionCreatedAt(sessions[j]))
+	})
+	return sessions, nil
+}
+
+// CurrentSession returns the session identified by token, the session token
+// sent by the client, among sessions. It returns ErrNoCurrentSession when
+// none matches, in particular when token is an API key.
+//
+// The API documents neither which session a token belongs to nor an endpoint
+// returning the current session. CurrentSession relies on the Id of a
+// SessionInfo being the token returned by Auth or Register, as the service
+// does today.
+func CurrentSession(sessions []SessionInfo, token string) (*SessionInfo, error) {
+	if token != "" {
+		for i := range sessions {
+			if sessions[i].Id != nil && *sessions[i].Id == token {
+				return &sessions[i], nil
+			}
+		}
+	}
+	return nil, ErrNoCurrentSession
+}
+
+// RevokeOption configures RevokeOtherSessions.
+type RevokeOption func(*revokeConfig)
+
+type revokeConfig struct {
+	concurrency int
+}
+
+// WithRevokeConcurrency bounds the number of DeleteSession calls in flight.
+// It defaults to four.
+func WithRevokeConcurrency(n int) RevokeOption {
+	return func(c *revokeConfig) {
+		c.concurrency = n
+	}
+}
+
+// RevokeResult reports the outcome of RevokeOtherSessions.
+type RevokeResult struct {
+	// Current is the Id of the session that was kept.
+	Current string `json:"current"`
+
+	// Revoked holds the Ids of the deleted sessions, sorted.
+	Revoked []string `json:"revoked"`
+
+	// Failed maps the Ids of the sessions that could not be deleted to the
+	// error of their DeleteSession call.
+	Failed map[string]error `json:"-"`
+}
+
+// RevokeOtherSessions deletes every session of the user but the one
+// identified by token, calling DeleteSession concurrently. It refuses to run
+// when token is not one of the sessions, which would log every device out,
+// returning ErrNoCurrentSession. A session already gone when it is deleted
+// counts as revoked. The returned error joins the errors of the failed
+// deletions, which are also listed in RevokeResult.Failed.
+func (c *ClientWithResponses) RevokeOtherSessions(ctx context.Context, token string, opts ...RevokeOption) (*RevokeResult, error) {
+	cfg := revokeConfig{concurrency: 4}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	if cfg.concurrency < 1 {
+		cfg.concurrency = 1
+	}
+
+	sessions, err := c.ListSessions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("runx: revoke sessions: %w", err)
+	}
+	current, err := CurrentSession(sessions, token)
+	if err != nil {
+		return nil, err
+	}
+	result := &RevokeResult{Current: *current.Id, Revoked: []string{}, Failed: map[string]error{}}
+
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, cfg.concurrency)
+	)
+	for _, s := range sessions {
+		if s.Id == nil || *s.Id == result.Current {
+			continue
+		}
+		id := *s.Id
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := c.revokeSession(ctx, id)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				result.Failed[id] = err
+			} else {
+				result.Revoked = append(result.Revoked, id)
+			}
+		}()
+	}
+	wg.Wait()
+	sort.Strings(result.Revoked)
+
+	var errs []error
+	for id, err := range result.Failed {
+		errs = append(errs, fmt.Errorf("runx: revoke session %s: %w", id, err))
+	}
+	return result, errors.Join(errs...)
+}
+
+// revokeSession deletes the session id, ignoring a 404.
+func (c *ClientWithResponses) revokeSession(ctx context.Context, id string) error {
+	rsp, err := c.DeleteSessionWithResponse(ctx, id)
+	if err == nil {
+		err = CheckResponse("DeleteSession", rsp.HTTPResponse, rsp.Body)
+	}
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Reasons for which AuditSessions flags a session.
+const (
+	SessionNewIP            = "new_ip"
+	SessionUnknownUserAgent = "unfamiliar_user_agent"
+	SessionTooOld           = "too_old"
+)
+
+// SessionPolicy describes the sessions considered legitimate by
+// AuditSessions. The address and the user agent of the current session are
+// always trusted.
+type SessionPolicy struct {
+	// KnownNetworks lists the trusted addresses, as IPs or CIDR prefixes
+	// such as "203.0.113.0/24".
+	KnownNetworks []string
+
+	// KnownUserAgents lists the prefixes of the trusted user agents, such as
+	// "runx-go/" or "Mozilla/5.0".
+	KnownUserAgents []string
+
+	// MaxAge flags the sessions created longer ago, when positive.
+	MaxAge time.Duration
+}
+
+// SessionAudit is the verdict on one session.
+type SessionAudit struct {
+	Id        string     `json:"id"`
+	Ip        string     `json:"ip,omitempty"`
+	UserAgent string     `json:"user_agent,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	Current   bool       `json:"current"`
+
+	// Reasons lists why the session is suspicious, empty when it is not.
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Suspicious reports whether the session was flagged.
+func (a SessionAudit) Suspicious() bool {
+	return len(a.Reasons) > 0
+}
+
+// SessionReport is the outcome of AuditSessions, meant to be exported as JSON.
+type SessionReport struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	User        string    `json:"user,omitempty"`
+
+	// Current is the Id of the session of the client, empty when the client
+	// is authenticated with an API key.
+	Current string `json:"current,omitempty"`
+
+	Sessions   []SessionAudit `json:"sessions"`
+	Suspicious int            `json:"suspicious"`
+}
+
+// AuditSessions lists the sessions of the user and flags those whose address
+// or user agent is not trusted by policy, or which are older than
+// policy.MaxAge. token identifies the current session, see CurrentSession.
+func (c *ClientWithResponses) AuditSessions(ctx context.Context, token string, policy SessionPolicy) (*SessionReport, error) {
+	sessions, err := c.ListSessions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("runx: audit sessions: %w", err)
+	}
+	return policy.Audit(sessions, token, time.Now())
+}
+
+// Audit flags sessions against the policy at time now, token identifying the
+// current session. It fails on a malformed KnownNetworks entry.
+func (p SessionPolicy) Audit(sessions []SessionInfo, token string, now time.Time) (*SessionReport, error) {
+	networks := make([]netip.Prefix, 0, len(p.KnownNetworks)+1)
+	for _, n := range p.KnownNetworks {
+		prefix, err := parseNetwork(n)
+		if err != nil {
+			return nil, fmt.Errorf("runx: session policy: %w", err)
+		}
+		networks = append(networks, prefix)
+	}
+	agents := append([]string(nil), p.KnownUserAgents...)
+	var exactAgent string
+
+	report := &SessionReport{GeneratedAt: now, Sessions: []SessionAudit{}}
+	// an API key has no session, leaving every session to the policy
+	if current, err := CurrentSession(sessions, token); err == nil {
+		report.Current = *current.Id
+		if prefix, err := parseNetwork(deref(current.Ip)); err == nil {
+			networks = append(networks, prefix)
+		}
+		exactAgent = deref(current.UserAgent)
+	}
+
+	for _, s := range sessions {
+		audit := SessionAudit{
+			Id:        deref(s.Id),
+			Ip:        deref(s.Ip),
+			UserAgent: deref(s.UserAgent),
+			CreatedAt: s.CreatedAt,
+			Current:   report.Current != "" && deref(s.Id) == report.Current,
+		}
+		if report.User == "" {
+			report.User = deref(s.User)
+		}
+		if !audit.Current {
+			if !trustedAddress(audit.Ip, networks) {
+				audit.Reasons = append(audit.Reasons, SessionNewIP)
+			}
+			if audit.UserAgent != exactAgent && !hasAnyPrefix(audit.UserAgent, agents) {
+				audit.Reasons = append(audit.Reasons, SessionUnknownUserAgent)
+			}
+		}
+		if p.MaxAge > 0 && s.CreatedAt != nil && now.Sub(*s.CreatedAt) > p.MaxAge {
+			audit.Reasons = append(audit.Reasons, SessionTooOld)
+		}
+		if audit.Suspicious() {
+			report.Suspicious++
+		}
+		report.Sessions = append(report.Sessions, audit)
+	}
+	return report, nil
+}
+
+// parseNetwork parses an IP, as a single address prefix, or a CIDR prefix.
+func parseNetwork(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func trustedAddress(ip string, networks []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, n := range networks {
+		if n.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if p != "" && strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func sessionCreatedAt(s SessionInfo) time.Time {
+	if s.CreatedAt == nil {
+		return time.Time{}
+	}
+	return *s.CreatedAt
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
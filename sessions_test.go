@@ -0,0 +1,130 @@
+package runx
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCurrentSession(t *testing.T) {
+	sessions := []SessionInfo{
+		{Id: ptr("session-a")},
+		{},
+		{Id: ptr("session-b")},
+	}
+	tests := []struct {
+		name    string
+		token   string
+		want    string
+		wantErr error
+	}{
+		{"first", "session-a", "session-a", nil},
+		{"last", "session-b", "session-b", nil},
+		{"api key", "rx_0123456789abcdef", "", ErrNoCurrentSession},
+		{"empty token", "", "", ErrNoCurrentSession},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CurrentSession(sessions, tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CurrentSession() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if got != nil {
+					t.Errorf("CurrentSession() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil || *got.Id != tt.want {
+				t.Errorf("CurrentSession() = %+v, want %s", got, tt.want)
+			}
+		})
+	}
+	if _, err := CurrentSession(nil, "session-a"); !errors.Is(err, ErrNoCurrentSession) {
+		t.Errorf("CurrentSession(nil) error = %v, want %v", err, ErrNoCurrentSession)
+	}
+}
+
+func TestSessionPolicyAudit(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	created := now.Add(-time.Hour)
+	old := now.Add(-60 * 24 * time.Hour)
+	sessions := []SessionInfo{
+		{Id: ptr("current"), Ip: ptr("198.51.100.7"), UserAgent: ptr("runx-cli/1.0"), CreatedAt: &old},
+		{Id: ptr("same-ip"), Ip: ptr("198.51.100.7"), UserAgent: ptr("runx-cli/1.0"), CreatedAt: &created},
+		{Id: ptr("office"), Ip: ptr("203.0.113.9"), UserAgent: ptr("Mozilla/5.0 (X11)"), CreatedAt: &created},
+		{Id: ptr("stranger"), Ip: ptr("192.0.2.1"), UserAgent: ptr("curl/8.0"), CreatedAt: &created},
+	}
+	policy := SessionPolicy{
+		KnownNetworks:   []string{"203.0.113.0/24"},
+		KnownUserAgents: []string{"Mozilla/5.0"},
+		MaxAge:          30 * 24 * time.Hour,
+	}
+	tests := []struct {
+		name        string
+		token       string
+		wantCurrent string
+		wantReasons map[string][]string
+	}{
+		{
+			name:        "session",
+			token:       "current",
+			wantCurrent: "current",
+			wantReasons: map[string][]string{
+				"current":  {SessionTooOld},
+				"same-ip":  nil,
+				"office":   nil,
+				"stranger": {SessionNewIP, SessionUnknownUserAgent},
+			},
+		},
+		{
+			// without a current session, its address and user agent are not trusted
+			name:  "api key",
+			token: "rx_0123456789abcdef",
+			wantReasons: map[string][]string{
+				"current":  {SessionNewIP, SessionUnknownUserAgent, SessionTooOld},
+				"same-ip":  {SessionNewIP, SessionUnknownUserAgent},
+				"office":   nil,
+				"stranger": {SessionNewIP, SessionUnknownUserAgent},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := policy.Audit(sessions, tt.token, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Current != tt.wantCurrent {
+				t.Errorf("Current = %q, want %q", report.Current, tt.wantCurrent)
+			}
+			suspicious := 0
+			for _, s := range report.Sessions {
+				if s.Current != (s.Id == tt.wantCurrent) {
+					t.Errorf("%s: Current = %v", s.Id, s.Current)
+				}
+				want := tt.wantReasons[s.Id]
+				if len(s.Reasons) != len(want) {
+					t.Errorf("%s: reasons = %v, want %v", s.Id, s.Reasons, want)
+					continue
+				}
+				for i := range want {
+					if s.Reasons[i] != want[i] {
+						t.Errorf("%s: reasons = %v, want %v", s.Id, s.Reasons, want)
+						break
+					}
+				}
+				if len(want) > 0 {
+					suspicious++
+				}
+			}
+			if report.Suspicious != suspicious {
+				t.Errorf("Suspicious = %d, want %d", report.Suspicious, suspicious)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,169 @@
+// Package tracing instruments the Run X client with OpenTelemetry.
+//
+// WithTracing creates a client span per API call, named after the
+// ClientInterface method, and propagates the W3C trace context to the server:
+//
+//	client, err := runx.NewClientWithResponses(server, apiKey,
+//		tracing.WithTracing(tracing.WithTracerProvider(tp)),
+//		runx.WithRetry(),
+//	)
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	runx "github.com/run-x-app/runx-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the tracer.
+const ScopeName = "github.com/run-x-app/runx-go/tracing"
+
+// Attributes set on the spans in addition to the HTTP semantic conventions.
+const (
+	OperationKey = attribute.Key("runx.operation")
+	AppIdKey     = attribute.Key("runx.app_id")
+	SessionIdKey = attribute.Key("runx.session_id")
+	ErrorMsgKey  = attribute.Key("runx.error.message")
+)
+
+// Option configures WithTracing.
+type Option func(*config)
+
+type config struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+// WithTracerProvider sets the provider of the tracer. It defaults to the
+// global provider, see otel.GetTracerProvider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = tp
+	}
+}
+
+// WithPropagator sets the propagator injecting the trace context into the
+// request headers. It defaults to the W3C Trace Context propagator.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = p
+	}
+}
+
+// WithTracing creates a client span per request, named runx.<Operation>
+// after the ClientInterface method, such as runx.RestartApp, and injects
+// its context into the request headers with InjectTraceContext. The span
+// records the HTTP method, URL and status, the app or session id of the
+// path and, on failure, the error message. Registered before WithRetry, a
+// span covers every attempt of a call; registered after, it covers one.
+func WithTracing(opts ...Option) runx.ClientOption {
+	cfg := config{provider: otel.GetTracerProvider(), propagator: propagation.TraceContext{}}
+	for _, o := range opts {
+		o(&cfg)
+	}
+	tracer := cfg.provider.Tracer(ScopeName)
+	inject := InjectTraceContext(cfg.propagator)
+
+	return runx.WithDoerMiddleware(func(next runx.HttpRequestDoer) runx.HttpRequestDoer {
+		return runx.DoerFunc(func(req *http.Request) (*http.Response, error) {
+			op := runx.OperationName(req)
+			name := "runx." + op
+			if op == "" {
+				name = "runx." + req.Method
+			}
+			attrs := []attribute.KeyValue{
+				semconv.HTTPRequestMethodKey.String(req.Method),
+				semconv.URLFull(req.URL.String()),
+				semconv.ServerAddress(req.URL.Hostname()),
+			}
+			if op != "" {
+				attrs = append(attrs, OperationKey.String(op))
+			}
+			params := runx.OperationParams(req)
+			if id, ok := params["appId"]; ok {
+				attrs = append(attrs, AppIdKey.String(id))
+			}
+			if id, ok := params["sessionId"]; ok {
+				attrs = append(attrs, SessionIdKey.String(id))
+			}
+			ctx, span := tracer.Start(req.Context(), name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+			defer span.End()
+
+			req = req.Clone(ctx)
+			if err := inject(ctx, req); err != nil {
+				return nil, err
+			}
+			rsp, err := next.Do(req)
+			if err != nil {
+				recordError(span, err)
+				return rsp, err
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(rsp.StatusCode))
+			if rsp.StatusCode >= 400 {
+				body, readErr := io.ReadAll(rsp.Body)
+				_ = rsp.Body.Close()
+				rsp.Body = io.NopCloser(bytes.NewReader(body))
+				if readErr != nil {
+					recordError(span, readErr)
+					return rsp, nil
+				}
+				recordError(span, runx.CheckResponse(op, rsp, body))
+			}
+			return rsp, nil
+		})
+	})
+}
+
+// InjectTraceContext returns a RequestEditorFn injecting the trace context of
+// ctx into the request headers with p, which defaults to the W3C Trace Context
+// propagator when nil. WithTracing uses it for its spans; given alone, it
+// propagates the spans of the caller.
+func InjectTraceContext(p propagation.TextMapPropagator) runx.RequestEditorFn {
+	if p == nil {
+		p = propagation.TraceContext{}
+	}
+	return func(ctx context.Context, req *http.Request) error {
+		p.Inject(ctx, propagation.HeaderCarrier(req.Header))
+		return nil
+	}
+}
+
+// recordError marks span as failed with the message of err. An *APIError,
+// returned by the middlewares of WithAPIErrors, also sets the status code.
+func recordError(span trace.Span, err error) {
+	msg := err.Error()
+	var apiErr *runx.APIError
+	if errors.As(err, &apiErr) {
+		msg = apiErr.Message
+		if apiErr.StatusCode != 0 {
+			span.SetAttributes(semconv.HTTPResponseStatusCode(apiErr.StatusCode), semconv.ErrorTypeKey.String(strconv.Itoa(apiErr.StatusCode)))
+		}
+	} else {
+		span.SetAttributes(semconv.ErrorTypeKey.String(errorType(err)))
+	}
+	span.SetAttributes(ErrorMsgKey.String(msg))
+	span.RecordError(err)
+	span.SetStatus(codes.Error, msg)
+}
+
+// errorType returns the error.type of a transport error.
+func errorType(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	}
+	return "_OTHER"
+}
@@ -0,0 +1,274 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	runx "github.com/run-x-app/runx-go"
+	"github.com/run-x-app/runx-go/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceServer answers every request with status and body, and records the
+// traceparent header it receives.
+type traceServer struct {
+	*httptest.Server
+	mu          sync.Mutex
+	traceparent []string
+}
+
+func newTraceServer(t *testing.T, status int, body string) *traceServer {
+	s := &traceServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.traceparent = append(s.traceparent, r.Header.Get("traceparent"))
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newClient(t *testing.T, server string, opts ...runx.ClientOption) (*runx.ClientWithResponses, *tracetest.SpanRecorder) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	opts = append([]runx.ClientOption{tracing.WithTracing(tracing.WithTracerProvider(tp))}, opts...)
+	client, err := runx.NewClientWithResponses(server, "key", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, sr
+}
+
+func attrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	m := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func onlySpan(t *testing.T, sr *tracetest.SpanRecorder) sdktrace.ReadOnlySpan {
+	t.Helper()
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("%d spans ended, want 1", len(spans))
+	}
+	return spans[0]
+}
+
+func TestTracingSpan(t *testing.T) {
+	srv := newTraceServer(t, http.StatusOK, `{"id":"app-1"}`)
+	client, sr := newClient(t, srv.URL)
+
+	rsp, err := client.GetAppWithResponse(context.Background(), "app-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.StatusCode() != http.StatusOK {
+		t.Fatalf("status = %d", rsp.StatusCode())
+	}
+	span := onlySpan(t, sr)
+	if span.Name() != "runx.GetApp" || span.SpanKind() != trace.SpanKindClient {
+		t.Errorf("span = %s of kind %s, want runx.GetApp of kind client", span.Name(), span.SpanKind())
+	}
+	if span.InstrumentationScope().Name != tracing.ScopeName {
+		t.Errorf("scope = %s, want %s", span.InstrumentationScope().Name, tracing.ScopeName)
+	}
+	want := map[attribute.Key]attribute.Value{
+		semconv.HTTPRequestMethodKey:      attribute.StringValue(http.MethodGet),
+		semconv.URLFullKey:                attribute.StringValue(srv.URL + "/app/app-1"),
+		semconv.ServerAddressKey:          attribute.StringValue("127.0.0.1"),
+		semconv.HTTPResponseStatusCodeKey: attribute.IntValue(http.StatusOK),
+		tracing.OperationKey:              attribute.StringValue("GetApp"),
+		tracing.AppIdKey:                  attribute.StringValue("app-1"),
+	}
+	got := attrs(span)
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("attribute %s = %v, want %v", k, got[k].Emit(), v.Emit())
+		}
+	}
+	for _, k := range []attribute.Key{tracing.SessionIdKey, tracing.ErrorMsgKey, semconv.ErrorTypeKey} {
+		if _, ok := got[k]; ok {
+			t.Errorf("unexpected attribute %s = %s", k, got[k].Emit())
+		}
+	}
+	if span.Status().Code != codes.Unset {
+		t.Errorf("status = %v, want unset", span.Status())
+	}
+
+	// the server receives the context of the span
+	sc := span.SpanContext()
+	want01 := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"
+	if len(srv.traceparent) != 1 || srv.traceparent[0] != want01 {
+		t.Errorf("traceparent = %q, want %q", srv.traceparent, want01)
+	}
+}
+
+func TestTracingParent(t *testing.T) {
+	srv := newTraceServer(t, http.StatusOK, `{}`)
+	client, sr := newClient(t, srv.URL)
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+
+	ctx, parent := tracer.Start(context.Background(), "parent")
+	if _, err := client.DeleteSessionWithResponse(ctx, "sess-1"); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	span := onlySpan(t, sr)
+	if span.Parent().SpanID() != parent.SpanContext().SpanID() || span.SpanContext().TraceID() != parent.SpanContext().TraceID() {
+		t.Errorf("span parent = %s, want %s", span.Parent().SpanID(), parent.SpanContext().SpanID())
+	}
+	got := attrs(span)
+	if got[tracing.SessionIdKey] != attribute.StringValue("sess-1") || got[tracing.OperationKey] != attribute.StringValue("DeleteSession") {
+		t.Errorf("attributes = %v", span.Attributes())
+	}
+	if _, ok := got[tracing.AppIdKey]; ok {
+		t.Errorf("unexpected attribute %s", tracing.AppIdKey)
+	}
+}
+
+func TestTracingErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		opts    []runx.ClientOption
+		message string
+	}{
+		{
+			name:    "not found",
+			status:  http.StatusNotFound,
+			body:    `{"error":"app not found"}`,
+			message: "app not found",
+		},
+		{
+			name:    "server error without a message",
+			status:  http.StatusInternalServerError,
+			body:    `{}`,
+			message: "Internal Server Error",
+		},
+		{
+			// WithAPIErrors is inner: the span records the *APIError
+			name:    "api errors",
+			status:  http.StatusTooManyRequests,
+			body:    `{"error":"slow down"}`,
+			opts:    []runx.ClientOption{runx.WithAPIErrors()},
+			message: "slow down",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTraceServer(t, tt.status, tt.body)
+			client, sr := newClient(t, srv.URL, tt.opts...)
+
+			rsp, err := client.GetAppWithResponse(context.Background(), "app-1")
+			if tt.opts == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				// the span leaves the body to the caller
+				if string(rsp.Body) != tt.body {
+					t.Errorf("body = %q, want %q", rsp.Body, tt.body)
+				}
+			} else if !errors.Is(err, runx.ErrRateLimited) {
+				t.Errorf("err = %v, want ErrRateLimited", err)
+			}
+			checkErrorSpan(t, onlySpan(t, sr), tt.status, tt.message)
+		})
+	}
+
+	t.Run("api errors outermost", func(t *testing.T) {
+		srv := newTraceServer(t, http.StatusNotFound, `{"error":"app not found"}`)
+		sr := tracetest.NewSpanRecorder()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+		// WithTracing is inner: the span sees the response
+		client, err := runx.NewClientWithResponses(srv.URL, "key", runx.WithAPIErrors(), tracing.WithTracing(tracing.WithTracerProvider(tp)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.GetAppWithResponse(context.Background(), "app-1"); !errors.Is(err, runx.ErrNotFound) {
+			t.Errorf("err = %v, want ErrNotFound", err)
+		}
+		checkErrorSpan(t, onlySpan(t, sr), http.StatusNotFound, "app not found")
+	})
+}
+
+func checkErrorSpan(t *testing.T, span sdktrace.ReadOnlySpan, status int, message string) {
+	t.Helper()
+	if span.Status().Code != codes.Error || span.Status().Description != message {
+		t.Errorf("status = %v, want error %q", span.Status(), message)
+	}
+	got := attrs(span)
+	want := map[attribute.Key]attribute.Value{
+		semconv.HTTPResponseStatusCodeKey: attribute.IntValue(status),
+		semconv.ErrorTypeKey:              attribute.StringValue(strconv.Itoa(status)),
+		tracing.ErrorMsgKey:               attribute.StringValue(message),
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("attribute %s = %v, want %v", k, got[k].Emit(), v.Emit())
+		}
+	}
+	if events := span.Events(); len(events) != 1 || events[0].Name != "exception" {
+		t.Errorf("events = %v, want the recorded error", events)
+	}
+}
+
+func TestTracingTransportError(t *testing.T) {
+	srv := newTraceServer(t, http.StatusOK, `{}`)
+	srv.Close()
+
+	tests := []struct {
+		name    string
+		ctx     func() (context.Context, context.CancelFunc)
+		errType string
+	}{
+		{"connection refused", func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) }, "_OTHER"},
+		{"canceled", func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			return ctx, cancel
+		}, "canceled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, sr := newClient(t, srv.URL)
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			_, err := client.GetAppWithResponse(ctx, "app-1")
+			if err == nil {
+				t.Fatal("GetApp() succeeded on a closed server")
+			}
+			span := onlySpan(t, sr)
+			if span.Status().Code != codes.Error {
+				t.Errorf("status = %v, want error", span.Status())
+			}
+			got := attrs(span)
+			if got[semconv.ErrorTypeKey] != attribute.StringValue(tt.errType) {
+				t.Errorf("error.type = %s, want %s", got[semconv.ErrorTypeKey].Emit(), tt.errType)
+			}
+			if _, ok := got[semconv.HTTPResponseStatusCodeKey]; ok {
+				t.Errorf("unexpected attribute %s", semconv.HTTPResponseStatusCodeKey)
+			}
+			if got[tracing.ErrorMsgKey].AsString() == "" {
+				t.Errorf("attribute %s is empty", tracing.ErrorMsgKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package runx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidRequest is matched by the ValidationErrors returned when a request
+// is rejected before being sent.
+var ErrInvalidRequest = errors.New("runx: invalid request")
+
+// ValidationError is a problem found in a request before sending it.
+type ValidationError struct {
+	// Index is the position of the app in CreateAppRequest.Apps, or -1 when
+	// the problem concerns the request as a whole.
+	Index int `json:"index"`
+
+	// Field is the JSON name of the offending field, such as "app" or "cpu".
+	Field string `json:"field"`
+
+	Message string `json:"message"`
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("%s: %s", e.Field, e.Message)
+	}
+	return fmt.Sprintf("apps[%d].%s: %s", e.Index, e.Field, e.Message)
+}
+
+// ValidationErrors lists every problem found in a request.
+type ValidationErrors []*ValidationError
+
+// Error implements the error interface.
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "runx: invalid request: " + strings.Join(msgs, "; ")
+}
+
+// Is matches ErrInvalidRequest.
+func (e ValidationErrors) Is(target error) bool {
+	return target == ErrInvalidRequest
+}
+
+// add records a problem of the app at index, or of the request when index is -1.
+func (e *ValidationErrors) add(index int, field, format string, args ...any) {
+	*e = append(*e, &ValidationError{Index: index, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns e, or nil when it is empty.
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Validator checks app requests against the catalog and the level of the
+// user before they are sent, reporting every problem at once instead of the
+// first one the server would answer with a 400, 401, 404 or 409.
+type Validator struct {
+	Catalog *Catalog
+
+	// Level is the level of the user, compared with the level of the
+	// catalog apps and packs.
+	Level int
+}
+
+// NewValidator returns a Validator for the user of c, fetching the catalog
+// through GetCatalogApps and the level of the user through Me.
+func (c *ClientWithResponses) NewValidator(ctx context.Context) (*Validator, error) {
+	cat, err := c.GetCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+	me, err := c.MeWithResponse(ctx)
+	if err == nil {
+		err = CheckResponse("Me", me.HTTPResponse, me.Body)
+	}
+	if err != nil {
+		return nil, err
+	}
+	v := &Validator{Catalog: cat}
+	if me.JSON200 != nil && me.JSON200.User != nil && me.JSON200.User.Level != nil {
+		v.Level = *me.JSON200.User.Level
+	}
+	return v, nil
+}
+
+// ValidateCreate checks req: the pack and the catalog apps must exist, be
+// enabled and be allowed at the level of the user, the resources must lie
+// between the catalog Threshold and Limit, GPUs must be authorized and
+// available, and the environment variables must read KEY=VALUE. It returns
+// ValidationErrors listing every problem, or nil.
+func (v *Validator) ValidateCreate(req CreateAppRequest) error {
+	var errs ValidationErrors
+	if req.Pack != nil {
+		pack := v.Catalog.Pack(*req.Pack)
+		switch {
+		case pack == nil:
+			errs.add(-1, "pack", "unknown pack %q", *req.Pack)
+			return errs
+		case pack.Enabled != nil && !*pack.Enabled:
+			errs.add(-1, "pack", "pack %q is disabled", *req.Pack)
+		case pack.Level != nil && *pack.Level > v.Level:
+			errs.add(-1, "pack", "pack %q requires level %d, the user has level %d", *req.Pack, *pack.Level, v.Level)
+		}
+	}
+	requests, err := v.Catalog.Requests(req)
+	if err != nil {
+		return err
+	}
+	if len(requests) == 0 {
+		errs.add(-1, "apps", "no app requested")
+	}
+
+	names := map[string]int{}
+	gpus := 0
+	for i, r := range requests {
+		if r.Name == "" {
+			errs.add(i, "name", "required")
+		} else if j, ok := names[r.Name]; ok {
+			errs.add(i, "name", "%q is already used by apps[%d]", r.Name, j)
+		} else {
+			names[r.Name] = i
+		}
+		switch app := v.Catalog.App(r.App); {
+		case r.App == "":
+			errs.add(i, "app", "required")
+		case app == nil:
+			errs.add(i, "app", "unknown catalog app %q", r.App)
+		case app.Enabled != nil && !*app.Enabled:
+			errs.add(i, "app", "catalog app %q is disabled", r.App)
+		case app.Level != nil && *app.Level > v.Level:
+			errs.add(i, "app", "catalog app %q requires level %d, the user has level %d", r.App, *app.Level, v.Level)
+		}
+		v.checkResources(&errs, i, r.Cpu, r.Ram, r.Disk, r.Gpu, r.Env)
+		if r.Gpu != nil {
+			gpus += *r.Gpu
+		}
+	}
+	if gpus > 0 && v.Catalog.GpuAuthorized && gpus > v.Catalog.AvailableGpus {
+		errs.add(-1, "gpu", "%d GPUs requested, %d available", gpus, v.Catalog.AvailableGpus)
+	}
+	return errs.err()
+}
+
+// ValidateUpdate checks req like ValidateCreate. When current, the app being
+// updated, is given and enabled, the additional GPUs must be available.
+func (v *Validator) ValidateUpdate(req UpdateAppRequest, current *AppExtended) error {
+	var errs ValidationErrors
+	if req.Name != nil && *req.Name == "" {
+		errs.add(-1, "name", "cannot be empty")
+	}
+	v.checkResources(&errs, -1, req.Cpu, req.Ram, req.Disk, req.Gpu, req.Env)
+	if req.Gpu != nil && current != nil && billed(*current) && v.Catalog.GpuAuthorized {
+		used := 0
+		if current.Gpu != nil {
+			used = *current.Gpu
+		}
+		if extra := *req.Gpu - used; extra > v.Catalog.AvailableGpus {
+			errs.add(-1, "gpu", "%d more GPUs requested, %d available", extra, v.Catalog.AvailableGpus)
+		}
+	}
+	return errs.err()
+}
+
+func (v *Validator) checkResources(errs *ValidationErrors, index int, cpu, ram, disk, gpu *int, env *[]string) {
+	for _, r := range []struct {
+		name  string
+		value *int
+	}{{"cpu", cpu}, {"ram", ram}, {"disk", disk}, {"gpu", gpu}} {
+		if r.value == nil {
+			continue
+		}
+		if limit, ok := v.Catalog.Limit[r.name]; ok && *r.value > limit {
+			errs.add(index, r.name, "%d exceeds the limit of %d", *r.value, limit)
+		}
+		if threshold, ok := v.Catalog.Threshold[r.name]; ok && *r.value < threshold {
+			errs.add(index, r.name, "%d is below the minimum of %d", *r.value, threshold)
+		}
+	}
+	if gpu != nil && *gpu > 0 && !v.Catalog.GpuAuthorized {
+		errs.add(index, "gpu", "GPU usage is not authorized for this account")
+	}
+	if env != nil {
+		for j, kv := range *env {
+			if key, _, ok := strings.Cut(kv, "="); !ok || !envName.MatchString(key) {
+				errs.add(index, fmt.Sprintf("env[%d]", j), "%q is not a KEY=VALUE variable", kv)
+			}
+		}
+	}
+}
+
+// envName matches the name of an environment variable.
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// WithValidation checks the CreateApp and UpdateApp requests with a Validator
+// before sending them, failing with ValidationErrors without reaching the
+// server when they are invalid. The catalog, the user and, for UpdateApp, the
+// apps are fetched with the credentials of the checked request.
+func WithValidation() ClientOption {
+	return WithDoerMiddleware(func(next HttpRequestDoer) HttpRequestDoer {
+		return DoerFunc(func(req *http.Request) (*http.Response, error) {
+			op, params := matchOperation(req)
+			if op == nil || (op.name != "CreateApp" && op.name != "UpdateApp") {
+				return next.Do(req)
+			}
+			ctx := req.Context()
+			client := guardClient(req, op, next)
+			v, err := client.NewValidator(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("runx: validation: %w", err)
+			}
+			if op.name == "CreateApp" {
+				var body CreateAppRequest
+				if err := decodeRequestBody(req, &body); err != nil {
+					return nil, fmt.Errorf("runx: validation: %w", err)
+				}
+				err = v.ValidateCreate(body)
+			} else {
+				var body UpdateAppRequest
+				if err := decodeRequestBody(req, &body); err != nil {
+					return nil, fmt.Errorf("runx: validation: %w", err)
+				}
+				apps, listErr := client.listApps(ctx)
+				if listErr != nil {
+					return nil, fmt.Errorf("runx: validation: %w", listErr)
+				}
+				err = v.ValidateUpdate(body, findApp(apps, params["appId"]))
+			}
+			if err != nil {
+				return nil, err
+			}
+			return next.Do(req)
+		})
+	})
+}
@@ -0,0 +1,205 @@
+package runx_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	runx "github.com/run-x-app/runx-go"
+	"github.com/run-x-app/runx-go/runxtest"
+)
+
+func testValidator() *runx.Validator {
+	return &runx.Validator{
+		Level: 1,
+		Catalog: &runx.Catalog{
+			Apps: []runx.CatalogApp{
+				{Id: ptr("nginx")},
+				{Id: ptr("jupyter"), Level: ptr(1), Gpu: ptr(1)},
+				{Id: ptr("premium"), Level: ptr(2)},
+				{Id: ptr("legacy"), Enabled: ptr(false)},
+			},
+			Packs: []runx.Pack{
+				{Id: ptr("web"), Apps: &[]string{"nginx", "jupyter"}},
+				{Id: ptr("enterprise"), Apps: &[]string{"premium"}, Level: ptr(2)},
+				{Id: ptr("old"), Apps: &[]string{"nginx"}, Enabled: ptr(false)},
+			},
+			Limit:         map[string]int{"cpu": 8, "ram": 32768, "disk": 102400, "gpu": 2},
+			Threshold:     map[string]int{"cpu": 1, "ram": 256, "disk": 1024},
+			AvailableGpus: 1,
+			GpuAuthorized: true,
+		},
+	}
+}
+
+func TestValidateCreate(t *testing.T) {
+	app := func(name, catalogApp string) runx.AppRequest {
+		return runx.AppRequest{Name: name, App: catalogApp}
+	}
+	tests := []struct {
+		name string
+		req  runx.CreateAppRequest
+		want []string
+	}{
+		{name: "valid", req: runx.CreateAppRequest{Apps: []runx.AppRequest{app("web", "nginx"), {Name: "lab", App: "jupyter", Gpu: ptr(1), Cpu: ptr(8), Env: &[]string{"A=1", "_B=", "C=x=y"}}}}},
+		{name: "valid pack", req: runx.CreateAppRequest{Pack: ptr("web")}},
+		{name: "no app", req: runx.CreateAppRequest{}, want: []string{"apps: no app requested"}},
+		{name: "unknown pack", req: runx.CreateAppRequest{Pack: ptr("missing")}, want: []string{`pack: unknown pack "missing"`}},
+		{name: "disabled pack", req: runx.CreateAppRequest{Pack: ptr("old")}, want: []string{`pack: pack "old" is disabled`}},
+		{
+			name: "pack above the level",
+			req:  runx.CreateAppRequest{Pack: ptr("enterprise")},
+			want: []string{`pack: pack "enterprise" requires level 2, the user has level 1`, `apps[0].app: catalog app "premium" requires level 2, the user has level 1`},
+		},
+		{name: "missing name", req: runx.CreateAppRequest{Apps: []runx.AppRequest{app("", "nginx")}}, want: []string{"apps[0].name: required"}},
+		{name: "duplicate name", req: runx.CreateAppRequest{Apps: []runx.AppRequest{app("web", "nginx"), app("db", "nginx"), app("web", "nginx")}}, want: []string{`apps[2].name: "web" is already used by apps[0]`}},
+		{name: "missing app", req: runx.CreateAppRequest{Apps: []runx.AppRequest{app("web", "")}}, want: []string{"apps[0].app: required"}},
+		{name: "unknown app", req: runx.CreateAppRequest{Apps: []runx.AppRequest{app("web", "redis")}}, want: []string{`apps[0].app: unknown catalog app "redis"`}},
+		{name: "disabled app", req: runx.CreateAppRequest{Apps: []runx.AppRequest{app("web", "legacy")}}, want: []string{`apps[0].app: catalog app "legacy" is disabled`}},
+		{name: "app above the level", req: runx.CreateAppRequest{Apps: []runx.AppRequest{app("web", "premium")}}, want: []string{`apps[0].app: catalog app "premium" requires level 2, the user has level 1`}},
+		{
+			name: "resources out of bounds",
+			req:  runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "web", App: "nginx", Cpu: ptr(9), Ram: ptr(128), Disk: ptr(200000)}}},
+			want: []string{"apps[0].cpu: 9 exceeds the limit of 8", "apps[0].ram: 128 is below the minimum of 256", "apps[0].disk: 200000 exceeds the limit of 102400"},
+		},
+		{name: "below the minimum cpu", req: runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "web", App: "nginx", Cpu: ptr(0)}}}, want: []string{"apps[0].cpu: 0 is below the minimum of 1"}},
+		{
+			name: "GPUs over the availability",
+			req:  runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "a", App: "jupyter", Gpu: ptr(1)}, {Name: "b", App: "jupyter", Gpu: ptr(1)}}},
+			want: []string{"gpu: 2 GPUs requested, 1 available"},
+		},
+		{name: "GPUs over the limit", req: runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "a", App: "jupyter", Gpu: ptr(3)}}}, want: []string{"apps[0].gpu: 3 exceeds the limit of 2", "gpu: 3 GPUs requested, 1 available"}},
+		{
+			name: "invalid environment",
+			req:  runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "web", App: "nginx", Env: &[]string{"OK=1", "NOVALUE", "1A=x", "=x", "A-B=x"}}}},
+			want: []string{`apps[0].env[1]: "NOVALUE" is not a KEY=VALUE variable`, `apps[0].env[2]: "1A=x" is not a KEY=VALUE variable`, `apps[0].env[3]: "=x" is not a KEY=VALUE variable`, `apps[0].env[4]: "A-B=x" is not a KEY=VALUE variable`},
+		},
+		{
+			name: "every problem at once",
+			req:  runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "", App: "redis", Cpu: ptr(64)}, app("web", "legacy")}},
+			want: []string{"apps[0].name: required", `apps[0].app: unknown catalog app "redis"`, "apps[0].cpu: 64 exceeds the limit of 8", `apps[1].app: catalog app "legacy" is disabled`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkValidation(t, testValidator().ValidateCreate(tt.req), tt.want)
+		})
+	}
+
+	t.Run("GPUs not authorized", func(t *testing.T) {
+		v := testValidator()
+		v.Catalog.GpuAuthorized = false
+		err := v.ValidateCreate(runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "a", App: "jupyter", Gpu: ptr(2)}}})
+		checkValidation(t, err, []string{"apps[0].gpu: GPU usage is not authorized for this account"})
+	})
+}
+
+func TestValidateUpdate(t *testing.T) {
+	running := &runx.AppExtended{Id: ptr("a1"), Enabled: ptr(true), Gpu: ptr(1)}
+	stopped := &runx.AppExtended{Id: ptr("a1"), Enabled: ptr(false)}
+	tests := []struct {
+		name    string
+		req     runx.UpdateAppRequest
+		current *runx.AppExtended
+		want    []string
+	}{
+		{name: "valid", req: runx.UpdateAppRequest{Name: ptr("web"), Cpu: ptr(2), Env: &[]string{"A=1"}}},
+		{name: "empty request", req: runx.UpdateAppRequest{}},
+		{name: "empty name", req: runx.UpdateAppRequest{Name: ptr("")}, want: []string{"name: cannot be empty"}},
+		{name: "resources", req: runx.UpdateAppRequest{Cpu: ptr(16), Ram: ptr(1)}, want: []string{"cpu: 16 exceeds the limit of 8", "ram: 1 is below the minimum of 256"}},
+		{name: "environment", req: runx.UpdateAppRequest{Env: &[]string{"A"}}, want: []string{`env[0]: "A" is not a KEY=VALUE variable`}},
+		// the running app already holds one GPU, and one more is available
+		{name: "additional GPU available", req: runx.UpdateAppRequest{Gpu: ptr(2)}, current: running},
+		{name: "additional GPUs unavailable", req: runx.UpdateAppRequest{Gpu: ptr(2)}, current: &runx.AppExtended{Enabled: ptr(true)}, want: []string{"gpu: 2 more GPUs requested, 1 available"}},
+		// a disabled app takes its GPUs when enabled, not now
+		{name: "GPUs of a disabled app", req: runx.UpdateAppRequest{Gpu: ptr(2)}, current: stopped},
+		{name: "GPUs without the current app", req: runx.UpdateAppRequest{Gpu: ptr(2)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkValidation(t, testValidator().ValidateUpdate(tt.req, tt.current), tt.want)
+		})
+	}
+}
+
+func checkValidation(t *testing.T, err error, want []string) {
+	t.Helper()
+	if want == nil {
+		if err != nil {
+			t.Errorf("error = %v, want none", err)
+		}
+		return
+	}
+	var errs runx.ValidationErrors
+	if !errors.As(err, &errs) || !errors.Is(err, runx.ErrInvalidRequest) {
+		t.Fatalf("error = %v, want ValidationErrors", err)
+	}
+	got := make([]string, len(errs))
+	for i, e := range errs {
+		got[i] = e.Error()
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// countRequests counts the requests of each operation that get past the
+// middlewares registered before it.
+func countRequests(counts map[string]int) runx.ClientOption {
+	return runx.WithDoerMiddleware(func(next runx.HttpRequestDoer) runx.HttpRequestDoer {
+		return runx.DoerFunc(func(req *http.Request) (*http.Response, error) {
+			counts[runx.OperationName(req)]++
+			return next.Do(req)
+		})
+	})
+}
+
+func TestWithValidation(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	counts := map[string]int{}
+	client := srv.Client(runx.WithValidation(), countRequests(counts))
+
+	if err := createApps(ctx, client, runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "web", App: "nginx", Cpu: ptr(2)}}}); err != nil {
+		t.Fatalf("CreateApp() = %v", err)
+	}
+	if counts["CreateApp"] != 1 || counts["GetCatalogApps"] != 1 || counts["Me"] != 1 {
+		t.Errorf("requests = %v, want the catalog, the user and the creation", counts)
+	}
+
+	// an invalid request never reaches the server
+	clear(counts)
+	_, err := client.CreateAppWithResponse(ctx, runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "web", App: "redis", Cpu: ptr(64)}}})
+	checkValidation(t, err, []string{`apps[0].app: unknown catalog app "redis"`, "apps[0].cpu: 64 exceeds the limit of 8"})
+	if counts["CreateApp"] != 0 || len(srv.Apps()) != 1 {
+		t.Errorf("requests = %v, want no CreateApp", counts)
+	}
+
+	// updates are checked against the app being updated
+	id := appByName(t, srv, "web")
+	if rsp, err := client.UpdateAppWithResponse(ctx, id, runx.UpdateAppRequest{Cpu: ptr(4)}); err != nil || rsp.StatusCode() != http.StatusOK {
+		t.Errorf("UpdateApp() = %v, %v", rsp, err)
+	}
+	clear(counts)
+	_, err = client.UpdateAppWithResponse(ctx, id, runx.UpdateAppRequest{Name: ptr(""), Env: &[]string{"oops"}})
+	checkValidation(t, err, []string{"name: cannot be empty", `env[0]: "oops" is not a KEY=VALUE variable`})
+	if counts["UpdateApp"] != 0 || counts["GetApps"] != 1 {
+		t.Errorf("requests = %v, want the apps listed and no UpdateApp", counts)
+	}
+
+	// other requests are not checked
+	clear(counts)
+	if _, err := client.GetAppsWithResponse(ctx); err != nil || counts["GetCatalogApps"] != 0 {
+		t.Errorf("GetApps() = %v, requests %v", err, counts)
+	}
+
+	// a failure to fetch the catalog stops the request
+	srv.InjectFault(runxtest.Fault{Operation: "GetCatalogApps", StatusCode: http.StatusInternalServerError, Times: 1})
+	err = createApps(ctx, client, runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: "db", App: "postgres"}}})
+	if !errors.Is(err, runx.ErrServer) || !strings.HasPrefix(err.Error(), "runx: validation:") {
+		t.Errorf("CreateApp() error = %v, want the catalog failure", err)
+	}
+}
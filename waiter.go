@@ -0,0 +1,253 @@
+package runx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Known values of AppExtended.Status.
+const (
+	AppStatusPending = "pending"
+	AppStatusRunning = "running"
+	AppStatusStopped = "stopped"
+	AppStatusFailed  = "failed"
+	AppStatusError   = "error"
+	AppStatusCrashed = "crashed"
+)
+
+// ErrAppFailed is matched by the *AppFailedError returned when an app reaches
+// a failure status while being waited for.
+var ErrAppFailed = errors.New("runx: app failed")
+
+// AppFailedError reports an app which reached a failure status.
+type AppFailedError struct {
+	App    *AppExtended
+	Status string
+}
+
+// Error implements the error interface.
+func (e *AppFailedError) Error() string {
+	return fmt.Sprintf("runx: app %s reached status %q", appLabel(e.App), e.Status)
+}
+
+// Is matches ErrAppFailed.
+func (e *AppFailedError) Is(target error) bool {
+	return target == ErrAppFailed
+}
+
+// WaitOption configures the polling done by the WaitUntil* methods.
+type WaitOption func(*waitConfig)
+
+type waitConfig struct {
+	interval    time.Duration
+	maxInterval time.Duration
+	timeout     time.Duration
+	failures    []string
+}
+
+// WithPollInterval sets the wait before the first poll, doubled after every
+// poll up to WithMaxPollInterval. It defaults to two seconds.
+func WithPollInterval(d time.Duration) WaitOption {
+	return func(c *waitConfig) {
+		c.interval = d
+	}
+}
+
+// WithMaxPollInterval caps the wait between two polls. It defaults to 30 seconds.
+func WithMaxPollInterval(d time.Duration) WaitOption {
+	return func(c *waitConfig) {
+		c.maxInterval = d
+	}
+}
+
+// WithWaitTimeout bounds the wait in addition to the context deadline.
+func WithWaitTimeout(d time.Duration) WaitOption {
+	return func(c *waitConfig) {
+		c.timeout = d
+	}
+}
+
+// WithFailureStatuses overrides the statuses considered terminal failures,
+// which default to failed, error and crashed.
+func WithFailureStatuses(statuses ...string) WaitOption {
+	return func(c *waitConfig) {
+		c.failures = statuses
+	}
+}
+
+func newWaitConfig(opts []WaitOption) *waitConfig {
+	cfg := &waitConfig{
+		interval:    2 * time.Second,
+		maxInterval: 30 * time.Second,
+		failures:    []string{AppStatusFailed, AppStatusError, AppStatusCrashed},
+	}
+	for _, o := range opts {
+		o(cfg)
+	}
+	return cfg
+}
+
+// WaitUntilRunning polls GetApps until the app identified by appId, either its
+// Id or ShortId, is running.
+func (c *ClientWithResponses) WaitUntilRunning(ctx context.Context, appId string, opts ...WaitOption) (*AppExtended, error) {
+	return c.WaitForAppStatus(ctx, appId, []string{AppStatusRunning}, opts...)
+}
+
+// WaitUntilStopped polls GetApps until the app identified by appId is stopped.
+func (c *ClientWithResponses) WaitUntilStopped(ctx context.Context, appId string, opts ...WaitOption) (*AppExtended, error) {
+	return c.WaitForAppStatus(ctx, appId, []string{AppStatusStopped}, opts...)
+}
+
+// WaitUntilDeleted polls GetApps until the app identified by appId is no
+// longer listed.
+func (c *ClientWithResponses) WaitUntilDeleted(ctx context.Context, appId string, opts ...WaitOption) error {
+	cfg := newWaitConfig(opts)
+	_, err := c.poll(ctx, cfg, func(apps []AppExtended) (*AppExtended, bool, error) {
+		app := findApp(apps, appId)
+		return app, app == nil, nil
+	})
+	if err != nil {
+		return fmt.Errorf("runx: waiting for app %s to be deleted: %w", appId, err)
+	}
+	return nil
+}
+
+// WaitForAppStatus polls GetApps with an exponential backoff until the app
+// identified by appId reaches one of the target statuses, and returns it. An
+// *AppFailedError is returned if the app reaches a failure status first.
+func (c *ClientWithResponses) WaitForAppStatus(ctx context.Context, appId string, targets []string, opts ...WaitOption) (*AppExtended, error) {
+	cfg := newWaitConfig(opts)
+	app, err := c.poll(ctx, cfg, func(apps []AppExtended) (*AppExtended, bool, error) {
+		app := findApp(apps, appId)
+		if app == nil {
+			return nil, false, fmt.Errorf("runx: app %s: %w", appId, ErrNotFound)
+		}
+		status := ""
+		if app.Status != nil {
+			status = *app.Status
+		}
+		if matchStatus(status, targets) {
+			return app, true, nil
+		}
+		if matchStatus(status, cfg.failures) {
+			return app, false, &AppFailedError{App: app, Status: status}
+		}
+		return app, false, nil
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrAppFailed) {
+		last := ""
+		if app != nil && app.Status != nil {
+			last = *app.Status
+		}
+		err = fmt.Errorf("runx: waiting for app %s to be %s, last status %q: %w", appId, strings.Join(targets, " or "), last, err)
+	}
+	return app, err
+}
+
+// poll lists the apps until check is done, fails, or the context expires.
+// Transient failures of GetApps are retried on the next poll. The last app
+// returned by check is returned in every case.
+func (c *ClientWithResponses) poll(ctx context.Context, cfg *waitConfig, check func([]AppExtended) (*AppExtended, bool, error)) (*AppExtended, error) {
+	if cfg.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+		defer cancel()
+	}
+
+	var last *AppExtended
+	interval := cfg.interval
+	for {
+		apps, err := c.listApps(ctx)
+		switch {
+		case err == nil:
+			app, done, err := check(apps)
+			if app != nil {
+				last = app
+			}
+			if done || err != nil {
+				return last, err
+			}
+		case !transient(err):
+			return last, err
+		case ctx.Err() != nil:
+			return last, ctx.Err()
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return last, ctx.Err()
+		case <-timer.C:
+		}
+		interval *= 2
+		if interval > cfg.maxInterval {
+			interval = cfg.maxInterval
+		}
+	}
+}
+
+// listApps returns the apps of the user, turning error responses into an
+// *APIError. The apps field is optional, so a response without it lists no
+// app; a body that cannot be decoded already fails in ParseGetAppsResponse,
+// and one that is not JSON is not taken for an empty list.
+func (c *ClientWithResponses) listApps(ctx context.Context) ([]AppExtended, error) {
+	rsp, err := c.GetAppsWithResponse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckResponse("GetApps", rsp.HTTPResponse, rsp.Body); err != nil {
+		return nil, err
+	}
+	if rsp.JSON200 == nil {
+		return nil, fmt.Errorf("runx: GetApps: unexpected response %q", rsp.HTTPResponse.Header.Get("Content-Type"))
+	}
+	if rsp.JSON200.Apps == nil {
+		return []AppExtended{}, nil
+	}
+	return *rsp.JSON200.Apps, nil
+}
+
+// transient reports whether err is worth retrying on the next poll.
+func transient(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500 || apiErr.StatusCode == http.StatusTooManyRequests
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// findApp returns the app whose Id or ShortId is id.
+func findApp(apps []AppExtended, id string) *AppExtended {
+	for i := range apps {
+		if (apps[i].Id != nil && *apps[i].Id == id) || (apps[i].ShortId != nil && *apps[i].ShortId == id) {
+			return &apps[i]
+		}
+	}
+	return nil
+}
+
+func matchStatus(status string, statuses []string) bool {
+	for _, s := range statuses {
+		if strings.EqualFold(status, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func appLabel(app *AppExtended) string {
+	switch {
+	case app == nil:
+		return "<unknown>"
+	case app.Name != nil:
+		return *app.Name
+	case app.Id != nil:
+		return *app.Id
+	}
+	return "<unknown>"
+}
@@ -0,0 +1,206 @@
+package runx_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	runx "github.com/run-x-app/runx-go"
+	"github.com/run-x-app/runx-go/runxtest"
+)
+
+// fastPoll polls the fake server often enough for the tests.
+var fastPoll = []runx.WaitOption{runx.WithPollInterval(5 * time.Millisecond), runx.WithMaxPollInterval(5 * time.Millisecond), runx.WithWaitTimeout(time.Second)}
+
+func waitOptions(opts ...runx.WaitOption) []runx.WaitOption {
+	return append(append([]runx.WaitOption{}, fastPoll...), opts...)
+}
+
+// newApp creates an app named name and returns it as created.
+func newApp(t *testing.T, client *runx.ClientWithResponses, name string) runx.App {
+	t.Helper()
+	rsp, err := client.CreateAppWithResponse(context.Background(), runx.CreateAppRequest{Apps: []runx.AppRequest{{Name: name, App: "nginx"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rsp.JSON200 == nil || rsp.JSON200.Apps == nil || len(*rsp.JSON200.Apps) != 1 {
+		t.Fatalf("CreateApp() = %d %s", rsp.StatusCode(), rsp.Body)
+	}
+	return (*rsp.JSON200.Apps)[0]
+}
+
+func TestWaitUntilRunning(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer(runxtest.WithStartDelay(30 * time.Millisecond))
+	defer srv.Close()
+	client := srv.Client(runx.WithAPIErrors())
+	app := newApp(t, client, "web")
+
+	for _, id := range []string{*app.Id, *app.ShortId} {
+		got, err := client.WaitUntilRunning(ctx, id, fastPoll...)
+		if err != nil {
+			t.Fatalf("WaitUntilRunning(%s) error = %v", id, err)
+		}
+		if *got.Id != *app.Id || *got.Status != runx.AppStatusRunning {
+			t.Errorf("WaitUntilRunning(%s) = %s %q, want %s running", id, *got.Id, *got.Status, *app.Id)
+		}
+	}
+
+	if _, err := client.EnableAppWithResponse(ctx, *app.Id, runx.False); err != nil {
+		t.Fatal(err)
+	}
+	got, err := client.WaitUntilStopped(ctx, *app.Id, fastPoll...)
+	if err != nil || *got.Status != runx.AppStatusStopped {
+		t.Errorf("WaitUntilStopped() = %v, %v, want stopped", got, err)
+	}
+
+	if _, err := client.DeleteAppWithResponse(ctx, *app.Id); err != nil {
+		t.Fatal(err)
+	}
+	// the last app of the account is deleted once GetApps lists none
+	if err := client.WaitUntilDeleted(ctx, *app.Id, fastPoll...); err != nil {
+		t.Errorf("WaitUntilDeleted() error = %v", err)
+	}
+	if _, err := client.WaitUntilRunning(ctx, *app.Id, fastPoll...); !errors.Is(err, runx.ErrNotFound) {
+		t.Errorf("WaitUntilRunning() of a deleted app error = %v, want %v", err, runx.ErrNotFound)
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	client := srv.Client(runx.WithAPIErrors())
+	app := newApp(t, client, "web")
+	srv.SetAppStatus(*app.Id, runx.AppStatusPending)
+
+	tests := []struct {
+		name string
+		ctx  func() (context.Context, context.CancelFunc)
+		opts []runx.WaitOption
+	}{
+		{
+			name: "wait timeout",
+			ctx:  func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) },
+			opts: waitOptions(runx.WithWaitTimeout(30 * time.Millisecond)),
+		},
+		{
+			name: "context deadline",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 30*time.Millisecond)
+			},
+			opts: fastPoll,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			start := time.Now()
+			got, err := client.WaitUntilRunning(ctx, *app.Id, tt.opts...)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("WaitUntilRunning() error = %v, want %v", err, context.DeadlineExceeded)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("WaitUntilRunning() returned after %s", elapsed)
+			}
+			// the last app seen is returned with the error
+			if got == nil || *got.Status != runx.AppStatusPending {
+				t.Errorf("WaitUntilRunning() = %v, want the pending app", got)
+			}
+			if !strings.Contains(err.Error(), `last status "pending"`) {
+				t.Errorf("error %q does not report the last status", err)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.WaitUntilDeleted(ctx, *app.Id, fastPoll...); !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitUntilDeleted() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestWaitFailure(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	client := srv.Client(runx.WithAPIErrors())
+
+	tests := []struct {
+		status string
+		opts   []runx.WaitOption
+	}{
+		{status: runx.AppStatusFailed},
+		{status: runx.AppStatusError},
+		{status: runx.AppStatusCrashed},
+		{status: "CRASHED"},
+		{status: runx.AppStatusStopped, opts: []runx.WaitOption{runx.WithFailureStatuses(runx.AppStatusStopped)}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			app := newApp(t, client, fmt.Sprintf("app-%d", i))
+			srv.SetAppStatus(*app.Id, tt.status)
+			got, err := client.WaitUntilRunning(ctx, *app.Id, waitOptions(tt.opts...)...)
+			if !errors.Is(err, runx.ErrAppFailed) {
+				t.Fatalf("WaitUntilRunning() error = %v, want %v", err, runx.ErrAppFailed)
+			}
+			var failed *runx.AppFailedError
+			if !errors.As(err, &failed) || failed.Status != tt.status || *failed.App.Id != *app.Id {
+				t.Errorf("WaitUntilRunning() error = %#v", err)
+			}
+			if got == nil || *got.Id != *app.Id {
+				t.Errorf("WaitUntilRunning() = %v, want the failed app", got)
+			}
+		})
+	}
+
+	// a failure status is not one once overridden
+	app := newApp(t, client, "web")
+	srv.SetAppStatus(*app.Id, runx.AppStatusFailed)
+	if _, err := client.WaitUntilRunning(ctx, *app.Id, waitOptions(runx.WithFailureStatuses(), runx.WithWaitTimeout(20*time.Millisecond))...); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitUntilRunning() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWaitGetAppsErrors(t *testing.T) {
+	ctx := context.Background()
+	srv := runxtest.NewServer()
+	defer srv.Close()
+	client := srv.Client(runx.WithAPIErrors())
+	app := newApp(t, client, "web")
+
+	// server errors and rate limiting are retried on the next poll
+	for _, status := range []int{http.StatusInternalServerError, http.StatusTooManyRequests} {
+		srv.InjectFault(runxtest.Fault{Operation: "GetApps", StatusCode: status, Times: 2})
+		if _, err := client.WaitUntilRunning(ctx, *app.Id, fastPoll...); err != nil {
+			t.Errorf("WaitUntilRunning() after two %d error = %v", status, err)
+		}
+	}
+
+	srv.InjectFault(runxtest.Fault{Operation: "GetApps", StatusCode: http.StatusUnauthorized, Times: 1})
+	if _, err := client.WaitUntilRunning(ctx, *app.Id, fastPoll...); !errors.Is(err, runx.ErrUnauthorized) {
+		t.Errorf("WaitUntilRunning() error = %v, want %v", err, runx.ErrUnauthorized)
+	}
+}
+
+func TestWaitUntilDeletedWithoutApps(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{}`)
+	}))
+	defer srv.Close()
+	client, err := runx.NewClientWithResponses(srv.URL, "key", runx.WithAPIErrors())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the apps field is optional, and the last app is gone without it
+	if err := client.WaitUntilDeleted(context.Background(), "app-1", fastPoll...); err != nil {
+		t.Errorf("WaitUntilDeleted() error = %v", err)
+	}
+}
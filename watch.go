@@ -0,0 +1,233 @@
+package runx
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WatchEventType is the kind of change reported by a Watcher.
+type WatchEventType string
+
+// Defines values for WatchEventType.
+const (
+	WatchAdded    WatchEventType = "ADDED"
+	WatchModified WatchEventType = "MODIFIED"
+	WatchDeleted  WatchEventType = "DELETED"
+	WatchError    WatchEventType = "ERROR"
+)
+
+// WatchEvent is a change observed by a Watcher.
+type WatchEvent struct {
+	Type WatchEventType
+
+	// App is the current state of the app, or its last known state when deleted.
+	App AppExtended
+
+	// Previous is the prior state of a modified app.
+	Previous *AppExtended
+
+	// Err is set on WatchError events, after which the cache is left untouched.
+	Err error
+}
+
+// WatcherOption configures a Watcher.
+type WatcherOption func(*Watcher)
+
+// WithWatchInterval sets the time between two lists. It defaults to ten seconds.
+func WithWatchInterval(d time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.interval = d
+	}
+}
+
+// WithWatchBuffer sets the capacity of the events channel. It defaults to 64.
+func WithWatchBuffer(n int) WatcherOption {
+	return func(w *Watcher) {
+		w.buffer = n
+	}
+}
+
+// Watcher periodically lists the apps through GetApps and reports the
+// differences with the previous list as events. It keeps the last list in a
+// local cache which can be queried while it runs.
+type Watcher struct {
+	client   *ClientWithResponses
+	interval time.Duration
+	buffer   int
+	events   chan WatchEvent
+
+	mu     sync.RWMutex
+	cache  map[string]AppExtended
+	synced bool
+}
+
+// NewWatcher creates a Watcher listing the apps of client. Events are
+// delivered once Run is called.
+func NewWatcher(client *ClientWithResponses, opts ...WatcherOption) *Watcher {
+	w := &Watcher{
+		client:   client,
+		interval: 10 * time.Second,
+		buffer:   64,
+		cache:    map[string]AppExtended{},
+	}
+	for _, o := range opts {
+		o(w)
+	}
+	w.events = make(chan WatchEvent, w.buffer)
+	return w
+}
+
+// Events returns the channel delivering the events. It is closed when Run returns.
+func (w *Watcher) Events() <-chan WatchEvent {
+	return w.events
+}
+
+// Run lists the apps until ctx is done, then closes the events channel and
+// returns nil. A failed list is reported as a WatchError event and retried
+// on the next tick, without emitting any deletion.
+func (w *Watcher) Run(ctx context.Context) error {
+	defer close(w.events)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if !w.sync(ctx) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// sync lists the apps and emits the changes. It returns false once ctx is done.
+func (w *Watcher) sync(ctx context.Context) bool {
+	apps, err := w.client.listApps(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return false
+		}
+		return w.emit(ctx, WatchEvent{Type: WatchError, Err: err})
+	}
+
+	current := make(map[string]AppExtended, len(apps))
+	for _, app := range apps {
+		if app.Id != nil {
+			current[*app.Id] = app
+		}
+	}
+
+	w.mu.Lock()
+	previous := w.cache
+	w.cache = current
+	w.synced = true
+	w.mu.Unlock()
+
+	var events []WatchEvent
+	for id, app := range current {
+		old, ok := previous[id]
+		switch {
+		case !ok:
+			events = append(events, WatchEvent{Type: WatchAdded, App: app})
+		case appChanged(old, app):
+			events = append(events, WatchEvent{Type: WatchModified, App: app, Previous: &old})
+		}
+	}
+	for id, app := range previous {
+		if _, ok := current[id]; !ok {
+			events = append(events, WatchEvent{Type: WatchDeleted, App: app})
+		}
+	}
+	// deliver the events in a stable order
+	sort.SliceStable(events, func(i, j int) bool {
+		return *events[i].App.Id < *events[j].App.Id
+	})
+	for _, e := range events {
+		if !w.emit(ctx, e) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *Watcher) emit(ctx context.Context, e WatchEvent) bool {
+	select {
+	case w.events <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// HasSynced reports whether the cache has been filled by a successful list.
+func (w *Watcher) HasSynced() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.synced
+}
+
+// Get returns the cached app with the given Id.
+func (w *Watcher) Get(id string) (AppExtended, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	app, ok := w.cache[id]
+	return app, ok
+}
+
+// GetByShortId returns the cached app with the given ShortId.
+func (w *Watcher) GetByShortId(shortId string) (AppExtended, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	for _, app := range w.cache {
+		if app.ShortId != nil && *app.ShortId == shortId {
+			return app, true
+		}
+	}
+	return AppExtended{}, false
+}
+
+// List returns the cached apps sorted by Id.
+func (w *Watcher) List() []AppExtended {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	apps := make([]AppExtended, 0, len(w.cache))
+	for _, app := range w.cache {
+		apps = append(apps, app)
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		return *apps[i].Id < *apps[j].Id
+	})
+	return apps
+}
+
+// appChanged reports whether an app was modified between two lists.
+func appChanged(old, app AppExtended) bool {
+	return !equalTime(old.UpdatedAt, app.UpdatedAt) ||
+		!equalString(old.Status, app.Status) ||
+		!equalBool(old.Enabled, app.Enabled)
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func equalString(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalBool(a, b *bool) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,64 @@
+package runx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatcherResponseWithoutApps(t *testing.T) {
+	bodies := []string{
+		`{"apps":[{"id":"app-1","status":"running"}]}`,
+		// the apps field is optional: the last app is deleted
+		`{}`,
+		`not json`,
+		`{"apps":[{"id":"app-1","status":"running"}]}`,
+		`{"apps":null}`,
+	}
+	var mu sync.Mutex
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		body := bodies[min(calls, len(bodies)-1)]
+		calls++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, body)
+	}))
+	defer srv.Close()
+
+	client, err := NewClientWithResponses(srv.URL, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWatcher(client, WithWatchInterval(time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Run(ctx)
+
+	want := []WatchEventType{WatchAdded, WatchDeleted, WatchError, WatchAdded, WatchDeleted}
+	var types []WatchEventType
+	for e := range w.Events() {
+		types = append(types, e.Type)
+		if e.Type == WatchDeleted {
+			if app, ok := w.Get("app-1"); ok {
+				t.Errorf("Get(app-1) = %v after its deletion", app)
+			}
+		}
+		if len(types) == len(want) {
+			cancel()
+		}
+	}
+	if len(types) < len(want) {
+		t.Fatalf("events = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("events = %v, want %v", types, want)
+		}
+	}
+}